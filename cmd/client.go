@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/muesli/coral"
+	"github.com/whalesburg/dero-stratum-miner/internal/api"
+)
+
+// apiFlags are the flags of the commands which talk to the API of a running miner.
+type apiFlags struct {
+	addr      string
+	transport string
+	token     string
+	timeout   time.Duration
+}
+
+func (f *apiFlags) register(cmd *coral.Command) {
+	cmd.PersistentFlags().StringVar(&f.addr, "api", "127.0.0.1:44000", "address of the API of the running miner")
+	cmd.PersistentFlags().StringVar(&f.transport, "api-transport", "http", "transport of the API (http or tcp)")
+	cmd.PersistentFlags().StringVar(&f.token, "api-token", "", "token of the API, if it's protected")
+	cmd.PersistentFlags().DurationVar(&f.timeout, "api-timeout", time.Second*5, "timeout of API requests")
+}
+
+func (f *apiFlags) client() (*api.Client, error) {
+	return api.NewClient(f.transport, f.addr, f.token, f.timeout)
+}
+
+// stats reads the stats of the running miner.
+func (f *apiFlags) stats(ctx context.Context) (*api.Stats, error) {
+	c, err := f.client()
+	if err != nil {
+		return nil, err
+	}
+	var stats api.Stats
+	if err := c.Call(ctx, "miner_getStats", nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jon4hz/hashconv"
+	"github.com/muesli/coral"
+	"github.com/whalesburg/dero-stratum-miner/internal/api"
+)
+
+// Exit codes of the ctl command, so it can be used in health checks and scripts.
+// Other errors exit with code 1.
+const (
+	ExitNotMining    = 2 // the miner is paused, disconnected or doesn't compute hashes
+	ExitUnreachable  = 3 // the API of the miner isn't reachable
+	ExitUnauthorized = 4 // the token is wrong or isn't allowed to control the miner, or the ip isn't allowed
+)
+
+var (
+	ctlAPI  apiFlags
+	ctlJSON bool
+)
+
+var errNotMining = errors.New("the miner is not mining")
+
+var ctlCmd = &coral.Command{
+	Use:   "ctl",
+	Short: "Control a running miner through its API",
+	Long: fmt.Sprintf(`Control a running miner through its API.
+
+Exit codes:
+  0  success
+  1  the command failed
+  %d  status only: the miner is paused, disconnected or doesn't compute hashes
+  %d  the API isn't reachable
+  %d  the token is wrong, the ip isn't allowed or the API doesn't allow to control the miner`, ExitNotMining, ExitUnreachable, ExitUnauthorized),
+}
+
+var ctlStatusCmd = &coral.Command{
+	Use:   "status",
+	Short: "Show a summary of the miner",
+	Args:  coral.NoArgs,
+	RunE: ctlRun(func(cmd *coral.Command, c *api.Client, _ []string) (any, error) {
+		var stats api.Stats
+		if err := c.Call(cmd.Context(), "miner_getStats", nil, &stats); err != nil {
+			return nil, err
+		}
+		if !ctlJSON {
+			printStatus(os.Stdout, &stats)
+		}
+		if !stats.Connected || stats.Paused || stats.Hashrate == 0 {
+			return &stats, &ExitError{Code: ExitNotMining, Err: errNotMining}
+		}
+		return &stats, nil
+	}),
+}
+
+var ctlPauseCmd = &coral.Command{
+	Use:   "pause",
+	Short: "Pause mining",
+	Args:  coral.NoArgs,
+	RunE:  ctlAction("miner_pause", nil, "Paused"),
+}
+
+var ctlResumeCmd = &coral.Command{
+	Use:   "resume",
+	Short: "Resume mining",
+	Args:  coral.NoArgs,
+	RunE:  ctlAction("miner_resume", nil, "Resumed"),
+}
+
+var ctlReconnectCmd = &coral.Command{
+	Use:   "reconnect",
+	Short: "Reconnect to the pool",
+	Args:  coral.NoArgs,
+	RunE:  ctlAction("miner_reconnect", nil, "Reconnecting to the pool"),
+}
+
+var ctlThreadsCmd = &coral.Command{
+	Use:   "threads [n]",
+	Short: "Show or change the number of mining threads",
+	Args:  coral.MaximumNArgs(1),
+	RunE: func(cmd *coral.Command, args []string) error {
+		cmd.SilenceUsage = true
+		if len(args) == 0 {
+			return ctlRun(func(cmd *coral.Command, c *api.Client, _ []string) (any, error) {
+				var stats api.Stats
+				if err := c.Call(cmd.Context(), "miner_getStats", nil, &stats); err != nil {
+					return nil, err
+				}
+				if !ctlJSON {
+					fmt.Printf("Mining with %d threads\n", len(stats.Threads)) // nolint: errcheck
+				}
+				return map[string]int{"threads": len(stats.Threads)}, nil
+			})(cmd, args)
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid number of threads: %s", args[0])
+		}
+		return ctlAction("miner_setThreads", &api.ThreadsReq{Threads: n}, fmt.Sprintf("Mining with %d threads", n))(cmd, args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(ctlCmd)
+	ctlCmd.AddCommand(ctlStatusCmd, ctlPauseCmd, ctlResumeCmd, ctlThreadsCmd, ctlReconnectCmd)
+	ctlAPI.register(ctlCmd)
+	ctlCmd.PersistentFlags().BoolVar(&ctlJSON, "json", false, "print the result as JSON")
+}
+
+// ctlRun calls the API with fn and prints its result if --json is set. Errors are mapped to the exit codes.
+func ctlRun(fn func(cmd *coral.Command, c *api.Client, args []string) (any, error)) func(*coral.Command, []string) error {
+	return func(cmd *coral.Command, args []string) error {
+		cmd.SilenceUsage = true
+		if ctlJSON {
+			// the error is part of the JSON output
+			cmd.SilenceErrors = true
+		}
+		c, err := ctlAPI.client()
+		if err != nil {
+			return err
+		}
+		res, err := fn(cmd, c, args)
+		err = ctlError(err)
+		if ctlJSON {
+			if err != nil && res == nil {
+				res = map[string]any{"ok": false, "error": err.Error()}
+			}
+			if encErr := json.NewEncoder(os.Stdout).Encode(res); encErr != nil {
+				return encErr
+			}
+		}
+		return err
+	}
+}
+
+// ctlAction calls a control method of the API and prints msg on success.
+func ctlAction(method string, params any, msg string) func(*coral.Command, []string) error {
+	return ctlRun(func(cmd *coral.Command, c *api.Client, _ []string) (any, error) {
+		if err := c.Call(cmd.Context(), method, params, nil); err != nil {
+			return nil, err
+		}
+		if !ctlJSON {
+			fmt.Println(msg) // nolint: errcheck
+		}
+		return map[string]bool{"ok": true}, nil
+	})
+}
+
+// ctlError maps the errors of the API client to the exit codes.
+func ctlError(err error) error {
+	var (
+		exitErr *ExitError
+		rpcErr  *api.RPCError
+	)
+	switch {
+	case err == nil, errors.As(err, &exitErr):
+		return err
+	case errors.Is(err, api.ErrUnreachable):
+		return &ExitError{Code: ExitUnreachable, Err: err}
+	case errors.Is(err, api.ErrUnauthorized), errors.Is(err, api.ErrForbidden):
+		return &ExitError{Code: ExitUnauthorized, Err: err}
+	case errors.As(err, &rpcErr) && (rpcErr.Message == api.ErrPermissionDenied.Error() || rpcErr.Message == api.ErrControlDisabled.Error()):
+		return &ExitError{Code: ExitUnauthorized, Err: err}
+	}
+	return err
+}
+
+func printStatus(w io.Writer, s *api.Stats) {
+	state := "mining"
+	switch {
+	case s.Paused:
+		state = "paused"
+	case !s.Connected:
+		state = "disconnected"
+	}
+	hashrate := func(h uint64) string {
+		return fmt.Sprintf("%s/s", hashconv.Format(int64(h)))
+	}
+
+	fmt.Fprintf(w, "Version:    %s\n", s.Version)                                                                                     // nolint: errcheck
+	fmt.Fprintf(w, "State:      %s\n", state)                                                                                         // nolint: errcheck
+	fmt.Fprintf(w, "Uptime:     %s\n", time.Duration(s.Uptime)*time.Second)                                                           // nolint: errcheck
+	fmt.Fprintf(w, "Pool:       %s\n", s.Pool)                                                                                        // nolint: errcheck
+	fmt.Fprintf(w, "Worker:     %s\n", s.Worker)                                                                                      // nolint: errcheck
+	fmt.Fprintf(w, "Threads:    %d\n", len(s.Threads))                                                                                // nolint: errcheck
+	fmt.Fprintf(w, "Hashrate:   %s (10s) %s (60s) %s (15m)\n", hashrate(s.Hashrate), hashrate(s.Hashrate1m), hashrate(s.Hashrate15m)) // nolint: errcheck
+	if s.Height > 0 {
+		fmt.Fprintf(w, "Height:     %d\n", s.Height)     // nolint: errcheck
+		fmt.Fprintf(w, "Difficulty: %d\n", s.Difficulty) // nolint: errcheck
+	}
+	fmt.Fprintf(w, "Shares:     %d accepted, %d rejected, %d invalid\n", s.Accepted, s.Rejected, s.Invalid) // nolint: errcheck
+	fmt.Fprintf(w, "Latency:    %s\n", time.Duration(s.LatencyMS)*time.Millisecond)                         // nolint: errcheck
+	fmt.Fprintf(w, "Reconnects: %d\n", s.Reconnects)                                                        // nolint: errcheck
+}
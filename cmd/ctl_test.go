@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/whalesburg/dero-stratum-miner/internal/api"
+)
+
+func TestCtlError(t *testing.T) {
+	other := errors.New("api: 500 Internal Server Error")
+	for _, tt := range []struct {
+		err  error
+		code int // 0 if the error isn't mapped
+	}{
+		{err: nil},
+		{err: other},
+		{err: &ExitError{Code: ExitNotMining, Err: errNotMining}, code: ExitNotMining},
+		{err: fmt.Errorf("%w: connection refused", api.ErrUnreachable), code: ExitUnreachable},
+		{err: api.ErrUnauthorized, code: ExitUnauthorized},
+		{err: api.ErrForbidden, code: ExitUnauthorized},
+		{err: &api.RPCError{Message: api.ErrPermissionDenied.Error()}, code: ExitUnauthorized},
+		{err: &api.RPCError{Message: api.ErrControlDisabled.Error()}, code: ExitUnauthorized},
+		{err: &api.RPCError{Message: "invalid thread count 0"}},
+	} {
+		err := ctlError(tt.err)
+		var exitErr *ExitError
+		if tt.code == 0 {
+			assert.Equal(t, tt.err, err)
+			assert.False(t, errors.As(err, &exitErr), tt.err)
+			continue
+		}
+		if assert.True(t, errors.As(err, &exitErr), tt.err) {
+			assert.Equal(t, tt.code, exitErr.Code, tt.err)
+			assert.ErrorIs(t, err, tt.err)
+		}
+	}
+}
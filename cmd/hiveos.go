@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/muesli/coral"
+	"github.com/whalesburg/dero-stratum-miner/internal/hiveos"
+	"github.com/whalesburg/dero-stratum-miner/internal/sensors"
+)
+
+var hiveosAPI apiFlags
+
+var hiveosCmd = &coral.Command{
+	Use:   "hiveos",
+	Short: "HiveOS integration",
+}
+
+var hiveosStatsCmd = &coral.Command{
+	Use:          "stats",
+	Short:        "Print the stats of the running miner in the format of HiveOS",
+	Args:         coral.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *coral.Command, args []string) error {
+		stats, err := hiveosAPI.stats(cmd.Context())
+		if err != nil {
+			return err
+		}
+		s := sensors.New()
+		return json.NewEncoder(os.Stdout).Encode(hiveos.NewStats(stats, s.Temperatures(), s.Fans()))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(hiveosCmd)
+	hiveosCmd.AddCommand(hiveosStatsCmd)
+	hiveosAPI.register(hiveosStatsCmd)
+}
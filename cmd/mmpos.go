@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/muesli/coral"
+	"github.com/whalesburg/dero-stratum-miner/internal/mmpos"
+)
+
+var mmposAPI apiFlags
+
+var mmposCmd = &coral.Command{
+	Use:   "mmpos",
+	Short: "mmpOS integration",
+}
+
+var mmposStatsCmd = &coral.Command{
+	Use:          "stats",
+	Short:        "Print the stats of the running miner in the format of mmpOS",
+	Args:         coral.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *coral.Command, args []string) error {
+		stats, err := mmposAPI.stats(cmd.Context())
+		if err != nil {
+			return err
+		}
+		return json.NewEncoder(os.Stdout).Encode(mmpos.NewStats(stats))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mmposCmd)
+	mmposCmd.AddCommand(mmposStatsCmd)
+	mmposAPI.register(mmposStatsCmd)
+}
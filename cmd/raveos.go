@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/muesli/coral"
+	"github.com/whalesburg/dero-stratum-miner/internal/raveos"
+	"github.com/whalesburg/dero-stratum-miner/internal/sensors"
+)
+
+var raveosAPI apiFlags
+
+var raveosCmd = &coral.Command{
+	Use:   "raveos",
+	Short: "RaveOS integration",
+}
+
+var raveosStatsCmd = &coral.Command{
+	Use:          "stats",
+	Short:        "Print the stats of the running miner in the format of RaveOS",
+	Args:         coral.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *coral.Command, args []string) error {
+		stats, err := raveosAPI.stats(cmd.Context())
+		if err != nil {
+			return err
+		}
+		s := sensors.New()
+		return json.NewEncoder(os.Stdout).Encode(raveos.NewStats(stats, s.Temperatures(), s.Fans()))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(raveosCmd)
+	raveosCmd.AddCommand(raveosStatsCmd)
+	raveosAPI.register(raveosStatsCmd)
+}
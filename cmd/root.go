@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -19,16 +20,32 @@ import (
 	mcoral "github.com/muesli/mango-coral"
 	"github.com/muesli/roff"
 	"github.com/whalesburg/dero-stratum-miner/internal/api"
+	"github.com/whalesburg/dero-stratum-miner/internal/color"
 	"github.com/whalesburg/dero-stratum-miner/internal/config"
 	"github.com/whalesburg/dero-stratum-miner/internal/console"
 	miner "github.com/whalesburg/dero-stratum-miner/internal/dero-stratum-miner"
 	"github.com/whalesburg/dero-stratum-miner/internal/dns"
+	"github.com/whalesburg/dero-stratum-miner/internal/health"
+	"github.com/whalesburg/dero-stratum-miner/internal/journal"
 	"github.com/whalesburg/dero-stratum-miner/internal/logging"
+	"github.com/whalesburg/dero-stratum-miner/internal/metrics"
+	"github.com/whalesburg/dero-stratum-miner/internal/mqtt"
+	"github.com/whalesburg/dero-stratum-miner/internal/notify"
+	"github.com/whalesburg/dero-stratum-miner/internal/remote"
+	"github.com/whalesburg/dero-stratum-miner/internal/sensors"
 	"github.com/whalesburg/dero-stratum-miner/internal/stratum"
+	"github.com/whalesburg/dero-stratum-miner/internal/systemd"
+	"github.com/whalesburg/dero-stratum-miner/internal/telemetry"
+	"github.com/whalesburg/dero-stratum-miner/internal/tui"
 	"github.com/whalesburg/dero-stratum-miner/internal/version"
 )
 
-var cfg = config.NewEmpty()
+var (
+	cfg           = config.NewEmpty()
+	configFile    string
+	webhooks      []string
+	webhookEvents []string
+)
 
 var rootCmd = &coral.Command{
 	Use:     "dero-stratum-miner",
@@ -45,17 +62,121 @@ func init() {
 
 	rootCmd.Flags().BoolVarP(&cfg.Miner.Testnet, "testnet", "t", false, "use testnet")
 	rootCmd.Flags().StringVarP(&cfg.Miner.PoolURL, "daemon-rpc-address", "r", "pool.whalesburg.com:4300", "stratum pool url")
+	rootCmd.Flags().StringSliceVar(&cfg.Miner.Pools, "pools", nil, "additional stratum pool urls the miner can be switched to")
 	rootCmd.Flags().IntVarP(&cfg.Miner.Threads, "mining-threads", "m", runtime.GOMAXPROCS(0), "number of threads to use")
 	rootCmd.Flags().BoolVar(&cfg.Miner.NonInteractive, "non-interactive", false, "non-interactive mode")
+	rootCmd.Flags().BoolVar(&cfg.Miner.Hotkeys, "hotkeys", false, "start the console in hotkey mode")
+	rootCmd.Flags().BoolVar(&cfg.Miner.TUI, "tui", false, "show a full-screen dashboard instead of the console")
 	rootCmd.Flags().StringVar(&cfg.Miner.DNS, "dns-server", "1.1.1.1", "DNS server to use (only effective on linux arm)")
 	rootCmd.Flags().BoolVar(&cfg.Miner.IgnoreTLSValidation, "ignore-tls-validation", false, "ignore TLS validation")
+	rootCmd.Flags().StringVar(&configFile, "config", "", "JSON config file which is merged over the flags. Can be reloaded at runtime")
 
 	rootCmd.Flags().BoolVar(&cfg.Logger.Debug, "debug", false, "enable debug mode")
 	rootCmd.Flags().Int8Var(&cfg.Logger.CLogLevel, "console-log-level", 0, "console log level")
+	rootCmd.Flags().StringToStringVar(&cfg.Logger.Levels, "log-levels", nil, "console log levels of components, e.g. stratum=debug,miner=info,api=warn")
+	rootCmd.Flags().StringVar(&cfg.Logger.Color, "color", color.Auto, "colorize the output: auto, always or never (auto honors NO_COLOR)")
+	rootCmd.Flags().StringVar(&cfg.Logger.Format, "log-format", logging.FormatConsole, "log format: console, json or logfmt")
+	rootCmd.Flags().StringVar(&cfg.Logger.File, "log-file", "", "also write the log to this file")
+	rootCmd.Flags().Int8Var(&cfg.Logger.FileLevel, "log-file-level", 1, "log level of the log file")
+	rootCmd.Flags().IntVar(&cfg.Logger.FileMaxSize, "log-file-max-size", 100, "rotate the log file if it gets larger than this many MB (0 disables)")
+	rootCmd.Flags().Var(&cfg.Logger.FileMaxAge, "log-file-max-age", "rotate the log file if it gets older than this, e.g. 24h (0 disables)")
+	rootCmd.Flags().IntVar(&cfg.Logger.FileMaxBackups, "log-file-max-backups", 5, "number of rotated log files to keep (0 keeps all)")
+	rootCmd.Flags().BoolVar(&cfg.Logger.FileCompress, "log-file-compress", false, "compress rotated log files with gzip")
+	rootCmd.Flags().BoolVar(&cfg.Logger.Journald, "log-journald", false, "also write the log to the systemd journal")
+	rootCmd.Flags().StringVar(&cfg.Logger.Syslog, "log-syslog", "", "also send the log to a syslog server, e.g. udp://host:514, tcp://host:514 or tls://host:6514")
+	rootCmd.Flags().StringVar(&cfg.Logger.SyslogFacility, "log-syslog-facility", logging.DefaultFacility, "syslog facility")
+	cfg.Logger.DedupWindow = config.Duration(time.Second * 10)
+	rootCmd.Flags().Var(&cfg.Logger.DedupWindow, "log-dedup-window", "collapse repeated warnings and errors within this window (0 disables)")
+	rootCmd.Flags().IntVar(&cfg.Logger.DedupBurst, "log-dedup-burst", 1, "number of repeated warnings and errors which are logged per window")
 
 	rootCmd.Flags().StringVar(&cfg.API.Listen, "api-listen", ":8080", "address to listen for API requests")
 	rootCmd.Flags().BoolVar(&cfg.API.Enabled, "api-enabled", false, "enable the API server")
 	rootCmd.Flags().StringVar(&cfg.API.Transport, "api-transport", "tcp", "transport to use for API requests")
+	rootCmd.Flags().BoolVar(&cfg.API.Control, "api-control", false, "allow API clients to control the miner (pause, threads, pool switch, shutdown...)")
+	rootCmd.Flags().StringVar(&cfg.API.ReadToken, "api-read-token", "", "token which grants read-only access to the API")
+	rootCmd.Flags().StringVar(&cfg.API.AdminToken, "api-admin-token", "", "token which grants full access to the API, including control methods")
+	rootCmd.Flags().StringSliceVar(&cfg.API.CORSOrigins, "api-cors-origins", nil, "origins which are allowed to access the http API from a browser (\"*\" allows all)")
+	rootCmd.Flags().StringSliceVar(&cfg.API.AllowedIPs, "api-allowed-ips", nil, "IPs or CIDR ranges which are allowed to access the API")
+	rootCmd.Flags().BoolVar(&cfg.API.XMRig, "api-xmrig", false, "serve xmrig compatible /1/summary and /2/backends endpoints (http transport only)")
+
+	rootCmd.Flags().BoolVar(&cfg.Metrics.Enabled, "metrics-enabled", false, "enable the prometheus metrics endpoint")
+	rootCmd.Flags().StringVar(&cfg.Metrics.Listen, "metrics-listen", "", "address to serve prometheus metrics on, protected by the API tokens and allowed ips (default is /metrics on the http API)")
+
+	rootCmd.Flags().StringSliceVar(&cfg.Telemetry.Outputs, "telemetry", nil, "push metrics to these outputs (influx+http://, influx+https://, influx+udp://, statsd:// or graphite://)")
+	cfg.Telemetry.Interval = config.Duration(time.Second * 10)
+	rootCmd.Flags().Var(&cfg.Telemetry.Interval, "telemetry-interval", "interval to push metrics")
+	rootCmd.Flags().StringVar(&cfg.Telemetry.Prefix, "telemetry-prefix", "dero_miner", "prefix of the pushed metric names")
+	rootCmd.Flags().StringVar(&cfg.Telemetry.InfluxToken, "telemetry-influx-token", "", "token for the InfluxDB http API")
+
+	rootCmd.Flags().StringVar(&cfg.MQTT.Broker, "mqtt-broker", "", "publish the status to this MQTT broker (mqtt:// or mqtts://)")
+	rootCmd.Flags().StringVar(&cfg.MQTT.Prefix, "mqtt-prefix", "", "topic prefix (default is dero-stratum-miner/<hostname>)")
+	rootCmd.Flags().StringVar(&cfg.MQTT.ClientID, "mqtt-client-id", "", "MQTT client id (default is dero-stratum-miner-<hostname>)")
+	rootCmd.Flags().StringVar(&cfg.MQTT.Username, "mqtt-username", "", "username for the MQTT broker")
+	rootCmd.Flags().StringVar(&cfg.MQTT.Password, "mqtt-password", "", "password for the MQTT broker")
+	cfg.MQTT.Interval = config.Duration(time.Second * 30)
+	rootCmd.Flags().Var(&cfg.MQTT.Interval, "mqtt-interval", "interval to publish the stats")
+	rootCmd.Flags().BoolVar(&cfg.MQTT.Commands, "mqtt-commands", false, "accept pause, resume and threads commands on the command topic")
+	rootCmd.Flags().BoolVar(&cfg.MQTT.Discovery, "mqtt-discovery", false, "publish Home Assistant discovery payloads")
+	rootCmd.Flags().StringVar(&cfg.MQTT.DiscoveryPrefix, "mqtt-discovery-prefix", "homeassistant", "topic prefix of the Home Assistant discovery")
+
+	rootCmd.Flags().BoolVar(&cfg.Journal.Enabled, "journal", false, "record every share in a journal, see the shares command")
+	rootCmd.Flags().StringVar(&cfg.Journal.Dir, "journal-dir", "", "directory of the share journal (default is shares in the user config dir)")
+	cfg.Journal.Retention = config.Duration(time.Hour * 24 * 90)
+	rootCmd.Flags().Var(&cfg.Journal.Retention, "journal-retention", "remove journal entries after this long (0 keeps them forever)")
+
+	rootCmd.Flags().StringVar(&cfg.Remote.URL, "remote-config", "", "poll the wallet, the pools and the threads from this https url and apply changes live")
+	cfg.Remote.Interval = config.Duration(time.Minute * 5)
+	rootCmd.Flags().Var(&cfg.Remote.Interval, "remote-config-interval", "interval to poll the remote config")
+	rootCmd.Flags().StringVar(&cfg.Remote.HMACKey, "remote-config-hmac-key", "", "require an HMAC-SHA256 signature of the remote config with this key")
+	rootCmd.Flags().StringVar(&cfg.Remote.PublicKey, "remote-config-public-key", "", "require an Ed25519 signature of the remote config by this base64 encoded key")
+	rootCmd.Flags().StringVar(&cfg.Remote.Cache, "remote-config-cache", "", "file to keep the last known good remote config in (default is remote-config.json in the user cache dir)")
+
+	rootCmd.Flags().BoolVar(&cfg.Health.Enabled, "health-enabled", false, "enable the /healthz and /readyz endpoints")
+	rootCmd.Flags().StringVar(&cfg.Health.Listen, "health-listen", "", "address to serve the health endpoints on without authentication (default is the http API)")
+	cfg.Health.Stall = config.Duration(time.Minute)
+	rootCmd.Flags().Var(&cfg.Health.Stall, "health-stall", "/healthz fails if no hashes are computed for this long while mining (0 disables)")
+	cfg.Health.JobAge = config.Duration(time.Minute * 5)
+	rootCmd.Flags().Var(&cfg.Health.JobAge, "health-job-age", "/readyz fails if no new job is received for this long (0 disables)")
+	rootCmd.Flags().Uint64Var(&cfg.Health.MinHashrate, "health-min-hashrate", 0, "/readyz fails below this hashrate in H/s")
+
+	rootCmd.Flags().StringSliceVar(&webhooks, "webhook", nil, "send notifications to these webhooks (generic JSON, Discord, Slack or Telegram)")
+	rootCmd.Flags().StringSliceVar(&webhookEvents, "webhook-events", nil, "events which are sent to the webhooks (default all)")
+	cfg.Notify.DisconnectAfter = config.Duration(time.Minute)
+	rootCmd.Flags().Var(&cfg.Notify.DisconnectAfter, "webhook-disconnect-after", "notify if the pool is disconnected for this long (0 disables)")
+	rootCmd.Flags().IntVar(&cfg.Notify.ReconnectStorm, "webhook-reconnect-storm", 5, "notify after this many reconnects within the reconnect window (0 disables)")
+	cfg.Notify.ReconnectWindow = config.Duration(time.Minute * 10)
+	rootCmd.Flags().Var(&cfg.Notify.ReconnectWindow, "webhook-reconnect-window", "window of the reconnect storm")
+	rootCmd.Flags().IntVar(&cfg.Notify.RejectStreak, "webhook-reject-streak", 10, "notify after this many rejected shares in a row (0 disables)")
+	rootCmd.Flags().IntVar(&cfg.Notify.HashrateDrop, "webhook-hashrate-drop", 50, "notify if the hashrate drops this many percent below its 15 minute baseline (0 disables)")
+	cfg.Notify.NotMiningAfter = config.Duration(time.Minute)
+	rootCmd.Flags().Var(&cfg.Notify.NotMiningAfter, "webhook-not-mining-after", "notify if the miner is connected but doesn't hash for this long (0 disables)")
+	cfg.Notify.Cooldown = config.Duration(time.Minute * 15)
+	rootCmd.Flags().Var(&cfg.Notify.Cooldown, "webhook-cooldown", "minimum time between two notifications of the same event")
+	rootCmd.Flags().IntVar(&cfg.Notify.Retries, "webhook-retries", 3, "retries of failed webhook requests")
+
+	rootCmd.Flags().BoolVar(&cfg.Watchdog.Enabled, "watchdog", false, fmt.Sprintf("restart stalled mining threads, reconnect if no shares are found and exit with code %d if that doesn't help", ExitWatchdog))
+	cfg.Watchdog.Stall = config.Duration(time.Minute)
+	rootCmd.Flags().Var(&cfg.Watchdog.Stall, "watchdog-stall", "restart the mining threads if no hashes are computed for this long (0 disables)")
+	rootCmd.Flags().IntVar(&cfg.Watchdog.ShareFactor, "watchdog-share-factor", 10, "reconnect if no share is accepted within this multiple of the expected time (0 disables)")
+	rootCmd.Flags().IntVar(&cfg.Watchdog.Retries, "watchdog-retries", 3, "recoveries which may fail before the miner exits")
+}
+
+// ExitWatchdog is the exit code if the watchdog couldn't recover the miner, so a supervisor can restart it.
+// 75 is EX_TEMPFAIL of sysexits.h.
+const ExitWatchdog = 75
+
+// ExitError makes the miner exit with the given code.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
 }
 
 func Execute() error {
@@ -66,6 +187,41 @@ func validateConfig(cfg *config.Config) error {
 	if err := validateAddress(cfg.Miner.Testnet, cfg.Miner.Wallet); err != nil {
 		return err
 	}
+	if cfg.Metrics.Enabled && cfg.Metrics.Listen == "" && (!cfg.API.Enabled || cfg.API.Transport != "http") {
+		return fmt.Errorf("metrics require either --metrics-listen or the API with the http transport")
+	}
+	if cfg.Health.Enabled && cfg.Health.Listen == "" && (!cfg.API.Enabled || cfg.API.Transport != "http") {
+		return fmt.Errorf("health endpoints require either --health-listen or the API with the http transport")
+	}
+	if cfg.Remote.URL != "" && cfg.Remote.Interval <= 0 {
+		return fmt.Errorf("--remote-config-interval must be positive")
+	}
+	if len(cfg.Telemetry.Outputs) > 0 && cfg.Telemetry.Interval <= 0 {
+		return fmt.Errorf("--telemetry-interval must be positive")
+	}
+	for _, output := range cfg.Telemetry.Outputs {
+		if _, err := telemetry.NewOutput(output, cfg.Telemetry.Prefix, cfg.Telemetry); err != nil {
+			return err
+		}
+	}
+	if cfg.MQTT.Broker != "" && cfg.MQTT.Interval <= 0 {
+		return fmt.Errorf("--mqtt-interval must be positive")
+	}
+	if cfg.MQTT.Discovery && cfg.MQTT.DiscoveryPrefix == "" {
+		return fmt.Errorf("--mqtt-discovery-prefix must not be empty")
+	}
+	if cfg.Journal.Retention < 0 {
+		return fmt.Errorf("--journal-retention must not be negative")
+	}
+	if cfg.Watchdog.Retries < 0 {
+		return fmt.Errorf("--watchdog-retries must not be negative")
+	}
+	if cfg.Notify.HashrateDrop < 0 || cfg.Notify.HashrateDrop >= 100 {
+		return fmt.Errorf("--webhook-hashrate-drop must be between 0 and 99 percent")
+	}
+	if cfg.Miner.TUI && cfg.Miner.NonInteractive {
+		return fmt.Errorf("--tui can't be used in non-interactive mode")
+	}
 	if cfg.Miner.Threads > runtime.GOMAXPROCS(0) {
 		return fmt.Errorf("Mining threads is more than available CPUs. This is NOT optimal. Threads count: %d, max possible: %d", cfg.Miner.Threads, runtime.GOMAXPROCS(0))
 	}
@@ -93,18 +249,51 @@ func validateAddress(testnet bool, a string) error {
 }
 
 func rootHandler(cmd *coral.Command, args []string) error {
+	for _, url := range webhooks {
+		cfg.Notify.Webhooks = append(cfg.Notify.Webhooks, config.Webhook{URL: url, Events: webhookEvents})
+	}
+	flagCfg := cfg.Clone()
+	if configFile != "" {
+		if err := config.Load(configFile, cfg); err != nil {
+			log.Fatalln("failed to load config:", err)
+		}
+	}
+	var src *remote.Source
+	if cfg.Remote.URL != "" {
+		var err error
+		src, err = newRemoteSource(cmd.Context(), flagCfg, cfg.Remote)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if data := src.Config(); data != nil {
+			if cfg, err = loadConfig(flagCfg, data); err != nil {
+				log.Fatalln(err)
+			}
+		}
+	}
 	if err := validateConfig(cfg); err != nil {
 		log.Fatalln(err)
 	}
 
+	if err := color.Setup(cfg.Logger.Color, os.Stdout); err != nil {
+		log.Fatalln(err)
+	}
+
 	done := make(chan os.Signal, 1)
 	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
+	ctx, cancel := context.WithCancel(cmd.Context())
+
 	var (
-		cli *readline.Instance
-		out io.Writer = os.Stdout
+		cli  *readline.Instance
+		dash *tui.Dashboard
+		out  io.Writer = os.Stdout
 	)
-	if !cfg.Miner.NonInteractive {
+	switch {
+	case cfg.Miner.TUI:
+		dash = tui.New(ctx)
+		out = dash.Writer()
+	case !cfg.Miner.NonInteractive:
 		var err error
 		cli, err = console.New()
 		if err != nil {
@@ -113,11 +302,15 @@ func rootHandler(cmd *coral.Command, args []string) error {
 		out = cli.Stdout()
 	}
 
-	logger := logging.New(out, cfg.Logger)
+	l, err := logging.New(out, cfg.Logger)
+	if err != nil {
+		log.Fatalln("failed to create logger:", err)
+	}
+	defer l.Close()
+	logger := l.Logr()
 
 	dns.BootstrapDNS(cfg.Miner.DNS)
 
-	ctx, cancel := context.WithCancel(cmd.Context())
 	stc := newStratumClient(ctx, cfg.Miner.PoolURL, cfg.Miner.Wallet, logger)
 
 	m, err := miner.New(ctx, cancel, cfg.Miner, stc, cli, logger)
@@ -125,6 +318,54 @@ func rootHandler(cmd *coral.Command, args []string) error {
 		log.Fatalln(err)
 	}
 	defer m.Close()
+	m.SetLogLevel(l.Levels())
+	if cfg.Watchdog.Enabled {
+		m.SetWatchdog(cfg.Watchdog)
+	}
+
+	if configFile != "" || src != nil {
+		m.SetReloadFunc(func() (*config.Miner, error) {
+			var data []byte
+			if src != nil {
+				data = src.Config()
+			}
+			c, err := loadConfig(flagCfg, data)
+			if err != nil {
+				return nil, err
+			}
+			return c.Miner, nil
+		})
+	}
+	if src != nil {
+		go src.Run(ctx, logger, func() {
+			if err := m.ReloadConfig(); err != nil {
+				logger.Error(err, "Failed to apply the remote config")
+			}
+		})
+	}
+
+	if dash != nil {
+		if err := dash.Start(m); err != nil {
+			log.Fatalln(err)
+		}
+		defer dash.Close()
+	}
+
+	if cfg.Journal.Enabled {
+		if cfg.Journal.Dir == "" {
+			cfg.Journal.Dir = journal.DefaultDir()
+		}
+		j, err := journal.Open(cfg.Journal)
+		if err != nil {
+			log.Fatalln("failed to open the share journal:", err)
+		}
+		// subscribed before the miner is started, the remaining shares are recorded before the file is closed
+		recorded := j.Start(ctx, m, logger)
+		defer func() {
+			<-recorded
+			j.Close() // nolint: errcheck
+		}()
+	}
 
 	go func() {
 		if err := m.Start(); err != nil {
@@ -132,14 +373,89 @@ func rootHandler(cmd *coral.Command, args []string) error {
 		}
 	}()
 
+	if len(cfg.Notify.Webhooks) > 0 {
+		n, err := notify.NewNotifier(ctx, cfg.Notify, logger)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		go notify.NewWatcher(m, n, cfg.Notify).Run(ctx)
+	}
+
+	if n, err := systemd.NewNotifier(); err == nil {
+		svc := systemd.NewService(m, n, systemd.WatchdogInterval(), logger)
+		go svc.Run(ctx)
+		defer svc.Stopping()
+	}
+
+	var apiServer *api.Server
 	if cfg.API.Enabled {
-		api, err := api.New(ctx, m, cfg.API, logger)
+		apiServer, err = api.New(ctx, m, cfg.API, logger)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer apiServer.Close()
+	}
+
+	if cfg.Metrics.Enabled {
+		collector := metrics.NewCollector(ctx, m)
+		if cfg.Metrics.Listen == "" {
+			if err := apiServer.Handle("/metrics", collector); err != nil {
+				log.Fatalln(err)
+			}
+		} else {
+			handler, listen, err := api.Protect(collector, cfg.Metrics.Listen, cfg.API, logger.WithName("metrics"))
+			if err != nil {
+				log.Fatalln(err)
+			}
+			metricsServer := metrics.New(ctx, handler, listen)
+			defer metricsServer.Close()
+			go func() {
+				if err := metricsServer.Serve(); err != nil {
+					log.Fatalln(err)
+				}
+			}()
+		}
+	}
+
+	if len(cfg.Telemetry.Outputs) > 0 {
+		pusher, err := telemetry.New(m, sensors.New().Temperatures, cfg.Telemetry, logger)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		go pusher.Run(ctx)
+	}
+
+	if cfg.MQTT.Broker != "" {
+		bridge, err := mqtt.New(m, cfg.MQTT, logger)
 		if err != nil {
 			log.Fatalln(err)
 		}
-		defer api.Close()
+		go bridge.Run(ctx)
+	}
+
+	if cfg.Health.Enabled {
+		checker := health.NewChecker(m, cfg.Health)
+		if cfg.Health.Listen == "" {
+			if err := apiServer.Handle("/healthz", checker.LiveHandler()); err != nil {
+				log.Fatalln(err)
+			}
+			if err := apiServer.Handle("/readyz", checker.ReadyHandler()); err != nil {
+				log.Fatalln(err)
+			}
+		} else {
+			healthServer := health.New(ctx, checker, cfg.Health)
+			defer healthServer.Close()
+			go func() {
+				if err := healthServer.Serve(); err != nil {
+					log.Fatalln(err)
+				}
+			}()
+		}
+	}
+
+	if apiServer != nil {
 		go func() {
-			if err := api.Serve(); err != nil {
+			if err := apiServer.Serve(); err != nil {
 				log.Fatalln(err)
 			}
 		}()
@@ -151,22 +467,65 @@ func rootHandler(cmd *coral.Command, args []string) error {
 	}
 	cancel()
 
+	if err := m.Err(); err != nil {
+		cmd.SilenceUsage = true
+		code := 1
+		if errors.Is(err, miner.ErrWatchdog) {
+			code = ExitWatchdog
+		}
+		return &ExitError{Code: code, Err: err}
+	}
 	return nil
 }
 
-func newStratumClient(ctx context.Context, url, addr string, logger logr.Logger) *stratum.Client {
-	logger = logger.WithName("stratum")
-	var useTLS bool
-	if strings.HasPrefix(url, "stratum+tls://") || strings.HasPrefix(url, "stratum+ssl://") {
-		useTLS = true
-		url = strings.TrimPrefix(url, "stratum+tls://")
-		url = strings.TrimPrefix(url, "stratum+ssl://")
-	} else {
-		useTLS = false
-		url = strings.TrimPrefix(url, "stratum://")
-		url = strings.TrimPrefix(url, "tcp://")
-		url = strings.TrimPrefix(url, "stratum+tcp://")
+// loadConfig merges the config file and the remote config over the flags and validates the result.
+func loadConfig(flagCfg *config.Config, remoteData []byte) (*config.Config, error) {
+	c := flagCfg.Clone()
+	if configFile != "" {
+		if err := config.Load(configFile, c); err != nil {
+			return nil, err
+		}
 	}
+	if remoteData != nil {
+		if err := config.Merge(remoteData, c); err != nil {
+			return nil, fmt.Errorf("invalid remote config: %w", err)
+		}
+	}
+	if err := validateConfig(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// newRemoteSource fetches the remote config once before the miner starts. If the endpoint
+// isn't reachable, the last known good copy is used.
+func newRemoteSource(ctx context.Context, flagCfg *config.Config, cfg *config.Remote) (*remote.Source, error) {
+	if cfg.Cache == "" {
+		cfg.Cache = remote.DefaultCache()
+	}
+	src, err := remote.New(cfg, func(data []byte) error {
+		_, err := loadConfig(flagCfg, data)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second*30)
+	defer cancel()
+	if _, err := src.Fetch(ctx); err != nil {
+		log.Println("failed to fetch the remote config:", err)
+		if err := src.LoadCache(); err != nil {
+			log.Println("failed to load the cached remote config:", err)
+		} else {
+			log.Println("using the cached remote config")
+		}
+	}
+	return src, nil
+}
+
+func newStratumClient(ctx context.Context, url, addr string, logger logr.Logger) *stratum.Client {
+	logger = logger.WithName("stratum").WithCallDepth(1) // report the caller in the stratum client, not the callbacks below
+	url, useTLS := stratum.ParseURL(url)
 	opts := []stratum.Opts{
 		stratum.WithUsername(addr),
 		stratum.WithContext(ctx),
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/muesli/coral"
+	"github.com/whalesburg/dero-stratum-miner/internal/config"
+	"github.com/whalesburg/dero-stratum-miner/internal/systemd"
+)
+
+var (
+	serviceOutput   string
+	serviceUser     string
+	serviceWatchdog time.Duration
+)
+
+var installServiceCmd = &coral.Command{
+	Use:   "install-service [flags] -- [miner flags]",
+	Short: "Write a systemd unit which starts the miner with the given flags",
+	Example: `  dero-stratum-miner install-service -- -w $WALLET -r pool.whalesburg.com:4300
+  systemctl daemon-reload && systemctl enable --now dero-stratum-miner`,
+	SilenceUsage: true,
+	RunE:         installServiceHandler,
+}
+
+func init() {
+	rootCmd.AddCommand(installServiceCmd)
+
+	installServiceCmd.Flags().StringVarP(&serviceOutput, "output", "o", "/etc/systemd/system/dero-stratum-miner.service", "path of the unit file, - for stdout")
+	installServiceCmd.Flags().StringVar(&serviceUser, "user", "", "user which runs the miner (default a dynamic user)")
+	installServiceCmd.Flags().DurationVar(&serviceWatchdog, "watchdog-sec", time.Minute, "restart the miner if it stops hashing for this long (0 disables)")
+}
+
+func installServiceHandler(cmd *coral.Command, args []string) error {
+	// the miner flags are checked now, so a typo doesn't end in a restart loop
+	if err := rootCmd.ParseFlags(args); err != nil {
+		return err
+	}
+	if configFile != "" {
+		if !filepath.IsAbs(configFile) {
+			return fmt.Errorf("the config file must be an absolute path")
+		}
+		if err := config.Load(configFile, cfg); err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+	}
+	if err := validateConfig(cfg); err != nil {
+		return err
+	}
+	if cfg.Miner.TUI {
+		return fmt.Errorf("the dashboard can't be used in a service")
+	}
+	if !cfg.Miner.NonInteractive {
+		args = append(args, "--non-interactive")
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+	unit := &systemd.Unit{
+		Description: "Dero Stratum Miner",
+		ExecStart:   append([]string{exe}, args...),
+		User:        serviceUser,
+		Watchdog:    serviceWatchdog,
+		ExitCodes:   []int{ExitWatchdog},
+	}
+	data, err := unit.Render()
+	if err != nil {
+		return err
+	}
+
+	if serviceOutput == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(serviceOutput, data, 0o644); err != nil { //#nosec G306
+		return err
+	}
+	name := filepath.Base(serviceOutput)
+	fmt.Printf("Wrote %s, start the miner with:\n  systemctl daemon-reload && systemctl enable --now %s\n", serviceOutput, name)
+	return nil
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/muesli/coral"
+	"github.com/whalesburg/dero-stratum-miner/internal/journal"
+)
+
+var sharesFlags struct {
+	dir     string
+	since   string
+	until   string
+	pool    string
+	outcome string
+	json    bool
+}
+
+var sharesCmd = &coral.Command{
+	Use:   "shares",
+	Short: "Query the share journal",
+	Long: `Query the share journal which is recorded with --journal.
+
+The time range is given with --since and --until, either as duration before now (24h),
+as date in the local time zone (2022-07-01) or in RFC 3339 (2022-07-01T12:00:00Z).`,
+}
+
+var sharesSummaryCmd = &coral.Command{
+	Use:   "summary",
+	Short: "Sum up the shares by pool",
+	Args:  coral.NoArgs,
+	RunE: sharesRun(func(entries []*journal.Entry) error {
+		summaries := journal.Summarize(entries)
+		if sharesFlags.json {
+			return json.NewEncoder(os.Stdout).Encode(summaries)
+		}
+		if len(summaries) == 0 {
+			fmt.Println("No shares found") // nolint: errcheck
+		}
+		for i, s := range summaries {
+			if i > 0 {
+				fmt.Println() // nolint: errcheck
+			}
+			printSummary(os.Stdout, s)
+		}
+		return nil
+	}),
+}
+
+var sharesListCmd = &coral.Command{
+	Use:   "list",
+	Short: "List the recorded shares",
+	Args:  coral.NoArgs,
+	RunE: sharesRun(func(entries []*journal.Entry) error {
+		switch sharesFlags.outcome {
+		case "", journal.OutcomeSubmitted, journal.OutcomeAccepted, journal.OutcomeRejected:
+		default:
+			return fmt.Errorf("invalid outcome %q", sharesFlags.outcome)
+		}
+		if sharesFlags.outcome != "" {
+			filtered := entries[:0]
+			for _, e := range entries {
+				if e.Outcome == sharesFlags.outcome {
+					filtered = append(filtered, e)
+				}
+			}
+			entries = filtered
+		}
+		if sharesFlags.json {
+			// one entry per line, like the journal itself
+			enc := json.NewEncoder(os.Stdout)
+			for _, e := range entries {
+				if err := enc.Encode(e); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TIME\tPOOL\tJOB\tHEIGHT\tDIFFICULTY\tNONCE\tOUTCOME\tLATENCY\tREASON") // nolint: errcheck
+		for _, e := range entries {
+			latency := ""
+			if e.Outcome != journal.OutcomeSubmitted {
+				latency = (time.Duration(e.LatencyMS) * time.Millisecond).String()
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%.0f\t%d\t%s\t%s\t%s\t%s\n", // nolint: errcheck
+				e.Time.Local().Format("2006-01-02 15:04:05"), e.Pool, e.JobID, e.Height, e.Difficulty, e.Nonce, e.Outcome, latency, e.Reason)
+		}
+		return w.Flush()
+	}),
+}
+
+func init() {
+	rootCmd.AddCommand(sharesCmd)
+	sharesCmd.AddCommand(sharesSummaryCmd, sharesListCmd)
+	sharesCmd.PersistentFlags().StringVar(&sharesFlags.dir, "journal-dir", "", "directory of the share journal (default is shares in the user config dir)")
+	sharesCmd.PersistentFlags().StringVar(&sharesFlags.since, "since", "", "only shares after this time")
+	sharesCmd.PersistentFlags().StringVar(&sharesFlags.until, "until", "", "only shares before this time")
+	sharesCmd.PersistentFlags().StringVar(&sharesFlags.pool, "pool", "", "only shares of pools whose url contains this")
+	sharesCmd.PersistentFlags().BoolVar(&sharesFlags.json, "json", false, "print the result as JSON")
+	sharesListCmd.Flags().StringVar(&sharesFlags.outcome, "outcome", "", fmt.Sprintf("only shares with this outcome (%s, %s or %s)", journal.OutcomeSubmitted, journal.OutcomeAccepted, journal.OutcomeRejected))
+}
+
+// sharesRun reads the entries which match the flags and passes them to fn.
+func sharesRun(fn func(entries []*journal.Entry) error) func(*coral.Command, []string) error {
+	return func(cmd *coral.Command, _ []string) error {
+		cmd.SilenceUsage = true
+		dir := sharesFlags.dir
+		if dir == "" {
+			dir = journal.DefaultDir()
+		}
+		now := time.Now()
+		filter := &journal.Filter{Pool: sharesFlags.pool}
+		var err error
+		if sharesFlags.since != "" {
+			if filter.From, err = journal.ParseTime(sharesFlags.since, now); err != nil {
+				return err
+			}
+		}
+		if sharesFlags.until != "" {
+			if filter.To, err = journal.ParseTime(sharesFlags.until, now); err != nil {
+				return err
+			}
+		}
+		entries, err := journal.Read(dir, filter)
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("no share journal in %s, start the miner with --journal", dir)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read the share journal: %w", err)
+		}
+		return fn(entries)
+	}
+}
+
+func printSummary(w io.Writer, s *journal.Summary) {
+	layout := "2006-01-02 15:04:05"
+	fmt.Fprintf(w, "Pool:       %s\n", s.Pool)                                                          // nolint: errcheck
+	fmt.Fprintf(w, "Period:     %s - %s\n", s.From.Local().Format(layout), s.To.Local().Format(layout)) // nolint: errcheck
+	fmt.Fprintf(w, "Submitted:  %d\n", s.Submitted)                                                     // nolint: errcheck
+	fmt.Fprintf(w, "Accepted:   %d (difficulty %d)\n", s.Accepted, s.Difficulty)                        // nolint: errcheck
+	fmt.Fprintf(w, "Rejected:   %d\n", s.Rejected)                                                      // nolint: errcheck
+	reasons := make([]string, 0, len(s.Reasons))
+	for r := range s.Reasons {
+		reasons = append(reasons, r)
+	}
+	sort.Strings(reasons)
+	for _, r := range reasons {
+		fmt.Fprintf(w, "  %s: %d\n", r, s.Reasons[r]) // nolint: errcheck
+	}
+	fmt.Fprintf(w, "Unanswered: %d\n", s.Unanswered)                                // nolint: errcheck
+	fmt.Fprintf(w, "Latency:    %s\n", time.Duration(s.LatencyMS)*time.Millisecond) // nolint: errcheck
+}
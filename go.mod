@@ -9,6 +9,7 @@ require (
 	github.com/go-logr/zapr v1.2.3
 	github.com/jon4hz/hashconv v1.0.0
 	github.com/jpillora/backoff v1.0.0
+	github.com/klauspost/cpuid/v2 v2.0.4
 	github.com/muesli/coral v1.0.0
 	github.com/muesli/mango-coral v1.0.1
 	github.com/muesli/roff v0.1.0
@@ -16,6 +17,7 @@ require (
 	github.com/teivah/broadcast v0.1.0
 	go.neonxp.dev/jsonrpc2 v1.2.0
 	go.uber.org/zap v1.24.0
+	golang.org/x/net v0.0.0-20220615171555-694bf12d69de
 	golang.org/x/sys v0.0.0-20220615213510-4f61da869c0c
 )
 
@@ -29,7 +31,6 @@ require (
 	github.com/dustin/go-humanize v1.0.0 // indirect
 	github.com/fxamacker/cbor/v2 v2.4.0 // indirect
 	github.com/inconshreveable/mousetrap v1.0.0 // indirect
-	github.com/klauspost/cpuid/v2 v2.0.4 // indirect
 	github.com/minio/sha256-simd v1.0.0 // indirect
 	github.com/muesli/mango v0.1.0 // indirect
 	github.com/muesli/mango-pflag v0.1.0 // indirect
@@ -42,7 +43,6 @@ require (
 	go.uber.org/atomic v1.7.0 // indirect
 	go.uber.org/multierr v1.6.0 // indirect
 	golang.org/x/crypto v0.0.0-20220525230936-793ad666bf5e // indirect
-	golang.org/x/net v0.0.0-20220615171555-694bf12d69de // indirect
 	golang.org/x/sync v0.0.0-20220513210516-0976fa681c29 // indirect
 	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
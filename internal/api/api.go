@@ -2,7 +2,9 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"path"
 	"strconv"
@@ -16,6 +18,8 @@ import (
 	"go.neonxp.dev/jsonrpc2/transport"
 )
 
+var ErrNoHTTPTransport = errors.New("api: handlers can only be mounted on the http transport")
+
 type Server struct {
 	ctx       context.Context
 	cancel    context.CancelFunc
@@ -23,15 +27,31 @@ type Server struct {
 	startTime time.Time
 	r         *rpc.RpcServer
 	m         *miner.Client
+	http      *httpTransport
+	auth      *auth
+	control   bool
 }
 
 func New(ctx context.Context, m *miner.Client, cfg *config.API, logr logr.Logger) (*Server, error) {
-	var tsp transport.Transport
+	a, err := newAuth(cfg)
+	if err != nil {
+		return nil, err
+	}
+	listen, changed := a.safeListen(cfg.Listen)
+	if changed {
+		logr.WithName("api").Info("No API token or allowed ips configured, only listening on localhost", "listen", listen)
+	}
+
+	var (
+		tsp   transport.Transport
+		httpt *httpTransport
+	)
 	switch cfg.Transport {
 	case "tcp":
-		tsp = &transport.TCP{Bind: cfg.Listen, Parallel: true}
+		tsp = &tcpTransport{bind: listen, auth: a, parallel: true}
 	case "http":
-		tsp = &transport.HTTP{Bind: cfg.Listen, Parallel: true, CORSOrigin: "*"}
+		httpt = newHTTPTransport(listen, a)
+		tsp = httpt
 	default:
 		return nil, fmt.Errorf("unknown transport %s", cfg.Transport)
 	}
@@ -42,13 +62,24 @@ func New(ctx context.Context, m *miner.Client, cfg *config.API, logr logr.Logger
 		rpc.WithTransport(tsp),
 	)
 	s := &Server{
-		ctx:    ctx,
-		cancel: cancel,
-		listen: cfg.Listen,
-		r:      r,
-		m:      m,
+		ctx:     ctx,
+		cancel:  cancel,
+		listen:  listen,
+		r:       r,
+		m:       m,
+		http:    httpt,
+		auth:    a,
+		control: cfg.Control,
 	}
 	s.r.Register("miner_getstat1", rpc.HS(s.MinerStats))
+	s.r.Register("miner_getStats", rpc.HS(s.GetStats))
+	s.registerControl()
+	s.registerEvents()
+	if cfg.XMRig {
+		if err := s.registerXMRig(); err != nil {
+			return nil, err
+		}
+	}
 	return s, nil
 }
 
@@ -57,6 +88,30 @@ func (s *Server) Serve() error {
 	return s.r.Run(s.ctx)
 }
 
+// Handle mounts an additional handler on the API listener. This is only supported by the http transport.
+func (s *Server) Handle(pattern string, handler http.Handler) error {
+	if s.http == nil {
+		return ErrNoHTTPTransport
+	}
+	s.http.handle(pattern, handler)
+	return nil
+}
+
+// Protect applies the access control of the API to a handler which is served on its own listener:
+// the ip allowlist and the tokens of cfg. It returns the handler and the address to listen on, which is
+// bound to localhost like the API if neither tokens nor allowed ips are configured.
+func Protect(handler http.Handler, listen string, cfg *config.API, logger logr.Logger) (http.Handler, string, error) {
+	a, err := newAuth(cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	listen, changed := a.safeListen(listen)
+	if changed {
+		logger.Info("No API token or allowed ips configured, only listening on localhost", "listen", listen)
+	}
+	return newHTTPTransport(listen, a).authenticate(handler), listen, nil
+}
+
 func (s *Server) Close() error {
 	if s.cancel != nil {
 		s.cancel()
@@ -0,0 +1,22 @@
+// Package apitest provides the stats of a running miner for the tests of the integrations built on the API.
+package apitest
+
+import (
+	_ "embed"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/whalesburg/dero-stratum-miner/internal/api"
+)
+
+//go:embed testdata/stats.json
+var stats []byte
+
+// Stats returns the stats of a miner with four threads which found a few shares.
+func Stats(t *testing.T) *api.Stats {
+	t.Helper()
+	var s api.Stats
+	require.NoError(t, json.Unmarshal(stats, &s))
+	return &s
+}
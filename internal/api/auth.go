@@ -0,0 +1,162 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/whalesburg/dero-stratum-miner/internal/config"
+)
+
+type role int
+
+const (
+	roleNone role = iota
+	roleRead
+	roleAdmin
+)
+
+type roleKey struct{}
+
+func withRole(ctx context.Context, r role) context.Context {
+	return context.WithValue(ctx, roleKey{}, r)
+}
+
+func roleFromContext(ctx context.Context) role {
+	r, _ := ctx.Value(roleKey{}).(role)
+	return r
+}
+
+// auth decides which clients may access the API and with which permissions.
+type auth struct {
+	readToken  string
+	adminToken string
+	origins    []string
+	allowed    []*net.IPNet
+}
+
+func newAuth(cfg *config.API) (*auth, error) {
+	a := &auth{
+		readToken:  cfg.ReadToken,
+		adminToken: cfg.AdminToken,
+		origins:    cfg.CORSOrigins,
+	}
+	for _, s := range cfg.AllowedIPs {
+		if !strings.Contains(s, "/") {
+			if ip := net.ParseIP(s); ip != nil && ip.To4() != nil {
+				s += "/32"
+			} else {
+				s += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed ip %q: %w", s, err)
+		}
+		a.allowed = append(a.allowed, n)
+	}
+	return a, nil
+}
+
+// required reports whether clients have to authenticate with a token.
+func (a *auth) required() bool {
+	return a.readToken != "" || a.adminToken != ""
+}
+
+// role returns the permissions granted by the token.
+// Without configured tokens every client is an admin, access is then limited by the bind address and the ip allowlist.
+func (a *auth) role(token string) role {
+	if !a.required() {
+		return roleAdmin
+	}
+	switch {
+	case tokenEqual(token, a.adminToken):
+		return roleAdmin
+	case tokenEqual(token, a.readToken):
+		return roleRead
+	}
+	return roleNone
+}
+
+func tokenEqual(got, want string) bool {
+	if want == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+// allowIP checks the remote address of a client against the allowlist.
+func (a *auth) allowIP(remoteAddr string) bool {
+	if len(a.allowed) == 0 {
+		return true
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range a.allowed {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// corsOrigin returns the value of the Access-Control-Allow-Origin header for the given origin.
+func (a *auth) corsOrigin(origin string) string {
+	for _, o := range a.origins {
+		if o == "*" {
+			return "*"
+		}
+		if origin != "" && strings.EqualFold(o, origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
+// allowOrigin reports whether a browser page from origin may call the API: pages which are served
+// by the API itself or from the configured CORS origins. Requests without an origin aren't sent by foreign pages.
+func (a *auth) allowOrigin(origin, host string) bool {
+	if origin == "" || a.corsOrigin(origin) != "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, host)
+}
+
+// loopback reports whether host, with or without a port, is localhost or a loopback address.
+func loopback(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
+
+// safeListen binds the API to localhost if it would be reachable from the network without any access control.
+func (a *auth) safeListen(listen string) (string, bool) {
+	if a.required() || len(a.allowed) > 0 {
+		return listen, false
+	}
+	host, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		return listen, false
+	}
+	if host != "" {
+		if ip := net.ParseIP(host); ip == nil || !ip.IsUnspecified() {
+			return listen, false
+		}
+	}
+	return net.JoinHostPort("127.0.0.1", port), true
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalesburg/dero-stratum-miner/internal/config"
+)
+
+func TestAuthRoles(t *testing.T) {
+	a, err := newAuth(&config.API{ReadToken: "read", AdminToken: "admin"})
+	require.NoError(t, err)
+
+	assert.True(t, a.required())
+	assert.Equal(t, roleAdmin, a.role("admin"))
+	assert.Equal(t, roleRead, a.role("read"))
+	assert.Equal(t, roleNone, a.role(""))
+	assert.Equal(t, roleNone, a.role("wrong"))
+
+	open, err := newAuth(&config.API{})
+	require.NoError(t, err)
+	assert.False(t, open.required())
+	assert.Equal(t, roleAdmin, open.role(""))
+}
+
+func TestAuthAllowIP(t *testing.T) {
+	a, err := newAuth(&config.API{AllowedIPs: []string{"10.0.0.0/8", "192.168.1.5", "::1"}})
+	require.NoError(t, err)
+
+	assert.True(t, a.allowIP("10.1.2.3:1234"))
+	assert.True(t, a.allowIP("192.168.1.5:80"))
+	assert.True(t, a.allowIP("[::1]:8080"))
+	assert.False(t, a.allowIP("192.168.1.6:80"))
+	assert.False(t, a.allowIP("invalid"))
+
+	_, err = newAuth(&config.API{AllowedIPs: []string{"not-an-ip"}})
+	assert.Error(t, err)
+}
+
+func TestAuthCORSOrigin(t *testing.T) {
+	a, err := newAuth(&config.API{CORSOrigins: []string{"https://dash.example.com"}})
+	require.NoError(t, err)
+	assert.Equal(t, "https://dash.example.com", a.corsOrigin("https://dash.example.com"))
+	assert.Equal(t, "", a.corsOrigin("https://evil.example.com"))
+
+	a.origins = []string{"*"}
+	assert.Equal(t, "*", a.corsOrigin("https://evil.example.com"))
+}
+
+func TestAuthSafeListen(t *testing.T) {
+	open, err := newAuth(&config.API{})
+	require.NoError(t, err)
+
+	listen, changed := open.safeListen(":8080")
+	assert.True(t, changed)
+	assert.Equal(t, "127.0.0.1:8080", listen)
+
+	listen, changed = open.safeListen("0.0.0.0:8080")
+	assert.True(t, changed)
+	assert.Equal(t, "127.0.0.1:8080", listen)
+
+	listen, changed = open.safeListen("192.168.1.2:8080")
+	assert.False(t, changed)
+	assert.Equal(t, "192.168.1.2:8080", listen)
+
+	secured, err := newAuth(&config.API{ReadToken: "read"})
+	require.NoError(t, err)
+	listen, changed = secured.safeListen(":8080")
+	assert.False(t, changed)
+	assert.Equal(t, ":8080", listen)
+}
+
+func TestProtect(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	get := func(h http.Handler, remote, token string) int {
+		r := httptest.NewRequest(http.MethodGet, "http://192.168.1.2:9100/metrics", nil)
+		r.RemoteAddr = remote + ":40000"
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	_, listen, err := Protect(ok, ":9100", &config.API{}, logr.Discard())
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:9100", listen)
+
+	h, listen, err := Protect(ok, ":9100", &config.API{ReadToken: "read", AllowedIPs: []string{"192.168.1.0/24"}}, logr.Discard())
+	require.NoError(t, err)
+	assert.Equal(t, ":9100", listen)
+	assert.Equal(t, http.StatusOK, get(h, "192.168.1.3", "read"))
+	assert.Equal(t, http.StatusUnauthorized, get(h, "192.168.1.3", ""))
+	assert.Equal(t, http.StatusForbidden, get(h, "10.0.0.1", "read"))
+
+	_, _, err = Protect(ok, ":9100", &config.API{AllowedIPs: []string{"nope"}}, logr.Discard())
+	assert.Error(t, err)
+}
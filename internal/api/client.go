@@ -0,0 +1,166 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnauthorized     = errors.New("api: unauthorized, check the token")
+	ErrForbidden        = errors.New("api: forbidden, check the allowed ips and the address")
+	ErrUnknownTransport = errors.New("api: unknown transport, use http or tcp")
+	ErrUnreachable      = errors.New("api: miner is not reachable")
+)
+
+// codeUnauthorized is returned by the tcp transport if the first request isn't a valid auth request.
+const codeUnauthorized = -32001
+
+// RPCError is an error returned by the JSON-RPC method.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return e.Message
+}
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      int    `json:"id"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+// Client calls the API of a running miner over the http or tcp transport.
+type Client struct {
+	transport string
+	addr      string
+	token     string
+	timeout   time.Duration
+}
+
+// NewClient creates a client for the API listening on addr. The token is only required
+// if the API is protected by tokens.
+func NewClient(transport, addr, token string, timeout time.Duration) (*Client, error) {
+	switch transport {
+	case "http", "tcp":
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTransport, transport)
+	}
+	return &Client{transport: transport, addr: addr, token: token, timeout: timeout}, nil
+}
+
+// Call calls the method and decodes its result into result, which may be nil.
+func (c *Client) Call(ctx context.Context, method string, params, result any) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	req, err := json.Marshal(rpcRequest{JSONRPC: "2.0", ID: 1, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	var body []byte
+	if c.transport == "http" {
+		body, err = c.callHTTP(ctx, req)
+	} else {
+		body, err = c.callTCP(ctx, req)
+	}
+	if err != nil {
+		return err
+	}
+
+	var res rpcResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return fmt.Errorf("api: invalid response: %w", err)
+	}
+	if res.Error != nil {
+		if res.Error.Code == codeUnauthorized {
+			return ErrUnauthorized
+		}
+		return res.Error
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(res.Result, result)
+}
+
+func (c *Client) callHTTP(ctx context.Context, body []byte) ([]byte, error) {
+	url := c.addr
+	if !strings.Contains(url, "://") {
+		url = "http://" + url
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(url, "/")+"/", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnreachable, err)
+	}
+	defer res.Body.Close()
+	switch {
+	case res.StatusCode == http.StatusUnauthorized:
+		return nil, ErrUnauthorized
+	case res.StatusCode == http.StatusForbidden:
+		return nil, ErrForbidden
+	case res.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("api: %s", res.Status)
+	}
+	return io.ReadAll(io.LimitReader(res.Body, 1<<20))
+}
+
+func (c *Client) callTCP(ctx context.Context, body []byte) ([]byte, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnreachable, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline) // nolint: errcheck
+	}
+	rd := bufio.NewReader(conn)
+
+	if c.token != "" {
+		auth, err := json.Marshal(rpcRequest{JSONRPC: "2.0", ID: 0, Method: "auth", Params: map[string]string{"token": c.token}})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := conn.Write(append(auth, '\n')); err != nil {
+			return nil, err
+		}
+		line, err := rd.ReadBytes('\n')
+		if err != nil {
+			return nil, err
+		}
+		var res rpcResponse
+		if err := json.Unmarshal(line, &res); err != nil || res.Error != nil {
+			return nil, ErrUnauthorized
+		}
+	}
+
+	if _, err := conn.Write(append(body, '\n')); err != nil {
+		return nil, err
+	}
+	return rd.ReadBytes('\n')
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalesburg/dero-stratum-miner/internal/config"
+	miner "github.com/whalesburg/dero-stratum-miner/internal/dero-stratum-miner"
+	"github.com/whalesburg/dero-stratum-miner/internal/stratum"
+)
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+// startServer serves the API of a miner which isn't started, so it never connects to the pool.
+func startServer(t *testing.T, transport string) (*miner.Client, string) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	cfg := &config.Miner{Wallet: "dero1abc.rig1", PoolURL: "127.0.0.1:1", Threads: 1}
+	m, err := miner.New(ctx, cancel, cfg, stratum.New(cfg.PoolURL, stratum.WithContext(ctx)), nil, logr.Discard())
+	require.NoError(t, err)
+
+	addr := freeAddr(t)
+	s, err := New(ctx, m, &config.API{Transport: transport, Listen: addr, Control: true, ReadToken: "read", AdminToken: "admin"}, logr.Discard())
+	require.NoError(t, err)
+	go s.Serve() // nolint: errcheck
+
+	// wait for the listener
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+		}
+		return err == nil
+	}, time.Second*5, time.Millisecond*10)
+	return m, addr
+}
+
+func TestClient(t *testing.T) {
+	for _, transport := range []string{"http", "tcp"} {
+		t.Run(transport, func(t *testing.T) {
+			m, addr := startServer(t, transport)
+			ctx := context.Background()
+
+			c, err := NewClient(transport, addr, "read", time.Second*5)
+			require.NoError(t, err)
+			var stats Stats
+			require.NoError(t, c.Call(ctx, "miner_getStats", nil, &stats))
+			assert.Equal(t, "rig1", stats.Worker)
+			assert.Equal(t, Algo, stats.Algo)
+			assert.False(t, stats.Paused)
+
+			// the read token can't control the miner
+			var ok bool
+			err = c.Call(ctx, "miner_pause", nil, &ok)
+			var rpcErr *RPCError
+			require.True(t, errors.As(err, &rpcErr), err)
+			assert.Equal(t, ErrPermissionDenied.Error(), rpcErr.Message)
+
+			c, err = NewClient(transport, addr, "admin", time.Second*5)
+			require.NoError(t, err)
+			require.NoError(t, c.Call(ctx, "miner_pause", nil, &ok))
+			assert.True(t, ok)
+			assert.True(t, m.IsPaused())
+
+			c, err = NewClient(transport, addr, "wrong", time.Second*5)
+			require.NoError(t, err)
+			assert.ErrorIs(t, c.Call(ctx, "miner_getStats", nil, &stats), ErrUnauthorized)
+		})
+	}
+}
+
+func TestClientUnreachable(t *testing.T) {
+	for _, transport := range []string{"http", "tcp"} {
+		t.Run(transport, func(t *testing.T) {
+			c, err := NewClient(transport, freeAddr(t), "", time.Second)
+			require.NoError(t, err)
+			assert.ErrorIs(t, c.Call(context.Background(), "miner_getStats", nil, nil), ErrUnreachable)
+		})
+	}
+}
+
+func TestClientForbidden(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient("http", srv.URL, "read", time.Second)
+	require.NoError(t, err)
+	assert.ErrorIs(t, c.Call(context.Background(), "miner_getStats", nil, nil), ErrForbidden)
+}
+
+func TestInvalidShare(t *testing.T) {
+	assert.True(t, invalidShare("Low difficulty share"))
+	assert.True(t, invalidShare("invalid nonce"))
+	assert.False(t, invalidShare("Stale share"))
+	assert.False(t, invalidShare("Duplicate share"))
+}
+
+func TestStatsLate(t *testing.T) {
+	assert.Equal(t, uint64(2), (&Stats{Rejected: 5, Invalid: 3}).Late())
+	// the invalid shares are counted separately and can be ahead
+	assert.Zero(t, (&Stats{Rejected: 3, Invalid: 5}).Late())
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	miner "github.com/whalesburg/dero-stratum-miner/internal/dero-stratum-miner"
+	"go.neonxp.dev/jsonrpc2/rpc"
+)
+
+var (
+	ErrControlDisabled  = errors.New("control methods are disabled")
+	ErrPermissionDenied = errors.New("permission denied: admin token required")
+)
+
+type ThreadsReq struct {
+	Threads int `json:"threads"`
+}
+
+type PoolReq struct {
+	Pool string `json:"pool"`
+}
+
+type LogLevelReq struct {
+	Component string `json:"component"` // the console level is changed if empty
+	Level     string `json:"level"`
+}
+
+func (s *Server) registerControl() {
+	s.r.Register("miner_pause", rpc.HS(s.Pause))
+	s.r.Register("miner_resume", rpc.HS(s.Resume))
+	s.r.Register("miner_setThreads", rpc.H(s.SetThreads))
+	s.r.Register("miner_switchPool", rpc.H(s.SwitchPool))
+	s.r.Register("miner_reconnect", rpc.HS(s.Reconnect))
+	s.r.Register("miner_reloadConfig", rpc.HS(s.ReloadConfig))
+	s.r.Register("miner_shutdown", rpc.HS(s.Shutdown))
+	s.r.Register("miner_getLogLevels", rpc.HS(s.GetLogLevels))
+	s.r.Register("miner_setLogLevel", rpc.H(s.SetLogLevel))
+}
+
+// canWrite checks whether the caller is allowed to change the state of the miner.
+func (s *Server) canWrite(ctx context.Context) error {
+	if !s.control {
+		return ErrControlDisabled
+	}
+	if roleFromContext(ctx) != roleAdmin {
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
+func (s *Server) Pause(ctx context.Context) (bool, error) {
+	if err := s.canWrite(ctx); err != nil {
+		return false, err
+	}
+	s.m.Pause()
+	return true, nil
+}
+
+func (s *Server) Resume(ctx context.Context) (bool, error) {
+	if err := s.canWrite(ctx); err != nil {
+		return false, err
+	}
+	s.m.Resume()
+	return true, nil
+}
+
+func (s *Server) SetThreads(ctx context.Context, req *ThreadsReq) (bool, error) {
+	if err := s.canWrite(ctx); err != nil {
+		return false, err
+	}
+	if err := s.m.SetThreads(req.Threads); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *Server) SwitchPool(ctx context.Context, req *PoolReq) (bool, error) {
+	if err := s.canWrite(ctx); err != nil {
+		return false, err
+	}
+	if err := s.m.SwitchPool(req.Pool); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *Server) Reconnect(ctx context.Context) (bool, error) {
+	if err := s.canWrite(ctx); err != nil {
+		return false, err
+	}
+	s.m.Reconnect()
+	return true, nil
+}
+
+func (s *Server) ReloadConfig(ctx context.Context) (bool, error) {
+	if err := s.canWrite(ctx); err != nil {
+		return false, err
+	}
+	if err := s.m.ReloadConfig(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *Server) GetLogLevels(ctx context.Context) (*miner.LogLevels, error) {
+	return s.m.GetLogLevels()
+}
+
+func (s *Server) SetLogLevel(ctx context.Context, req *LogLevelReq) (bool, error) {
+	if err := s.canWrite(ctx); err != nil {
+		return false, err
+	}
+	if err := s.m.ChangeLogLevel(req.Component, req.Level); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *Server) Shutdown(ctx context.Context) (bool, error) {
+	if err := s.canWrite(ctx); err != nil {
+		return false, err
+	}
+	// give the transport a chance to send the response before the miner stops
+	time.AfterFunc(time.Second, s.m.Shutdown)
+	return true, nil
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"golang.org/x/net/websocket"
+)
+
+// eventBuffer is the number of events which are queued for a websocket client.
+// Events are dropped for clients which fall further behind, so a slow client can't stall the miner.
+const eventBuffer = 64
+
+// registerEvents serves a websocket on /events which streams every event of the miner as JSON message.
+func (s *Server) registerEvents() {
+	if s.http == nil {
+		return
+	}
+	s.http.handle("/events", websocket.Server{
+		Handshake: s.checkOrigin,
+		Handler:   s.streamEvents,
+	})
+}
+
+// checkOrigin only allows pages served from the API itself or from the configured CORS origins to open the websocket.
+func (s *Server) checkOrigin(_ *websocket.Config, r *http.Request) error {
+	if !s.auth.allowOrigin(r.Header.Get("Origin"), r.Host) {
+		return errors.New("origin not allowed")
+	}
+	return nil
+}
+
+func (s *Server) streamEvents(ws *websocket.Conn) {
+	defer ws.Close()
+
+	l := s.m.NewEventListener(eventBuffer)
+	defer l.Close()
+
+	// the client isn't expected to send anything, reading only detects a closed connection
+	closed := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, ws) // nolint: errcheck
+		close(closed)
+	}()
+
+	for {
+		select {
+		case ev, ok := <-l.Ch():
+			if !ok {
+				return
+			}
+			if err := websocket.JSON.Send(ws, ev); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
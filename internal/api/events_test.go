@@ -0,0 +1,61 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	miner "github.com/whalesburg/dero-stratum-miner/internal/dero-stratum-miner"
+	"golang.org/x/net/websocket"
+)
+
+func dialEvents(addr, token, origin string) (*websocket.Conn, error) {
+	cfg, err := websocket.NewConfig("ws://"+addr+"/events?token="+token, origin)
+	if err != nil {
+		return nil, err
+	}
+	return websocket.DialConfig(cfg)
+}
+
+func TestEvents(t *testing.T) {
+	m, addr := startServer(t, "http")
+
+	ws, err := dialEvents(addr, "read", "http://"+addr)
+	require.NoError(t, err)
+	defer ws.Close()
+
+	// the listener is registered after the handshake, retry until the event arrives
+	ws.SetReadDeadline(time.Now().Add(time.Second * 5)) // nolint: errcheck
+	var ev struct {
+		Type miner.EventType `json:"type"`
+	}
+	for ev.Type != miner.EventPaused {
+		m.Pause()
+		m.Resume()
+		require.NoError(t, websocket.JSON.Receive(ws, &ev))
+	}
+	require.NoError(t, websocket.JSON.Receive(ws, &ev))
+	assert.Equal(t, miner.EventResumed, ev.Type)
+}
+
+func TestEventsOrigin(t *testing.T) {
+	_, addr := startServer(t, "http")
+
+	for origin, ok := range map[string]bool{
+		"http://" + addr:             true,
+		"https://evil.example.com":   false,
+		"http://127.0.0.1.nip.io:80": false,
+	} {
+		ws, err := dialEvents(addr, "read", origin)
+		if ok {
+			assert.NoError(t, err, origin)
+			ws.Close()
+		} else {
+			assert.Error(t, err, origin)
+		}
+	}
+
+	_, err := dialEvents(addr, "wrong", "http://"+addr)
+	assert.Error(t, err)
+}
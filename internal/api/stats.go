@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/whalesburg/dero-stratum-miner/internal/version"
+)
+
+// Algo is the name of the mining algorithm.
+const Algo = "astrobwtv3"
+
+// Stats are the detailed stats of the miner. The integrations for mining operating systems are built on them.
+type Stats struct {
+	Version     string   `json:"version"`
+	Algo        string   `json:"algo"`
+	Uptime      int64    `json:"uptime"` // in seconds
+	Pool        string   `json:"pool"`
+	Worker      string   `json:"worker"`
+	Connected   bool     `json:"connected"`
+	Paused      bool     `json:"paused"`
+	Hashrate    uint64   `json:"hashrate"` // average of the last 10 seconds
+	Hashrate1m  uint64   `json:"hashrate_1m"`
+	Hashrate15m uint64   `json:"hashrate_15m"`
+	Threads     []uint64 `json:"threads"` // hashrate of every thread over the last 10 seconds
+	Hashes      uint64   `json:"hashes"`
+	Accepted    uint64   `json:"accepted"`
+	Rejected    uint64   `json:"rejected"` // including the invalid shares
+	Invalid     uint64   `json:"invalid"`  // rejected as invalid, e.g. because of a low difficulty
+	Difficulty  uint64   `json:"difficulty"`
+	Height      uint64   `json:"height"`
+	LatencyMS   int64    `json:"latency_ms"`
+	Reconnects  int      `json:"reconnects"`
+}
+
+// Late returns the rejected shares which aren't invalid, e.g. stale or duplicate shares.
+func (s *Stats) Late() uint64 {
+	if s.Invalid > s.Rejected {
+		return 0
+	}
+	return s.Rejected - s.Invalid
+}
+
+func (s *Server) GetStats(ctx context.Context) (*Stats, error) {
+	stats := &Stats{
+		Version:     version.Version,
+		Algo:        Algo,
+		Uptime:      int64(s.m.GetUptime().Seconds()),
+		Pool:        s.m.GetPoolURL(),
+		Worker:      s.m.GetWorker(),
+		Connected:   s.m.IsConnected(),
+		Paused:      s.m.IsPaused(),
+		Hashrate:    s.m.GetHashrateAverage(time.Second * 10),
+		Hashrate1m:  s.m.GetHashrateAverage(time.Minute),
+		Hashrate15m: s.m.GetHashrateAverage(time.Minute * 15),
+		Threads:     s.m.GetThreadHashrates(time.Second * 10),
+		Hashes:      s.m.GetHashes(),
+		Accepted:    s.m.GetAcceptedShares(),
+		Rejected:    s.m.GetRejectedShares(),
+		LatencyMS:   s.m.GetShareLatency().Milliseconds(),
+		Reconnects:  s.m.GetReconnects(),
+	}
+	if stats.Threads == nil {
+		stats.Threads = make([]uint64, s.m.GetThreads())
+	}
+	for reason, n := range s.m.GetRejectReasons() {
+		if invalidShare(reason) {
+			stats.Invalid += n
+		}
+	}
+	// the counters are read one after the other, a share can be rejected in between
+	if stats.Invalid > stats.Rejected {
+		stats.Invalid = stats.Rejected
+	}
+	if job := s.m.GetJob(); job != nil {
+		stats.Difficulty = job.Difficulty
+		stats.Height = uint64(job.Height)
+	}
+	return stats, nil
+}
+
+// invalidShare reports whether the pool rejected the share because it's wrong,
+// unlike stale or duplicate shares which are rejected because they're late.
+func invalidShare(reason string) bool {
+	reason = strings.ToLower(reason)
+	for _, s := range []string{"invalid", "low difficulty", "low diff", "bad"} {
+		if strings.Contains(reason, s) {
+			return true
+		}
+	}
+	return false
+}
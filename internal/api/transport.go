@@ -0,0 +1,208 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.neonxp.dev/jsonrpc2/transport"
+)
+
+// httpTransport is a jsonrpc2 transport which serves the JSON-RPC endpoint on "/"
+// and allows additional handlers to be mounted on the same listener.
+type httpTransport struct {
+	bind     string
+	auth     *auth
+	parallel bool
+	mux      *http.ServeMux
+	// loopback is set if the API only listens on localhost. The Host header is checked then,
+	// so pages can't reach the API by resolving their own domain to 127.0.0.1 (DNS rebinding).
+	loopback bool
+
+	ctx      context.Context
+	resolver transport.Resolver
+}
+
+func newHTTPTransport(bind string, a *auth) *httpTransport {
+	t := &httpTransport{
+		bind:     bind,
+		auth:     a,
+		parallel: true,
+		mux:      http.NewServeMux(),
+	}
+	if host, _, err := net.SplitHostPort(bind); err == nil {
+		t.loopback = loopback(host)
+	}
+	t.mux.HandleFunc("/", t.serveRPC)
+	return t
+}
+
+func (t *httpTransport) handle(pattern string, handler http.Handler) {
+	t.mux.Handle(pattern, handler)
+}
+
+func (t *httpTransport) Run(ctx context.Context, resolver transport.Resolver) error {
+	t.ctx = ctx
+	t.resolver = resolver
+
+	srv := &http.Server{
+		Addr:              t.bind,
+		Handler:           t.authenticate(t.mux),
+		ReadHeaderTimeout: time.Second * 10,
+	}
+	go func() {
+		<-ctx.Done()
+		srv.Close() // nolint: errcheck
+	}()
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// authenticate checks the ip allowlist, the host, the origin and the bearer token of every request
+// and stores the granted role in the request context.
+func (t *httpTransport) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !t.auth.allowIP(r.RemoteAddr) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if t.loopback && !loopback(r.Host) {
+			http.Error(w, "invalid host", http.StatusForbidden)
+			return
+		}
+		if !t.auth.allowOrigin(r.Header.Get("Origin"), r.Host) {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+		if origin := t.auth.corsOrigin(r.Header.Get("Origin")); origin != "" {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+		}
+		if r.Method == http.MethodOptions {
+			// preflight requests never carry credentials
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" && r.URL.Path == "/events" && strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			// browsers can't set headers for websockets
+			token = r.URL.Query().Get("token")
+		}
+		role := t.auth.role(token)
+		if role == roleNone {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="dero-stratum-miner"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(withRole(r.Context(), role)))
+	})
+}
+
+func (t *httpTransport) serveRPC(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	// html forms can't send JSON, so foreign pages can't call the API with a simple request
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt != "application/json" {
+		http.Error(w, "content type must be application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	t.resolver.Resolve(withRole(t.ctx, roleFromContext(r.Context())), r.Body, w, t.parallel)
+}
+
+// tcpTransport is a jsonrpc2 transport for raw TCP connections.
+// If tokens are configured, the first message of a connection must be an auth request:
+//
+//	{"jsonrpc":"2.0","id":0,"method":"auth","params":{"token":"..."}}
+type tcpTransport struct {
+	bind     string
+	auth     *auth
+	parallel bool
+}
+
+type authReq struct {
+	ID     any    `json:"id"`
+	Method string `json:"method"`
+	Params struct {
+		Token string `json:"token"`
+	} `json:"params"`
+}
+
+func (t *tcpTransport) Run(ctx context.Context, resolver transport.Resolver) error {
+	ln, err := net.Listen("tcp", t.bind)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		ln.Close() // nolint: errcheck
+	}()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go t.serve(ctx, conn, resolver)
+	}
+}
+
+func (t *tcpTransport) serve(ctx context.Context, conn net.Conn, resolver transport.Resolver) {
+	defer conn.Close()
+	if !t.auth.allowIP(conn.RemoteAddr().String()) {
+		return
+	}
+
+	rd := bufio.NewReader(conn)
+	role := t.auth.role("")
+	if t.auth.required() {
+		role = t.handshake(conn, rd)
+		if role == roleNone {
+			return
+		}
+	}
+	resolver.Resolve(withRole(ctx, role), rd, conn, t.parallel)
+}
+
+func (t *tcpTransport) handshake(conn net.Conn, rd *bufio.Reader) role {
+	conn.SetReadDeadline(time.Now().Add(time.Second * 10)) // nolint: errcheck
+	line, err := rd.ReadBytes('\n')
+	conn.SetReadDeadline(time.Time{}) // nolint: errcheck
+	if err != nil {
+		return roleNone
+	}
+
+	var req authReq
+	role := roleNone
+	if err := json.Unmarshal(line, &req); err == nil && req.Method == "auth" {
+		role = t.auth.role(req.Params.Token)
+	}
+
+	res := map[string]any{"jsonrpc": "2.0", "id": req.ID}
+	if role == roleNone {
+		res["error"] = map[string]any{"code": codeUnauthorized, "message": "unauthorized"}
+	} else {
+		res["result"] = true
+	}
+	json.NewEncoder(conn).Encode(res) // nolint: errcheck
+	return role
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalesburg/dero-stratum-miner/internal/config"
+)
+
+func TestAuthenticate(t *testing.T) {
+	a, err := newAuth(&config.API{ReadToken: "read", CORSOrigins: []string{"https://dash.example.com"}})
+	require.NoError(t, err)
+	tr := newHTTPTransport("127.0.0.1:8080", a)
+	h := tr.authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, tt := range []struct {
+		name   string
+		url    string
+		header map[string]string
+		code   int
+	}{
+		{name: "token", url: "http://127.0.0.1:8080/", header: map[string]string{"Authorization": "Bearer read"}, code: http.StatusOK},
+		{name: "localhost", url: "http://localhost:8080/", header: map[string]string{"Authorization": "Bearer read"}, code: http.StatusOK},
+		{name: "no token", url: "http://127.0.0.1:8080/", code: http.StatusUnauthorized},
+		{name: "rebinding", url: "http://evil.example.com:8080/", header: map[string]string{"Authorization": "Bearer read"}, code: http.StatusForbidden},
+		{name: "cors origin", url: "http://127.0.0.1:8080/", header: map[string]string{"Authorization": "Bearer read", "Origin": "https://dash.example.com"}, code: http.StatusOK},
+		{name: "same origin", url: "http://127.0.0.1:8080/", header: map[string]string{"Authorization": "Bearer read", "Origin": "http://127.0.0.1:8080"}, code: http.StatusOK},
+		{name: "foreign origin", url: "http://127.0.0.1:8080/", header: map[string]string{"Authorization": "Bearer read", "Origin": "https://evil.example.com"}, code: http.StatusForbidden},
+		{name: "query token", url: "http://127.0.0.1:8080/?token=read", code: http.StatusUnauthorized},
+		{name: "query token for events", url: "http://127.0.0.1:8080/events?token=read", header: map[string]string{"Upgrade": "websocket"}, code: http.StatusOK},
+		{name: "query token without upgrade", url: "http://127.0.0.1:8080/events?token=read", code: http.StatusUnauthorized},
+	} {
+		r := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		for k, v := range tt.header {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		assert.Equal(t, tt.code, w.Code, tt.name)
+	}
+
+	// the host isn't checked if the API is reachable from the network
+	tr = newHTTPTransport("0.0.0.0:8080", a)
+	r := httptest.NewRequest(http.MethodGet, "http://miner.lan:8080/", nil)
+	r.Header.Set("Authorization", "Bearer read")
+	w := httptest.NewRecorder()
+	tr.authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestServeRPCContentType(t *testing.T) {
+	a, err := newAuth(&config.API{})
+	require.NoError(t, err)
+	tr := newHTTPTransport("127.0.0.1:8080", a)
+
+	// a form post of a foreign page
+	r := httptest.NewRequest(http.MethodPost, "http://127.0.0.1:8080/", strings.NewReader(`{"jsonrpc":"2.0","id":0,"method":"miner_pause"}`))
+	r.Header.Set("Content-Type", "text/plain")
+	w := httptest.NewRecorder()
+	tr.serveRPC(w, r)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+}
+
+func TestLoopback(t *testing.T) {
+	for host, want := range map[string]bool{
+		"localhost":        true,
+		"LOCALHOST:8080":   true,
+		"127.0.0.1":        true,
+		"127.0.0.2:8080":   true,
+		"[::1]:8080":       true,
+		"::1":              true,
+		"":                 false,
+		"0.0.0.0":          false,
+		"192.168.1.2:8080": false,
+		"evil.example.com": false,
+	} {
+		assert.Equal(t, want, loopback(host), host)
+	}
+}
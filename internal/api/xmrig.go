@@ -0,0 +1,292 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/klauspost/cpuid/v2"
+	"github.com/whalesburg/dero-stratum-miner/internal/stratum"
+	"github.com/whalesburg/dero-stratum-miner/internal/version"
+)
+
+const xmrigAlgo = "astrobwt/v3"
+
+// xmrig compatible responses of the http API, see https://xmrig.com/docs/miner/api
+// Values which are not available are reported as null.
+
+type XMRigSummary struct {
+	ID         string           `json:"id"`
+	WorkerID   string           `json:"worker_id"`
+	Uptime     int64            `json:"uptime"`
+	Restricted bool             `json:"restricted"`
+	Features   []string         `json:"features"`
+	Results    XMRigResults     `json:"results"`
+	Algo       string           `json:"algo"`
+	Connection XMRigConnection  `json:"connection"`
+	Version    string           `json:"version"`
+	Kind       string           `json:"kind"`
+	UA         string           `json:"ua"`
+	CPU        XMRigCPU         `json:"cpu"`
+	Paused     bool             `json:"paused"`
+	Algorithms []string         `json:"algorithms"`
+	Hashrate   XMRigHashrate    `json:"hashrate"`
+	Hugepages  bool             `json:"hugepages"`
+	DonateLvl  int              `json:"donate_level"`
+	Resources  map[string]int64 `json:"resources"`
+}
+
+type XMRigResults struct {
+	DiffCurrent uint64   `json:"diff_current"`
+	SharesGood  uint64   `json:"shares_good"`
+	SharesTotal uint64   `json:"shares_total"`
+	AvgTime     int64    `json:"avg_time"`
+	AvgTimeMS   int64    `json:"avg_time_ms"`
+	HashesTotal uint64   `json:"hashes_total"`
+	Best        []uint64 `json:"best"`
+	ErrorLog    []string `json:"error_log"`
+}
+
+type XMRigConnection struct {
+	Pool        string   `json:"pool"`
+	IP          *string  `json:"ip"`
+	Uptime      int64    `json:"uptime"`
+	UptimeMS    int64    `json:"uptime_ms"`
+	Ping        int64    `json:"ping"`
+	Failures    int      `json:"failures"`
+	TLS         *string  `json:"tls"`
+	Algo        string   `json:"algo"`
+	Diff        uint64   `json:"diff"`
+	Accepted    uint64   `json:"accepted"`
+	Rejected    uint64   `json:"rejected"`
+	AvgTime     int64    `json:"avg_time"`
+	AvgTimeMS   int64    `json:"avg_time_ms"`
+	HashesTotal uint64   `json:"hashes_total"`
+	ErrorLog    []string `json:"error_log"`
+}
+
+type XMRigCPU struct {
+	Brand    string   `json:"brand"`
+	Family   int      `json:"family"`
+	Model    int      `json:"model"`
+	AES      bool     `json:"aes"`
+	AVX2     bool     `json:"avx2"`
+	X64      bool     `json:"x64"`
+	Bit64    bool     `json:"64_bit"`
+	L2       int      `json:"l2"`
+	L3       int      `json:"l3"`
+	Cores    int      `json:"cores"`
+	Threads  int      `json:"threads"`
+	Packages int      `json:"packages"`
+	Nodes    int      `json:"nodes"`
+	Backend  string   `json:"backend"`
+	MSR      string   `json:"msr"`
+	Assembly string   `json:"assembly"`
+	Arch     string   `json:"arch"`
+	Flags    []string `json:"flags"`
+}
+
+type XMRigHashrate struct {
+	Total   []*float64   `json:"total"`
+	Highest float64      `json:"highest"`
+	Threads [][]*float64 `json:"threads"`
+}
+
+type XMRigBackend struct {
+	Type     string        `json:"type"`
+	Enabled  bool          `json:"enabled"`
+	Algo     string        `json:"algo"`
+	Profile  string        `json:"profile"`
+	HWAES    bool          `json:"hw-aes"`
+	Priority int           `json:"priority"`
+	Hashrate []*float64    `json:"hashrate"`
+	Threads  []XMRigThread `json:"threads"`
+}
+
+type XMRigThread struct {
+	Intensity int        `json:"intensity"`
+	Affinity  int        `json:"affinity"`
+	Hashrate  []*float64 `json:"hashrate"`
+}
+
+func (s *Server) registerXMRig() error {
+	if s.http == nil {
+		return ErrNoHTTPTransport
+	}
+	s.http.handle("/1/summary", jsonHandler(func() any { return s.XMRigSummary() }))
+	s.http.handle("/2/summary", jsonHandler(func() any { return s.XMRigSummary() }))
+	s.http.handle("/2/backends", jsonHandler(func() any { return s.XMRigBackends() }))
+	return nil
+}
+
+func jsonHandler(fn func() any) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(fn()) // nolint: errcheck
+	})
+}
+
+func (s *Server) XMRigSummary() *XMRigSummary {
+	var diff float64
+	if job := s.m.GetJob(); job != nil {
+		diff = float64(job.Difficulty)
+	}
+	accepted := s.m.GetAcceptedShares()
+	connAccepted, connRejected := s.m.GetConnectionShares()
+	pool := s.m.GetPoolURL()
+
+	uptime := s.m.GetUptime()
+	var connUptime, avgTime, connAvgTime time.Duration
+	if since := s.m.GetConnectedSince(); !since.IsZero() {
+		connUptime = time.Since(since)
+	}
+	if accepted > 0 {
+		avgTime = uptime / time.Duration(accepted)
+	}
+	if connAccepted > 0 {
+		connAvgTime = connUptime / time.Duration(connAccepted)
+	}
+
+	features := []string{"api"}
+	if _, tls := stratum.ParseURL(pool); tls {
+		features = append(features, "tls")
+	}
+
+	var threads [][]*float64
+	for _, t := range s.xmrigThreads() {
+		threads = append(threads, t.Hashrate)
+	}
+
+	return &XMRigSummary{
+		ID:         s.m.GetWorker(),
+		WorkerID:   s.m.GetWorker(),
+		Uptime:     int64(uptime.Seconds()),
+		Restricted: !s.control,
+		Features:   features,
+		Results: XMRigResults{
+			DiffCurrent: uint64(diff),
+			SharesGood:  accepted,
+			SharesTotal: s.m.GetTotalShares(),
+			AvgTime:     int64(avgTime.Seconds()),
+			AvgTimeMS:   avgTime.Milliseconds(),
+			HashesTotal: s.m.GetHashes(),
+			Best:        make([]uint64, 10),
+			ErrorLog:    []string{},
+		},
+		Algo: xmrigAlgo,
+		Connection: XMRigConnection{
+			Pool:        pool,
+			Uptime:      int64(connUptime.Seconds()),
+			UptimeMS:    connUptime.Milliseconds(),
+			Ping:        s.m.GetShareLatency().Milliseconds(),
+			Failures:    s.m.GetReconnects(),
+			Algo:        xmrigAlgo,
+			Diff:        uint64(diff),
+			Accepted:    connAccepted,
+			Rejected:    connRejected,
+			AvgTime:     int64(connAvgTime.Seconds()),
+			AvgTimeMS:   connAvgTime.Milliseconds(),
+			HashesTotal: s.m.GetHashes(),
+			ErrorLog:    []string{},
+		},
+		Version:    version.Version,
+		Kind:       "cpu",
+		UA:         "dero-stratum-miner/" + version.Version,
+		CPU:        xmrigCPU(),
+		Paused:     s.m.IsPaused(),
+		Algorithms: []string{xmrigAlgo},
+		Hashrate: XMRigHashrate{
+			Total:   s.xmrigHashrate(),
+			Highest: float64(s.m.GetHighestHashrate()),
+			Threads: threads,
+		},
+		Resources: map[string]int64{},
+	}
+}
+
+func (s *Server) XMRigBackends() []XMRigBackend {
+	return []XMRigBackend{{
+		Type:     "cpu",
+		Enabled:  true,
+		Algo:     xmrigAlgo,
+		Profile:  xmrigAlgo,
+		HWAES:    cpuid.CPU.Supports(cpuid.AESNI),
+		Priority: -1,
+		Hashrate: s.xmrigHashrate(),
+		Threads:  s.xmrigThreads(),
+	}}
+}
+
+func (s *Server) xmrigHashrate() []*float64 {
+	return []*float64{
+		s.xmrigAverage(time.Second * 10),
+		s.xmrigAverage(time.Minute),
+		s.xmrigAverage(time.Minute * 15),
+	}
+}
+
+// xmrigAverage returns the hashrate over the window or nil if the miner doesn't run long enough.
+func (s *Server) xmrigAverage(window time.Duration) *float64 {
+	if s.m.GetUptime() < window {
+		return nil
+	}
+	return hashrate(s.m.GetHashrateAverage(window))
+}
+
+func (s *Server) xmrigThreads() []XMRigThread {
+	short := s.m.GetThreadHashrates(time.Second * 10)
+	var long []uint64
+	if s.m.GetUptime() >= time.Minute {
+		long = s.m.GetThreadHashrates(time.Minute)
+	}
+	threads := make([]XMRigThread, len(short))
+	for i := range short {
+		threads[i] = XMRigThread{
+			Intensity: 1,
+			Affinity:  -1,
+			Hashrate:  []*float64{hashrate(short[i]), nil, nil},
+		}
+		if i < len(long) {
+			threads[i].Hashrate[1] = hashrate(long[i])
+		}
+	}
+	return threads
+}
+
+func hashrate(h uint64) *float64 {
+	f := float64(h)
+	return &f
+}
+
+func xmrigCPU() XMRigCPU {
+	arch := runtime.GOARCH
+	if arch == "amd64" {
+		arch = "x86_64"
+	}
+	is64 := runtime.GOARCH == "amd64" || runtime.GOARCH == "arm64"
+	return XMRigCPU{
+		Brand:    cpuid.CPU.BrandName,
+		Family:   cpuid.CPU.Family,
+		Model:    cpuid.CPU.Model,
+		AES:      cpuid.CPU.Supports(cpuid.AESNI),
+		AVX2:     cpuid.CPU.Supports(cpuid.AVX2),
+		X64:      runtime.GOARCH == "amd64",
+		Bit64:    is64,
+		L2:       cpuid.CPU.Cache.L2,
+		L3:       cpuid.CPU.Cache.L3,
+		Cores:    cpuid.CPU.PhysicalCores,
+		Threads:  cpuid.CPU.LogicalCores,
+		Packages: 1,
+		Nodes:    1,
+		Backend:  "cpuid",
+		MSR:      "none",
+		Assembly: "none",
+		Arch:     arch,
+		Flags:    cpuid.CPU.FeatureSet(),
+	}
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalesburg/dero-stratum-miner/internal/config"
+	miner "github.com/whalesburg/dero-stratum-miner/internal/dero-stratum-miner"
+	"github.com/whalesburg/dero-stratum-miner/internal/stratum"
+)
+
+// xmrigHandler returns the http handler of an API with the xmrig endpoints for a miner which isn't started.
+func xmrigHandler(t *testing.T, pool string) http.Handler {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	cfg := &config.Miner{Wallet: "dero1abc.rig1", PoolURL: pool, Threads: 1}
+	m, err := miner.New(ctx, cancel, cfg, stratum.New(cfg.PoolURL, stratum.WithContext(ctx)), nil, logr.Discard())
+	require.NoError(t, err)
+	s, err := New(ctx, m, &config.API{Transport: "http", Listen: "127.0.0.1:0", XMRig: true}, logr.Discard())
+	require.NoError(t, err)
+	return s.http.authenticate(s.http.mux)
+}
+
+func getJSON(t *testing.T, h http.Handler, path string, v any) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://127.0.0.1"+path, nil))
+	require.Equal(t, http.StatusOK, w.Code, path)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"), path)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), v), path)
+}
+
+func TestXMRigSummary(t *testing.T) {
+	for _, tt := range []struct {
+		pool     string
+		features []string
+	}{
+		{pool: "stratum+tcp://127.0.0.1:1", features: []string{"api"}},
+		{pool: "stratum+tls://127.0.0.1:1", features: []string{"api", "tls"}},
+	} {
+		h := xmrigHandler(t, tt.pool)
+		for _, path := range []string{"/1/summary", "/2/summary"} {
+			var s XMRigSummary
+			getJSON(t, h, path, &s)
+			assert.Equal(t, "rig1", s.WorkerID, path)
+			assert.Equal(t, tt.features, s.Features, path)
+			assert.True(t, s.Restricted, path)
+			assert.Equal(t, xmrigAlgo, s.Algo, path)
+			assert.Equal(t, tt.pool, s.Connection.Pool, path)
+			// not connected and no shares yet
+			assert.Zero(t, s.Connection.Uptime, path)
+			assert.Zero(t, s.Results.AvgTime, path)
+			assert.Zero(t, s.Results.SharesGood, path)
+			// the miner doesn't run long enough for any average
+			assert.Equal(t, []*float64{nil, nil, nil}, s.Hashrate.Total, path)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	xmrigHandler(t, "127.0.0.1:1").ServeHTTP(w, httptest.NewRequest(http.MethodPost, "http://127.0.0.1/2/summary", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestXMRigBackends(t *testing.T) {
+	var backends []XMRigBackend
+	getJSON(t, xmrigHandler(t, "127.0.0.1:1"), "/2/backends", &backends)
+	require.Len(t, backends, 1)
+	assert.Equal(t, "cpu", backends[0].Type)
+	assert.True(t, backends[0].Enabled)
+	assert.Equal(t, xmrigAlgo, backends[0].Algo)
+	assert.Len(t, backends[0].Hashrate, 3)
+}
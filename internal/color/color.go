@@ -0,0 +1,83 @@
+package color
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+
+	"github.com/chzyer/readline"
+)
+
+const (
+	Auto   = "auto"
+	Always = "always"
+	Never  = "never"
+)
+
+const (
+	Reset       = "\033[0m"
+	Bold        = "\033[1m"
+	Red         = "\033[31m"
+	Green       = "\033[32m"
+	Yellow      = "\033[33m"
+	BrightGreen = "\033[92m"
+)
+
+var disabled int32
+
+// Setup decides whether colors are used for the output to f.
+// In auto mode colors are only used if f is a terminal and NO_COLOR isn't set.
+func Setup(mode string, f *os.File) error {
+	enabled, err := Detect(mode, f)
+	if err != nil {
+		return err
+	}
+	Enable(enabled)
+	return nil
+}
+
+// Detect reports whether the mode enables colors for the output to f.
+func Detect(mode string, f *os.File) (bool, error) {
+	switch mode {
+	case Always:
+		return true, nil
+	case Never:
+		return false, nil
+	case Auto, "":
+		if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+			return false, nil
+		}
+		return readline.IsTerminal(int(f.Fd())), nil
+	}
+	return false, fmt.Errorf("invalid color mode %q, must be one of auto, always, never", mode)
+}
+
+// Enable switches colors on or off.
+func Enable(enabled bool) {
+	var v int32 = 1
+	if enabled {
+		v = 0
+	}
+	atomic.StoreInt32(&disabled, v)
+}
+
+// Enabled reports whether colors are used.
+func Enabled() bool {
+	return atomic.LoadInt32(&disabled) == 0
+}
+
+// Code returns the escape code if colors are enabled.
+func Code(code string) string {
+	if !Enabled() {
+		return ""
+	}
+	return code
+}
+
+// Wrap colors s with the escape code if colors are enabled.
+func Wrap(code, s string) string {
+	if !Enabled() {
+		return s
+	}
+	return code + s + Reset
+}
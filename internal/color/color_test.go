@@ -0,0 +1,49 @@
+package color
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetect(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+	defer w.Close()
+
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("TERM", "xterm")
+
+	enabled, err := Detect(Auto, w)
+	require.NoError(t, err)
+	assert.False(t, enabled, "pipes are no terminal")
+
+	enabled, err = Detect(Always, w)
+	require.NoError(t, err)
+	assert.True(t, enabled)
+
+	t.Setenv("NO_COLOR", "1")
+	enabled, err = Detect(Always, w)
+	require.NoError(t, err)
+	assert.True(t, enabled, "the flag wins over NO_COLOR")
+
+	enabled, err = Detect(Never, w)
+	require.NoError(t, err)
+	assert.False(t, enabled)
+
+	_, err = Detect("sometimes", w)
+	assert.Error(t, err)
+}
+
+func TestWrap(t *testing.T) {
+	defer Enable(true)
+
+	Enable(true)
+	assert.Equal(t, Bold+"x"+Reset, Wrap(Bold, "x"))
+	Enable(false)
+	assert.Equal(t, "x", Wrap(Bold, "x"))
+	assert.Equal(t, "", Code(Red))
+}
@@ -1,37 +1,210 @@
 package config
 
+import (
+	"encoding/json"
+	"os"
+)
+
 type Config struct {
-	Miner  *Miner
-	Logger *Logger
-	API    *API
+	Miner     *Miner     `json:"miner"`
+	Logger    *Logger    `json:"logger"`
+	API       *API       `json:"api"`
+	Metrics   *Metrics   `json:"metrics"`
+	Notify    *Notify    `json:"notify"`
+	Watchdog  *Watchdog  `json:"watchdog"`
+	Health    *Health    `json:"health"`
+	Remote    *Remote    `json:"remote"`
+	Telemetry *Telemetry `json:"telemetry"`
+	MQTT      *MQTT      `json:"mqtt"`
+	Journal   *Journal   `json:"journal"`
 }
 
 type Miner struct {
-	Wallet              string
-	Testnet             bool
-	PoolURL             string
-	Threads             int
-	NonInteractive      bool
-	DNS                 string
-	IgnoreTLSValidation bool
+	Wallet              string   `json:"wallet"`
+	Testnet             bool     `json:"testnet"`
+	PoolURL             string   `json:"pool"`
+	Pools               []string `json:"pools"`
+	Threads             int      `json:"threads"`
+	NonInteractive      bool     `json:"non_interactive"`
+	Hotkeys             bool     `json:"hotkeys"`
+	TUI                 bool     `json:"tui"`
+	DNS                 string   `json:"dns"`
+	IgnoreTLSValidation bool     `json:"ignore_tls_validation"`
 }
 
 type Logger struct {
-	Debug     bool
-	CLogLevel int8
+	Debug          bool              `json:"debug"`
+	CLogLevel      int8              `json:"console_log_level"`
+	Levels         map[string]string `json:"levels"` // log levels of components like stratum or api
+	Color          string            `json:"color"`
+	Format         string            `json:"format"`
+	File           string            `json:"file"`
+	FileLevel      int8              `json:"file_log_level"`
+	FileMaxSize    int               `json:"file_max_size"` // in MB
+	FileMaxAge     Duration          `json:"file_max_age"`
+	FileMaxBackups int               `json:"file_max_backups"`
+	FileCompress   bool              `json:"file_compress"`
+	Journald       bool              `json:"journald"`
+	Syslog         string            `json:"syslog"` // udp://, tcp:// or tls://host:port
+	SyslogFacility string            `json:"syslog_facility"`
+	DedupWindow    Duration          `json:"dedup_window"`
+	DedupBurst     int               `json:"dedup_burst"`
 }
 
 type API struct {
-	Transport string
-	Listen    string
-	Enabled   bool
+	Transport   string   `json:"transport"`
+	Listen      string   `json:"listen"`
+	Enabled     bool     `json:"enabled"`
+	Control     bool     `json:"control"`
+	ReadToken   string   `json:"read_token"`
+	AdminToken  string   `json:"admin_token"`
+	CORSOrigins []string `json:"cors_origins"`
+	AllowedIPs  []string `json:"allowed_ips"`
+	XMRig       bool     `json:"xmrig"`
+}
+
+type Metrics struct {
+	Enabled bool   `json:"enabled"`
+	Listen  string `json:"listen"`
+}
+
+type Notify struct {
+	Webhooks        []Webhook `json:"webhooks"`
+	DisconnectAfter Duration  `json:"disconnect_after"`
+	ReconnectStorm  int       `json:"reconnect_storm"` // reconnects within ReconnectWindow
+	ReconnectWindow Duration  `json:"reconnect_window"`
+	RejectStreak    int       `json:"reject_streak"`
+	HashrateDrop    int       `json:"hashrate_drop"` // percent below the baseline
+	NotMiningAfter  Duration  `json:"not_mining_after"`
+	Cooldown        Duration  `json:"cooldown"`
+	Retries         int       `json:"retries"`
+}
+
+type Watchdog struct {
+	Enabled     bool     `json:"enabled"`
+	Stall       Duration `json:"stall"`        // restart the threads if no hashes are computed for this long
+	ShareFactor int      `json:"share_factor"` // reconnect if no share is accepted within this multiple of the expected time
+	Retries     int      `json:"retries"`      // failed recoveries before the miner exits
+}
+
+type Health struct {
+	Enabled     bool     `json:"enabled"`
+	Listen      string   `json:"listen"`
+	Stall       Duration `json:"stall"`        // not alive if no hashes are computed for this long while mining
+	JobAge      Duration `json:"job_age"`      // not ready if no new job is received for this long
+	MinHashrate uint64   `json:"min_hashrate"` // not ready below this hashrate in H/s
+}
+
+type Remote struct {
+	URL       string   `json:"url"`
+	Interval  Duration `json:"interval"`
+	HMACKey   string   `json:"hmac_key"`   // verifies the HMAC-SHA256 signature of the config
+	PublicKey string   `json:"public_key"` // base64 encoded Ed25519 key which verifies the signature of the config
+	Cache     string   `json:"cache"`      // path of the last known good copy
+}
+
+type Telemetry struct {
+	Outputs     []string `json:"outputs"` // urls like influx+http://host:8086/api/v2/write?org=..., influx+udp://, statsd:// or graphite://
+	Interval    Duration `json:"interval"`
+	Prefix      string   `json:"prefix"`
+	InfluxToken string   `json:"influx_token"`
+}
+
+type MQTT struct {
+	Broker          string   `json:"broker"` // url like mqtt://127.0.0.1:1883 or mqtts://broker:8883
+	Prefix          string   `json:"prefix"` // dero-stratum-miner/<hostname> if empty
+	ClientID        string   `json:"client_id"`
+	Username        string   `json:"username"`
+	Password        string   `json:"password"`
+	Interval        Duration `json:"interval"`
+	Commands        bool     `json:"commands"`  // accept commands on the command topic
+	Discovery       bool     `json:"discovery"` // publish Home Assistant discovery payloads
+	DiscoveryPrefix string   `json:"discovery_prefix"`
+}
+
+type Journal struct {
+	Enabled   bool     `json:"enabled"`
+	Dir       string   `json:"dir"`
+	Retention Duration `json:"retention"` // files older than this are removed, 0 keeps them forever
+}
+
+type Webhook struct {
+	URL    string   `json:"url"`
+	Format string   `json:"format"` // generic, discord, slack or telegram, detected from the url if empty
+	Events []string `json:"events"` // all events if empty
 }
 
 // NewEmpty returns a new empty config
 func NewEmpty() *Config {
 	return &Config{
-		Miner:  &Miner{},
-		Logger: &Logger{},
-		API:    &API{},
+		Miner:     &Miner{},
+		Logger:    &Logger{},
+		API:       &API{},
+		Metrics:   &Metrics{},
+		Notify:    &Notify{},
+		Watchdog:  &Watchdog{},
+		Health:    &Health{},
+		Remote:    &Remote{},
+		Telemetry: &Telemetry{},
+		MQTT:      &MQTT{},
+		Journal:   &Journal{},
+	}
+}
+
+// Clone returns a deep copy of the config.
+func (c *Config) Clone() *Config {
+	miner := *c.Miner
+	miner.Pools = append([]string(nil), c.Miner.Pools...)
+	logger := *c.Logger
+	if c.Logger.Levels != nil {
+		logger.Levels = make(map[string]string, len(c.Logger.Levels))
+		for k, v := range c.Logger.Levels {
+			logger.Levels[k] = v
+		}
+	}
+	api := *c.API
+	api.CORSOrigins = append([]string(nil), c.API.CORSOrigins...)
+	api.AllowedIPs = append([]string(nil), c.API.AllowedIPs...)
+	metrics := *c.Metrics
+	notify := *c.Notify
+	notify.Webhooks = make([]Webhook, len(c.Notify.Webhooks))
+	for i, w := range c.Notify.Webhooks {
+		w.Events = append([]string(nil), w.Events...)
+		notify.Webhooks[i] = w
 	}
+	watchdog := *c.Watchdog
+	health := *c.Health
+	remote := *c.Remote
+	telemetry := *c.Telemetry
+	telemetry.Outputs = append([]string(nil), c.Telemetry.Outputs...)
+	mqtt := *c.MQTT
+	journal := *c.Journal
+	return &Config{
+		Miner:     &miner,
+		Logger:    &logger,
+		API:       &api,
+		Metrics:   &metrics,
+		Notify:    &notify,
+		Watchdog:  &watchdog,
+		Health:    &health,
+		Remote:    &remote,
+		Telemetry: &telemetry,
+		MQTT:      &mqtt,
+		Journal:   &journal,
+	}
+}
+
+// Load reads a JSON config file and merges it over the given config.
+// Options which are not set in the file keep their current value.
+func Load(path string, c *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return Merge(data, c)
+}
+
+// Merge merges a JSON config over the given config.
+func Merge(data []byte, c *Config) error {
+	return json.Unmarshal(data, c)
 }
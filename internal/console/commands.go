@@ -0,0 +1,154 @@
+package console
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/chzyer/readline"
+	"github.com/whalesburg/dero-stratum-miner/internal/color"
+)
+
+var (
+	ErrUnknownCommand = errors.New("unknown command")
+	ErrUsage          = errors.New("invalid arguments")
+)
+
+// Command is a command which can be run from the interactive console.
+type Command struct {
+	Name    string
+	Aliases []string
+	// Args describes the arguments in the help text, e.g. "[n]"
+	Args string
+	Help string
+	// Complete returns the candidates for the first argument, it's optional.
+	Complete func() []string
+	Run      func(w io.Writer, args []string) error
+}
+
+// Registry holds the console commands.
+// It generates the help text and implements readline.AutoCompleter.
+type Registry struct {
+	mu       sync.RWMutex
+	commands []*Command
+	names    map[string]*Command
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		names: make(map[string]*Command),
+	}
+}
+
+// Register adds commands to the registry. Existing commands with the same name are replaced.
+func (r *Registry) Register(cmds ...*Command) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, cmd := range cmds {
+		if old, ok := r.names[cmd.Name]; ok {
+			r.remove(old)
+		}
+		r.commands = append(r.commands, cmd)
+		for _, name := range append([]string{cmd.Name}, cmd.Aliases...) {
+			r.names[name] = cmd
+		}
+	}
+}
+
+func (r *Registry) remove(cmd *Command) {
+	for i, c := range r.commands {
+		if c == cmd {
+			r.commands = append(r.commands[:i], r.commands[i+1:]...)
+			break
+		}
+	}
+	for name, c := range r.names {
+		if c == cmd {
+			delete(r.names, name)
+		}
+	}
+}
+
+// Lookup returns the command with the given name or alias.
+func (r *Registry) Lookup(name string) (*Command, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	cmd, ok := r.names[strings.ToLower(name)]
+	return cmd, ok
+}
+
+// Commands returns all commands sorted by name.
+func (r *Registry) Commands() []*Command {
+	r.mu.RLock()
+	cmds := append([]*Command(nil), r.commands...)
+	r.mu.RUnlock()
+	sort.Slice(cmds, func(i, j int) bool { return cmds[i].Name < cmds[j].Name })
+	return cmds
+}
+
+// Exec parses the line and runs the matching command.
+// Empty lines are ignored.
+func (r *Registry) Exec(w io.Writer, line string) error {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+	cmd, ok := r.Lookup(fields[0])
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownCommand, fields[0])
+	}
+	err := cmd.Run(w, fields[1:])
+	if errors.Is(err, ErrUsage) {
+		return fmt.Errorf("usage: %s %s", cmd.Name, cmd.Args)
+	}
+	return err
+}
+
+// Usage writes the help text of all commands.
+func (r *Registry) Usage(w io.Writer) {
+	io.WriteString(w, "commands:\n") // nolint: errcheck
+	for _, cmd := range r.Commands() {
+		r.writeUsage(w, cmd)
+	}
+}
+
+// CommandUsage writes the help text of a single command.
+func (r *Registry) CommandUsage(w io.Writer, name string) error {
+	cmd, ok := r.Lookup(name)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownCommand, name)
+	}
+	r.writeUsage(w, cmd)
+	if len(cmd.Aliases) > 0 {
+		fmt.Fprintf(w, "\taliases: %s\n", strings.Join(cmd.Aliases, ", ")) // nolint: errcheck
+	}
+	return nil
+}
+
+func (r *Registry) writeUsage(w io.Writer, cmd *Command) {
+	name := cmd.Name
+	if cmd.Args != "" {
+		name += " " + cmd.Args
+	}
+	fmt.Fprintf(w, "\t%s%s\n", color.Wrap(color.Bold, fmt.Sprintf("%-18s", name)), cmd.Help) // nolint: errcheck
+}
+
+// Do implements readline.AutoCompleter. Commands complete by name or alias and,
+// if they define Complete, by their first argument.
+func (r *Registry) Do(line []rune, pos int) ([][]rune, int) {
+	items := make([]readline.PrefixCompleterInterface, 0)
+	for _, cmd := range r.Commands() {
+		var args []readline.PrefixCompleterInterface
+		if cmd.Complete != nil {
+			complete := cmd.Complete
+			args = append(args, readline.PcItemDynamic(func(string) []string { return complete() }))
+		}
+		for _, name := range append([]string{cmd.Name}, cmd.Aliases...) {
+			items = append(items, readline.PcItem(name, args...))
+		}
+	}
+	return readline.NewPrefixCompleter(items...).Do(line, pos)
+}
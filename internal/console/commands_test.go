@@ -0,0 +1,100 @@
+package console
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRegistry(got *[]string) *Registry {
+	r := NewRegistry()
+	r.Register(
+		&Command{
+			Name:    "exit",
+			Aliases: []string{"quit"},
+			Help:    "Quit the miner",
+			Run: func(io.Writer, []string) error {
+				*got = append(*got, "exit")
+				return nil
+			},
+		},
+		&Command{
+			Name:     "threads",
+			Args:     "[n]",
+			Help:     "Change the threads",
+			Complete: func() []string { return []string{"1", "2"} },
+			Run: func(_ io.Writer, args []string) error {
+				if len(args) != 1 {
+					return ErrUsage
+				}
+				*got = append(*got, "threads "+args[0])
+				return nil
+			},
+		},
+	)
+	return r
+}
+
+func TestRegistryExec(t *testing.T) {
+	var got []string
+	r := newTestRegistry(&got)
+
+	require.NoError(t, r.Exec(io.Discard, "  threads 4 "))
+	require.NoError(t, r.Exec(io.Discard, "QUIT"))
+	require.NoError(t, r.Exec(io.Discard, ""))
+	assert.Equal(t, []string{"threads 4", "exit"}, got)
+
+	err := r.Exec(io.Discard, "say hello")
+	assert.True(t, errors.Is(err, ErrUnknownCommand))
+	assert.EqualError(t, r.Exec(io.Discard, "threads"), "usage: threads [n]")
+}
+
+func TestRegistryUsage(t *testing.T) {
+	r := newTestRegistry(new([]string))
+
+	var b bytes.Buffer
+	r.Usage(&b)
+	assert.Contains(t, b.String(), "threads [n]")
+	assert.Contains(t, b.String(), "Quit the miner")
+	assert.Less(t, bytes.Index(b.Bytes(), []byte("exit")), bytes.Index(b.Bytes(), []byte("threads")))
+
+	b.Reset()
+	require.NoError(t, r.CommandUsage(&b, "quit"))
+	assert.Contains(t, b.String(), "aliases: quit")
+}
+
+func TestRegistryReplace(t *testing.T) {
+	r := newTestRegistry(new([]string))
+	r.Register(&Command{Name: "threads", Run: func(io.Writer, []string) error { return nil }})
+
+	assert.Len(t, r.Commands(), 2)
+	assert.NoError(t, r.Exec(io.Discard, "threads"))
+}
+
+func TestRegistryComplete(t *testing.T) {
+	r := newTestRegistry(new([]string))
+
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{line: "thr", want: []string{"eads "}},
+		{line: "q", want: []string{"uit "}},
+		{line: "threads ", want: []string{"1 ", "2 "}},
+		{line: "exit ", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, _ := r.Do([]rune(tt.line), len(tt.line))
+			var s []string
+			for _, g := range got {
+				s = append(s, string(g))
+			}
+			assert.Equal(t, tt.want, s)
+		})
+	}
+}
@@ -5,13 +5,15 @@ import (
 	"path/filepath"
 
 	"github.com/chzyer/readline"
+	"github.com/whalesburg/dero-stratum-miner/internal/color"
 )
 
+// New creates the readline instance of the interactive console.
+// Tab completion is provided by the Registry of the commands, see SetCommands.
 func New() (*readline.Instance, error) {
 	l, err := readline.NewEx(&readline.Config{
-		Prompt:          "\033[92mDero-Stratum-Miner:\033[32m>>>\033[0m ",
+		Prompt:          color.Code(color.BrightGreen) + "Dero-Stratum-Miner:" + color.Code(color.Green) + ">>>" + color.Code(color.Reset) + " ",
 		HistoryFile:     filepath.Join(os.TempDir(), "dero_stratum_miner_history.tmp"),
-		AutoComplete:    completer,
 		InterruptPrompt: "^C",
 		EOFPrompt:       "exit",
 
@@ -24,15 +26,6 @@ func New() (*readline.Instance, error) {
 	return l, nil
 }
 
-var completer = readline.NewPrefixCompleter(
-	readline.PcItem("help"),
-	readline.PcItem("status"),
-	readline.PcItem("version"),
-	readline.PcItem("bye"),
-	readline.PcItem("exit"),
-	readline.PcItem("quit"),
-)
-
 func filterInput(r rune) (rune, bool) {
 	switch r {
 	// block CtrlZ feature
@@ -41,3 +34,9 @@ func filterInput(r rune) (rune, bool) {
 	}
 	return r, true
 }
+
+// SetCommands enables tab completion for the commands of the registry.
+// It must be called before the console starts reading.
+func SetCommands(l *readline.Instance, r *Registry) {
+	l.Config.AutoComplete = r
+}
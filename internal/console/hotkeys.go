@@ -0,0 +1,128 @@
+package console
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/chzyer/readline"
+	"github.com/whalesburg/dero-stratum-miner/internal/color"
+)
+
+// CommandModeKey leaves the hotkey mode.
+const CommandModeKey = ':'
+
+// Hotkey runs a console command if the key is pressed.
+type Hotkey struct {
+	Key     rune
+	Command string
+}
+
+// Hotkeys runs console commands on single key presses.
+// While enabled, all printable keys are consumed so typed commands don't work until the mode is switched off again.
+type Hotkeys struct {
+	enabled int32
+	mu      sync.RWMutex
+	keys    []Hotkey
+	pressed chan string
+	toggled func(enabled bool)
+}
+
+func NewHotkeys(keys ...Hotkey) *Hotkeys {
+	return &Hotkeys{
+		keys:    keys,
+		pressed: make(chan string, 8),
+	}
+}
+
+// Pressed returns the commands of the pressed hotkeys.
+func (h *Hotkeys) Pressed() <-chan string {
+	return h.pressed
+}
+
+// OnToggle sets a function which is called if the hotkey mode is switched on or off.
+func (h *Hotkeys) OnToggle(fn func(enabled bool)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.toggled = fn
+}
+
+// Enabled reports whether the hotkey mode is active.
+func (h *Hotkeys) Enabled() bool {
+	return atomic.LoadInt32(&h.enabled) == 1
+}
+
+// SetEnabled switches the hotkey mode on or off.
+func (h *Hotkeys) SetEnabled(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	if atomic.SwapInt32(&h.enabled, v) == v {
+		return
+	}
+	h.mu.RLock()
+	fn := h.toggled
+	h.mu.RUnlock()
+	if fn != nil {
+		fn(enabled)
+	}
+}
+
+func (h *Hotkeys) command(r rune) (string, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, k := range h.keys {
+		if k.Key == r {
+			return k.Command, true
+		}
+	}
+	return "", false
+}
+
+// Filter is used as readline FuncFilterInputRune.
+func (h *Hotkeys) Filter(r rune) (rune, bool) {
+	// control keys like enter or ctrl-c keep working
+	if !h.Enabled() || r < 0x20 {
+		return r, true
+	}
+	if r == CommandModeKey {
+		h.SetEnabled(false)
+		return r, false
+	}
+	if cmd, ok := h.command(r); ok {
+		select {
+		case h.pressed <- cmd:
+		default:
+			// drop the key if the commands can't keep up
+		}
+	}
+	return r, false
+}
+
+// Usage writes the help text of the hotkeys.
+func (h *Hotkeys) Usage(w io.Writer) {
+	h.mu.RLock()
+	keys := append([]Hotkey(nil), h.keys...)
+	h.mu.RUnlock()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Key < keys[j].Key })
+
+	io.WriteString(w, "hotkeys:\n") // nolint: errcheck
+	for _, k := range keys {
+		fmt.Fprintf(w, "\t%s\t%s\n", color.Wrap(color.Bold, string(k.Key)), k.Command) // nolint: errcheck
+	}
+	fmt.Fprintf(w, "\t%s\tback to typing commands\n", color.Wrap(color.Bold, string(CommandModeKey))) // nolint: errcheck
+}
+
+// SetHotkeys installs the hotkeys as input filter of the console.
+// It must be called before the console starts reading.
+func SetHotkeys(l *readline.Instance, h *Hotkeys) {
+	l.Config.FuncFilterInputRune = func(r rune) (rune, bool) {
+		if r, ok := filterInput(r); !ok {
+			return r, false
+		}
+		return h.Filter(r)
+	}
+}
@@ -0,0 +1,38 @@
+package console
+
+import (
+	"testing"
+
+	"github.com/chzyer/readline"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHotkeysFilter(t *testing.T) {
+	h := NewHotkeys(Hotkey{Key: 'h', Command: "hashrate"})
+	var toggles []bool
+	h.OnToggle(func(enabled bool) { toggles = append(toggles, enabled) })
+
+	// typed commands pass through while the mode is off
+	r, ok := h.Filter('h')
+	assert.True(t, ok)
+	assert.Equal(t, 'h', r)
+	assert.Empty(t, h.Pressed())
+
+	h.SetEnabled(true)
+	_, ok = h.Filter('h')
+	assert.False(t, ok)
+	assert.Equal(t, "hashrate", <-h.Pressed())
+
+	// unknown keys are consumed without running a command
+	_, ok = h.Filter('x')
+	assert.False(t, ok)
+	assert.Empty(t, h.Pressed())
+
+	_, ok = h.Filter(readline.CharInterrupt)
+	assert.True(t, ok)
+
+	_, ok = h.Filter(CommandModeKey)
+	assert.False(t, ok)
+	assert.False(t, h.Enabled())
+	assert.Equal(t, []bool{true, false}, toggles)
+}
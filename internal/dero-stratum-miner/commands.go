@@ -0,0 +1,334 @@
+package miner
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jon4hz/hashconv"
+	"github.com/whalesburg/dero-stratum-miner/internal/console"
+	"github.com/whalesburg/dero-stratum-miner/internal/version"
+)
+
+// LogLevel changes the verbosity of the logger at runtime.
+// Components are the names of the loggers like stratum or api, they can have their own level.
+type LogLevel interface {
+	Level() string
+	SetLevel(level string) error
+	Components() map[string]string
+	SetComponent(name, level string) error
+}
+
+// SetLogLevel enables the loglevel console command and API methods.
+func (c *Client) SetLogLevel(l LogLevel) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.logLevel = l
+}
+
+// Commands returns the registry of the console commands.
+func (c *Client) Commands() *console.Registry {
+	return c.commands
+}
+
+func (c *Client) registerCommands() {
+	c.commands.Register(
+		&console.Command{
+			Name:     "help",
+			Args:     "[command]",
+			Help:     "this help",
+			Complete: c.commandNames,
+			Run:      c.cmdHelp,
+		},
+		&console.Command{
+			Name: "version",
+			Help: "Show version",
+			Run: func(w io.Writer, _ []string) error {
+				fmt.Fprintf(w, "Version %s OS:%s ARCH:%s\n", version.Version, runtime.GOOS, runtime.GOARCH) // nolint: errcheck
+				return nil
+			},
+		},
+		&console.Command{
+			Name:    "exit",
+			Aliases: []string{"quit", "bye"},
+			Help:    "Quit the miner",
+			Run: func(io.Writer, []string) error {
+				c.Shutdown()
+				return nil
+			},
+		},
+		&console.Command{
+			Name: "status",
+			Help: "Show a summary of the miner",
+			Run:  c.cmdStatus,
+		},
+		&console.Command{
+			Name: "hashrate",
+			Help: "Show the hashrate of all threads",
+			Run:  c.cmdHashrate,
+		},
+		&console.Command{
+			Name: "shares",
+			Help: "Show share statistics and reject reasons",
+			Run:  c.cmdShares,
+		},
+		&console.Command{
+			Name: "pool",
+			Help: "Show the pool connection",
+			Run:  c.cmdPool,
+		},
+		&console.Command{
+			Name: "job",
+			Help: "Show the current job",
+			Run:  c.cmdJob,
+		},
+		&console.Command{
+			Name: "pause",
+			Help: "Pause mining",
+			Run: func(w io.Writer, _ []string) error {
+				c.Pause()
+				return nil
+			},
+		},
+		&console.Command{
+			Name: "resume",
+			Help: "Resume mining",
+			Run: func(w io.Writer, _ []string) error {
+				c.Resume()
+				return nil
+			},
+		},
+		&console.Command{
+			Name:     "threads",
+			Args:     "[n]",
+			Help:     "Show or change the number of mining threads",
+			Complete: func() []string { return numbers(1, runtime.GOMAXPROCS(0)) },
+			Run:      c.cmdThreads,
+		},
+		&console.Command{
+			Name: "reconnect",
+			Help: "Reconnect to the pool",
+			Run: func(w io.Writer, _ []string) error {
+				c.Reconnect()
+				return nil
+			},
+		},
+		&console.Command{
+			Name:     "hotkeys",
+			Args:     "[on|off]",
+			Help:     "Switch to single key commands",
+			Complete: func() []string { return []string{"on", "off"} },
+			Run:      c.cmdHotkeys,
+		},
+		&console.Command{
+			Name:     "loglevel",
+			Args:     "[component] [level]",
+			Help:     "Show or change the console log levels",
+			Complete: func() []string { return append(levelNames(), logComponents...) },
+			Run:      c.cmdLogLevel,
+		},
+	)
+}
+
+func (c *Client) commandNames() []string {
+	var names []string
+	for _, cmd := range c.commands.Commands() {
+		names = append(names, cmd.Name)
+	}
+	return names
+}
+
+func numbers(from, to int) []string {
+	var s []string
+	for i := from; i <= to; i++ {
+		s = append(s, strconv.Itoa(i))
+	}
+	return s
+}
+
+func formatHashrate(h uint64) string {
+	return fmt.Sprintf("%s/s", hashconv.Format(int64(h)))
+}
+
+func (c *Client) cmdHelp(w io.Writer, args []string) error {
+	if len(args) > 0 {
+		return c.commands.CommandUsage(w, args[0])
+	}
+	c.commands.Usage(w)
+	return nil
+}
+
+func (c *Client) cmdStatus(w io.Writer, _ []string) error {
+	state := "mining"
+	switch {
+	case c.IsPaused():
+		state = "paused"
+	case !c.IsConnected():
+		state = "disconnected"
+	}
+	network := "mainnet"
+	if c.config.Testnet {
+		network = "testnet"
+	}
+
+	fmt.Fprintf(w, "Version:    %s (%s)\n", version.Version, network)                                                                                          // nolint: errcheck
+	fmt.Fprintf(w, "State:      %s\n", state)                                                                                                                  // nolint: errcheck
+	fmt.Fprintf(w, "Uptime:     %s\n", c.GetUptime().Truncate(time.Second))                                                                                    // nolint: errcheck
+	fmt.Fprintf(w, "Pool:       %s\n", c.GetPoolURL())                                                                                                         // nolint: errcheck
+	fmt.Fprintf(w, "Worker:     %s\n", c.GetWorker())                                                                                                          // nolint: errcheck
+	fmt.Fprintf(w, "Threads:    %d\n", c.GetThreads())                                                                                                         // nolint: errcheck
+	fmt.Fprintf(w, "Hashrate:   %s (60s) %s (15m)\n", formatHashrate(c.GetHashrateAverage(time.Minute)), formatHashrate(c.GetHashrateAverage(time.Minute*15))) // nolint: errcheck
+	if job := c.GetJob(); job != nil {
+		fmt.Fprintf(w, "Height:     %.0f\n", job.Height)   // nolint: errcheck
+		fmt.Fprintf(w, "Difficulty: %d\n", job.Difficulty) // nolint: errcheck
+	}
+	fmt.Fprintf(w, "Shares:     %d accepted, %d rejected\n", c.GetAcceptedShares(), c.GetRejectedShares()) // nolint: errcheck
+	fmt.Fprintf(w, "Latency:    %s\n", c.GetShareLatency().Truncate(time.Millisecond))                     // nolint: errcheck
+	return nil
+}
+
+func (c *Client) cmdHashrate(w io.Writer, _ []string) error {
+	windows := []time.Duration{time.Second * 10, time.Minute, time.Minute * 15}
+	// the hashrate of the threads is only kept for one minute
+	threadWindows := windows[:2]
+	threads := make([][]uint64, len(threadWindows))
+	for i, window := range threadWindows {
+		threads[i] = c.GetThreadHashrates(window)
+	}
+
+	fmt.Fprintf(w, "%-8s %14s %14s %14s\n", "thread", "10s", "60s", "15m") // nolint: errcheck
+	for tid := 0; tid < c.GetThreads(); tid++ {
+		fmt.Fprintf(w, "%-8d", tid) // nolint: errcheck
+		for i := range threadWindows {
+			var h uint64
+			if tid < len(threads[i]) {
+				h = threads[i][tid]
+			}
+			fmt.Fprintf(w, " %14s", formatHashrate(h)) // nolint: errcheck
+		}
+		fmt.Fprintln(w) // nolint: errcheck
+	}
+	fmt.Fprintf(w, "%-8s", "total") // nolint: errcheck
+	for _, window := range windows {
+		fmt.Fprintf(w, " %14s", formatHashrate(c.GetHashrateAverage(window))) // nolint: errcheck
+	}
+	fmt.Fprintf(w, "\nhighest: %s\n", formatHashrate(c.GetHighestHashrate())) // nolint: errcheck
+	return nil
+}
+
+func (c *Client) cmdShares(w io.Writer, _ []string) error {
+	accepted, rejected := c.GetAcceptedShares(), c.GetRejectedShares()
+	fmt.Fprintf(w, "Submitted: %d\n", c.GetSubmittedShares()) // nolint: errcheck
+	fmt.Fprintf(w, "Accepted:  %d\n", accepted)               // nolint: errcheck
+	fmt.Fprintf(w, "Rejected:  %d\n", rejected)               // nolint: errcheck
+	if total := accepted + rejected; total > 0 {
+		fmt.Fprintf(w, "Rate:      %.2f%% accepted\n", float64(accepted)/float64(total)*100) // nolint: errcheck
+	}
+
+	reasons := c.GetRejectReasons()
+	if len(reasons) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(reasons))
+	for reason := range reasons {
+		keys = append(keys, reason)
+	}
+	sort.Slice(keys, func(i, j int) bool { return reasons[keys[i]] > reasons[keys[j]] })
+	fmt.Fprintln(w, "Reject reasons:") // nolint: errcheck
+	for _, reason := range keys {
+		fmt.Fprintf(w, "\t%6d  %s\n", reasons[reason], reason) // nolint: errcheck
+	}
+	return nil
+}
+
+func (c *Client) cmdPool(w io.Writer, _ []string) error {
+	fmt.Fprintf(w, "Pool:       %s\n", c.GetPoolURL()) // nolint: errcheck
+	if c.IsConnected() {
+		fmt.Fprintf(w, "Connected:  since %s (%s)\n", c.GetConnectedSince().Format(time.RFC3339), time.Since(c.GetConnectedSince()).Truncate(time.Second)) // nolint: errcheck
+	} else {
+		fmt.Fprintln(w, "Connected:  no") // nolint: errcheck
+	}
+	fmt.Fprintf(w, "Reconnects: %d\n", c.GetReconnects())                              // nolint: errcheck
+	fmt.Fprintf(w, "Latency:    %s\n", c.GetShareLatency().Truncate(time.Millisecond)) // nolint: errcheck
+	if pools := c.GetPools(); len(pools) > 1 {
+		fmt.Fprintln(w, "Pools:") // nolint: errcheck
+		for _, p := range pools {
+			fmt.Fprintf(w, "\t%s\n", p) // nolint: errcheck
+		}
+	}
+	return nil
+}
+
+func (c *Client) cmdJob(w io.Writer, _ []string) error {
+	job := c.GetJob()
+	if job == nil {
+		fmt.Fprintln(w, "no job received yet") // nolint: errcheck
+		return nil
+	}
+	fmt.Fprintf(w, "Job:        %s\n", job.ID)                   // nolint: errcheck
+	fmt.Fprintf(w, "Height:     %.0f\n", job.Height)             // nolint: errcheck
+	fmt.Fprintf(w, "Difficulty: %d\n", job.Difficulty)           // nolint: errcheck
+	fmt.Fprintf(w, "Target:     %s\n", job.Target)               // nolint: errcheck
+	fmt.Fprintf(w, "Received:   %d jobs\n", c.GetJobsReceived()) // nolint: errcheck
+	return nil
+}
+
+func (c *Client) cmdThreads(w io.Writer, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintf(w, "Threads: %d (max: %d)\n", c.GetThreads(), runtime.GOMAXPROCS(0)) // nolint: errcheck
+		return nil
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		return console.ErrUsage
+	}
+	return c.SetThreads(n)
+}
+
+func (c *Client) cmdLogLevel(w io.Writer, args []string) error {
+	switch len(args) {
+	case 0:
+		levels, err := c.GetLogLevels()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%-10s %s\n", "console", levels.Console) // nolint: errcheck
+		names := make([]string, 0, len(levels.Components))
+		for name := range levels.Components {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(w, "%-10s %s\n", name, levels.Components[name]) // nolint: errcheck
+		}
+		return nil
+	case 1:
+		return c.ChangeLogLevel("", args[0])
+	case 2:
+		return c.ChangeLogLevel(args[0], args[1])
+	}
+	return console.ErrUsage
+}
+
+func levelNames() []string {
+	return []string{"debug", "info", "warn", "error", "default"}
+}
+
+func (c *Client) cmdHotkeys(w io.Writer, args []string) error {
+	if len(args) == 0 {
+		c.hotkeys.Usage(w)
+		return nil
+	}
+	switch args[0] {
+	case "on":
+		c.hotkeys.SetEnabled(true)
+	case "off":
+		c.hotkeys.SetEnabled(false)
+	default:
+		return console.ErrUsage
+	}
+	return nil
+}
@@ -1,27 +1,23 @@
 package miner
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
-	"runtime"
-	"strconv"
-	"strings"
 
 	"github.com/chzyer/readline"
-	"github.com/whalesburg/dero-stratum-miner/internal/version"
+	"github.com/whalesburg/dero-stratum-miner/internal/color"
+	"github.com/whalesburg/dero-stratum-miner/internal/console"
 )
 
-func usage(w io.Writer) {
-	io.WriteString(w, "commands:\n")                               // nolint: errcheck
-	io.WriteString(w, "\t\033[1mhelp\033[0m\t\tthis help\n")       // nolint: errcheck
-	io.WriteString(w, "\t\033[1mbye\033[0m\t\tQuit the miner\n")   // nolint: errcheck
-	io.WriteString(w, "\t\033[1mversion\033[0m\t\tShow version\n") // nolint: errcheck
-	io.WriteString(w, "\t\033[1mexit\033[0m\t\tQuit the miner\n")  // nolint: errcheck
-	io.WriteString(w, "\t\033[1mquit\033[0m\t\tQuit the miner\n")  // nolint: errcheck
-}
-
 func (c *Client) startConsole() {
+	console.SetCommands(c.console, c.commands)
+	console.SetHotkeys(c.console, c.hotkeys)
+	go c.handleHotkeys()
+	c.hotkeys.SetEnabled(c.config.Hotkeys)
+
 	for {
 		line, err := c.console.Readline()
 		if err == readline.ErrInterrupt {
@@ -38,38 +34,24 @@ func (c *Client) startConsole() {
 			break
 		}
 
-		line = strings.TrimSpace(line)
-		lineParts := strings.Fields(line)
-
-		command := ""
-		if len(lineParts) >= 1 {
-			command = strings.ToLower(lineParts[0])
+		c.runCommand(line)
+		if c.ctx.Err() != nil {
+			return
 		}
+	}
+}
 
-		switch {
-		case line == "help":
-			usage(c.console.Stderr())
-
-		case strings.HasPrefix(line, "say"):
-			line := strings.TrimSpace(line[3:])
-			if len(line) == 0 {
-				fmt.Println("say what?")
-				break
-			}
-		case command == "version":
-			fmt.Printf("Version %s OS:%s ARCH:%s \n", version.Version, runtime.GOOS, runtime.GOARCH)
-
-		case strings.ToLower(line) == "bye":
-			fallthrough
-		case strings.ToLower(line) == "exit":
-			fallthrough
-		case strings.ToLower(line) == "quit":
-			c.cancel()
-			os.Exit(0)
-		case line == "":
-		default:
-			fmt.Println("you said:", strconv.Quote(line))
+// runCommand runs a console command. The output is written at once, readline redraws the prompt after every write.
+func (c *Client) runCommand(line string) {
+	var b bytes.Buffer
+	if err := c.commands.Exec(&b, line); err != nil {
+		if errors.Is(err, console.ErrUnknownCommand) {
+			err = fmt.Errorf("%w, type help to list all commands", err)
 		}
+		fmt.Fprintln(&b, err) // nolint: errcheck
+	}
+	if b.Len() > 0 {
+		c.console.Stdout().Write(b.Bytes()) // nolint: errcheck
 	}
 }
 
@@ -77,6 +59,43 @@ func (c *Client) setPrompt(heightString, diffString, miningString, testnetString
 	if c.console == nil {
 		return
 	}
-	c.console.SetPrompt(fmt.Sprintf("\033[1m\033[32mDero-Stratum-Miner: \033[0m%s %s \033[33mShares %d Rejected %d \033[32m%s>%s>>\033[0m ", heightString, diffString, c.GetTotalShares(), c.GetRejectedShares(), miningString, testnetString))
+	c.console.SetPrompt(fmt.Sprintf("%sDero-Stratum-Miner: %s%s %s %sShares %d Rejected %d %s%s>%s>>%s ",
+		color.Code(color.Bold+color.Green), color.Code(color.Reset),
+		heightString, diffString,
+		color.Code(color.Yellow), c.GetTotalShares(), c.GetRejectedShares(),
+		color.Code(color.Green), miningString, testnetString, color.Code(color.Reset),
+	))
 	c.console.Refresh()
 }
+
+func (c *Client) registerHotkeys() {
+	c.hotkeys = console.NewHotkeys(
+		console.Hotkey{Key: 'h', Command: "hashrate"},
+		console.Hotkey{Key: 's', Command: "shares"},
+		console.Hotkey{Key: 'p', Command: "pause"},
+		console.Hotkey{Key: 'r', Command: "resume"},
+		console.Hotkey{Key: 'c', Command: "pool"},
+		console.Hotkey{Key: 'j', Command: "job"},
+		console.Hotkey{Key: '?', Command: "hotkeys"},
+	)
+	c.hotkeys.OnToggle(func(enabled bool) {
+		if enabled {
+			c.logger.Info(fmt.Sprintf("Hotkeys enabled, press ? for help and %c to type commands", console.CommandModeKey))
+		} else {
+			c.logger.Info("Hotkeys disabled")
+		}
+	})
+}
+
+// handleHotkeys runs the commands of the pressed hotkeys.
+// They can't be run by the input filter itself, it's called from the input loop of readline which must not block.
+func (c *Client) handleHotkeys() {
+	for {
+		select {
+		case cmd := <-c.hotkeys.Pressed():
+			c.runCommand(cmd)
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package miner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+
+	"github.com/whalesburg/dero-stratum-miner/internal/config"
+	"github.com/whalesburg/dero-stratum-miner/internal/logging"
+)
+
+var (
+	ErrUnknownPool  = errors.New("pool is not configured")
+	ErrNoReloadFunc = errors.New("no config file to reload from")
+	ErrNoLogLevel   = errors.New("the log level can't be changed")
+)
+
+// Pause stops all mining threads from hashing until Resume is called.
+func (c *Client) Pause() {
+	if atomic.CompareAndSwapInt32(&c.paused, 0, 1) {
+		c.logger.Info("Mining paused")
+		c.emit(EventPaused, nil)
+	}
+}
+
+// Resume continues mining after Pause was called.
+func (c *Client) Resume() {
+	if atomic.CompareAndSwapInt32(&c.paused, 1, 0) {
+		c.logger.Info("Mining resumed")
+		c.emit(EventResumed, nil)
+	}
+}
+
+// IsPaused reports whether mining is paused.
+func (c *Client) IsPaused() bool {
+	return atomic.LoadInt32(&c.paused) == 1
+}
+
+// GetThreads returns the number of running mining threads.
+func (c *Client) GetThreads() int {
+	c.threadsMu.Lock()
+	defer c.threadsMu.Unlock()
+	return len(c.threadCancels)
+}
+
+// SetThreads starts or stops mining threads until n threads are running.
+func (c *Client) SetThreads(n int) error {
+	limit := runtime.GOMAXPROCS(0)
+	if limit > maxThreads {
+		limit = maxThreads
+	}
+	if n < 1 || n > limit {
+		return fmt.Errorf("invalid thread count %d, must be between 1 and %d", n, limit)
+	}
+
+	c.threadsMu.Lock()
+	defer c.threadsMu.Unlock()
+
+	for len(c.threadCancels) < n {
+		c.threadCancels = append(c.threadCancels, c.startThread(len(c.threadCancels)))
+	}
+	for len(c.threadCancels) > n {
+		c.threadCancels[len(c.threadCancels)-1]()
+		c.threadCancels = c.threadCancels[:len(c.threadCancels)-1]
+	}
+
+	c.mu.Lock()
+	changed := c.config.Threads != n
+	c.config.Threads = n
+	c.mu.Unlock()
+	if changed {
+		c.logger.Info(fmt.Sprintf("Threads: %d (max: %d)", n, runtime.GOMAXPROCS(0)))
+	}
+	return nil
+}
+
+// restartThreads stops all mining threads and starts them again.
+func (c *Client) restartThreads() {
+	c.threadsMu.Lock()
+	defer c.threadsMu.Unlock()
+	for i, cancel := range c.threadCancels {
+		cancel()
+		c.threadCancels[i] = c.startThread(i)
+	}
+}
+
+// startThread starts the mining thread tid. The thread waits until the previous thread
+// with the same id has stopped, so they never share the nonce space and the hash counter.
+// A thread which hangs in a hash thereby also holds back its replacement.
+// threadsMu must be held.
+func (c *Client) startThread(tid int) context.CancelFunc {
+	ctx, cancel := context.WithCancel(c.ctx)
+	prev, done := c.threadDone[tid], make(chan struct{})
+	c.threadDone[tid] = done
+	go func() {
+		defer close(done)
+		if prev != nil {
+			<-prev
+		}
+		c.mineblock(ctx, tid)
+	}()
+	return cancel
+}
+
+// GetPools returns the main pool followed by all additionally configured pools.
+func (c *Client) GetPools() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	pools := []string{c.config.PoolURL}
+	for _, p := range c.config.Pools {
+		if p != c.config.PoolURL {
+			pools = append(pools, p)
+		}
+	}
+	return pools
+}
+
+// SwitchPool reconnects the miner to another configured pool.
+func (c *Client) SwitchPool(url string) error {
+	var known bool
+	for _, p := range c.GetPools() {
+		if p == url {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return ErrUnknownPool
+	}
+
+	c.mu.Lock()
+	if c.config.PoolURL != url {
+		// keep the previous pool in the list so it's possible to switch back
+		pools := []string{c.config.PoolURL}
+		for _, p := range c.config.Pools {
+			if p != url && p != c.config.PoolURL {
+				pools = append(pools, p)
+			}
+		}
+		c.config.Pools = pools
+		c.config.PoolURL = url
+	}
+	c.mu.Unlock()
+
+	c.logger.Info("Switching pool", logging.KeyPool, url)
+	c.emit(EventPoolSwitch, &PoolEvent{Pool: url})
+	c.stratum.SetURL(url)
+	c.Reconnect()
+	return nil
+}
+
+// Reconnect closes the connection to the pool and connects again.
+func (c *Client) Reconnect() {
+	c.stratum.CloseAndReconnect()
+}
+
+// Shutdown stops the miner gracefully.
+func (c *Client) Shutdown() {
+	c.logger.Info("Shutting down")
+	c.cancel()
+}
+
+// SetReloadFunc sets the function which is used by ReloadConfig to read the new config.
+func (c *Client) SetReloadFunc(fn func() (*config.Miner, error)) {
+	c.reload = fn
+}
+
+// ReloadConfig reads the config again and applies it.
+func (c *Client) ReloadConfig() error {
+	if c.reload == nil {
+		return ErrNoReloadFunc
+	}
+	cfg, err := c.reload()
+	if err != nil {
+		return err
+	}
+	return c.ApplyConfig(cfg)
+}
+
+// ApplyConfig applies a new config to the running miner.
+// Changes of the wallet, pools and threads are applied live, all other options require a restart.
+func (c *Client) ApplyConfig(cfg *config.Miner) error {
+	if err := c.SetThreads(cfg.Threads); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	reconnect := c.config.Wallet != cfg.Wallet || c.config.PoolURL != cfg.PoolURL
+	if c.config.Testnet != cfg.Testnet || c.config.DNS != cfg.DNS || c.config.IgnoreTLSValidation != cfg.IgnoreTLSValidation {
+		c.logger.Info("Some config changes require a restart to take effect")
+	}
+	switchPool := c.config.PoolURL != cfg.PoolURL
+	c.config.Wallet = cfg.Wallet
+	c.config.PoolURL = cfg.PoolURL
+	c.config.Pools = append([]string(nil), cfg.Pools...)
+	c.mu.Unlock()
+
+	if switchPool {
+		c.emit(EventPoolSwitch, &PoolEvent{Pool: cfg.PoolURL})
+	}
+	if reconnect {
+		c.logger.Info("Reconnecting with new config", logging.KeyPool, cfg.PoolURL)
+		c.stratum.SetUsername(cfg.Wallet)
+		c.stratum.SetURL(cfg.PoolURL)
+		c.Reconnect()
+	}
+	return nil
+}
+
+// LogLevels are the console log level and the levels of the components which have their own.
+type LogLevels struct {
+	Console    string            `json:"console"`
+	Components map[string]string `json:"components"`
+}
+
+// logComponents are the names of the loggers, they are used for completion.
+var logComponents = []string{"api", "miner", "stratum"}
+
+// GetLogLevels returns the current log levels.
+func (c *Client) GetLogLevels() (*LogLevels, error) {
+	c.mu.RLock()
+	l := c.logLevel
+	c.mu.RUnlock()
+	if l == nil {
+		return nil, ErrNoLogLevel
+	}
+	return &LogLevels{Console: l.Level(), Components: l.Components()}, nil
+}
+
+// ChangeLogLevel changes the level of a component or the console level if the component is empty.
+func (c *Client) ChangeLogLevel(component, level string) error {
+	c.mu.RLock()
+	l := c.logLevel
+	c.mu.RUnlock()
+	if l == nil {
+		return ErrNoLogLevel
+	}
+	if component == "" || component == "console" {
+		if err := l.SetLevel(level); err != nil {
+			return err
+		}
+		c.logger.Info("Log level changed", "level", level)
+		return nil
+	}
+	if err := l.SetComponent(component, level); err != nil {
+		return err
+	}
+	c.logger.Info("Log level changed", "component", component, "level", level)
+	return nil
+}
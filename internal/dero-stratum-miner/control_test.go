@@ -0,0 +1,153 @@
+package miner
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalesburg/dero-stratum-miner/internal/config"
+	"github.com/whalesburg/dero-stratum-miner/internal/stratum"
+)
+
+const (
+	testPool   = "127.0.0.1:1"
+	testBackup = "127.0.0.1:2"
+	testSpare  = "127.0.0.1:3"
+)
+
+// newTestClient returns a miner which isn't started. The test pools refuse connections,
+// so a reconnect doesn't get further than dialing.
+func newTestClient(t *testing.T, cfg *config.Miner, logger logr.Logger) *Client {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	s := stratum.New(cfg.PoolURL, stratum.WithContext(ctx), stratum.WithReadTimeout(time.Second*5), stratum.WithWriteTimeout(time.Second*5))
+	c, err := New(ctx, cancel, cfg, s, nil, logger)
+	require.NoError(t, err)
+	return c
+}
+
+func TestSetThreads(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	c := newTestClient(t, &config.Miner{PoolURL: testPool, Threads: 1}, logr.Discard())
+
+	for _, tt := range []struct {
+		threads int
+		err     bool
+		want    int
+	}{
+		{threads: 2, want: 2},
+		{threads: 4, want: 4},
+		{threads: 1, want: 1},
+		{threads: 0, err: true, want: 1},
+		{threads: -1, err: true, want: 1},
+		{threads: 5, err: true, want: 1},
+		{threads: 3, want: 3},
+	} {
+		err := c.SetThreads(tt.threads)
+		if tt.err {
+			assert.Error(t, err, tt.threads)
+		} else {
+			assert.NoError(t, err, tt.threads)
+		}
+		assert.Equal(t, tt.want, c.GetThreads(), tt.threads)
+		assert.Equal(t, tt.want, c.config.Threads, tt.threads)
+	}
+}
+
+func TestSwitchPool(t *testing.T) {
+	c := newTestClient(t, &config.Miner{PoolURL: testPool, Pools: []string{testBackup, testSpare}}, logr.Discard())
+	events := c.NewEventListener(8)
+	defer events.Close()
+
+	for _, tt := range []struct {
+		pool  string
+		err   error
+		pools []string
+	}{
+		{pool: "127.0.0.1:4", err: ErrUnknownPool, pools: []string{testPool, testBackup, testSpare}},
+		{pool: testSpare, pools: []string{testSpare, testPool, testBackup}},
+		// the previous pool stays in the list
+		{pool: testPool, pools: []string{testPool, testSpare, testBackup}},
+		{pool: testPool, pools: []string{testPool, testSpare, testBackup}},
+	} {
+		err := c.SwitchPool(tt.pool)
+		assert.ErrorIs(t, err, tt.err, tt.pool)
+		assert.Equal(t, tt.pools, c.GetPools(), tt.pool)
+		assert.Equal(t, tt.pools[0], c.stratum.GetURL(), tt.pool)
+		if tt.err == nil {
+			ev := <-events.Ch()
+			assert.Equal(t, EventPoolSwitch, ev.Type)
+			assert.Equal(t, &PoolEvent{Pool: tt.pool}, ev.Data)
+		}
+	}
+}
+
+func TestApplyConfig(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(2))
+	c := newTestClient(t, &config.Miner{Wallet: "wallet", PoolURL: testPool, Threads: 1}, logr.Discard())
+	require.NoError(t, c.SetThreads(1))
+
+	var reconnected bool
+	for _, tt := range []struct {
+		name      string
+		cfg       config.Miner
+		err       bool
+		want      config.Miner
+		reconnect bool
+	}{
+		{
+			name: "threads",
+			cfg:  config.Miner{Wallet: "wallet", PoolURL: testPool, Threads: 2},
+			want: config.Miner{Wallet: "wallet", PoolURL: testPool, Threads: 2},
+		},
+		{
+			name: "invalid threads",
+			cfg:  config.Miner{Wallet: "other", PoolURL: testBackup, Threads: 3},
+			err:  true,
+			want: config.Miner{Wallet: "wallet", PoolURL: testPool, Threads: 2},
+		},
+		{
+			name: "pools",
+			cfg:  config.Miner{Wallet: "wallet", PoolURL: testPool, Pools: []string{testSpare}, Threads: 2},
+			want: config.Miner{Wallet: "wallet", PoolURL: testPool, Pools: []string{testSpare}, Threads: 2},
+		},
+		{
+			name:      "wallet",
+			cfg:       config.Miner{Wallet: "other", PoolURL: testPool, Threads: 2},
+			want:      config.Miner{Wallet: "other", PoolURL: testPool, Threads: 2},
+			reconnect: true,
+		},
+		{
+			name:      "pool",
+			cfg:       config.Miner{Wallet: "other", PoolURL: testBackup, Threads: 1},
+			want:      config.Miner{Wallet: "other", PoolURL: testBackup, Threads: 1},
+			reconnect: true,
+		},
+		{
+			name: "restart required",
+			cfg:  config.Miner{Wallet: "other", PoolURL: testBackup, Threads: 1, Testnet: true},
+			want: config.Miner{Wallet: "other", PoolURL: testBackup, Threads: 1},
+		},
+	} {
+		err := c.ApplyConfig(&tt.cfg)
+		if tt.err {
+			assert.Error(t, err, tt.name)
+		} else {
+			assert.NoError(t, err, tt.name)
+		}
+		assert.Equal(t, tt.want, *c.config, tt.name)
+		assert.Equal(t, tt.want.Threads, c.GetThreads(), tt.name)
+		assert.Equal(t, tt.want.PoolURL, c.stratum.GetURL(), tt.name)
+		// the pools are never reachable, so the client keeps reconnecting after the first change
+		if tt.reconnect {
+			reconnected = true
+			assert.Eventually(t, func() bool { return c.GetReconnects() > 0 }, time.Second*5, time.Millisecond*10, tt.name)
+		} else if !reconnected {
+			assert.Zero(t, c.GetReconnects(), tt.name)
+		}
+	}
+}
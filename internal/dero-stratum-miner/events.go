@@ -0,0 +1,126 @@
+package miner
+
+import (
+	"time"
+
+	"github.com/teivah/broadcast"
+)
+
+type EventType string
+
+const (
+	EventJob            EventType = "job"
+	EventShareSubmitted EventType = "share_submitted"
+	EventShareAccepted  EventType = "share_accepted"
+	EventShareRejected  EventType = "share_rejected"
+	EventConnected      EventType = "connected"
+	EventDisconnected   EventType = "disconnected"
+	EventPoolSwitch     EventType = "pool_switch"
+	EventPaused         EventType = "paused"
+	EventResumed        EventType = "resumed"
+	EventHashrate       EventType = "hashrate"
+)
+
+var hashrateEventInterval = time.Second * 10
+
+// Event is something that happened in the miner. Data holds one of the *Event structs below,
+// depending on the type, or nil.
+type Event struct {
+	Type EventType `json:"type"`
+	Time time.Time `json:"time"`
+	Data any       `json:"data,omitempty"`
+}
+
+type JobEvent struct {
+	JobID      string  `json:"job_id"`
+	Height     float64 `json:"height"`
+	Difficulty uint64  `json:"difficulty"`
+}
+
+type ShareEvent struct {
+	Pool       string  `json:"pool"`
+	JobID      string  `json:"job_id"`
+	Nonce      string  `json:"nonce"`
+	Result     string  `json:"result"`
+	Height     float64 `json:"height,omitempty"`
+	Difficulty uint64  `json:"difficulty,omitempty"`
+	Reason     string  `json:"reason,omitempty"`
+	LatencyMS  int64   `json:"latency_ms,omitempty"`
+}
+
+type PoolEvent struct {
+	Pool string `json:"pool"`
+}
+
+type HashrateEvent struct {
+	Hashrate uint64   `json:"hashrate"`
+	Threads  []uint64 `json:"threads"`
+}
+
+// NewEventListener returns a listener which receives all events of the miner.
+// Events are dropped if the listener isn't ready to receive them, so the buffer should be sized accordingly.
+func (c *Client) NewEventListener(buff int) *broadcast.Listener[*Event] {
+	return c.events.Listener(buff)
+}
+
+// NewShareEventListener returns a listener which receives the share events. Unlike the other listeners it
+// doesn't miss any shares, the miner waits until there's room in the buffer. The listener must be
+// received from until the context of the miner is done.
+func (c *Client) NewShareEventListener(buff int) *broadcast.Listener[*Event] {
+	return c.shares.Listener(buff)
+}
+
+func (c *Client) emit(t EventType, data any) {
+	c.events.Broadcast(&Event{
+		Type: t,
+		Time: time.Now(),
+		Data: data,
+	})
+}
+
+// emitShare sends the event to all listeners and waits for the share listeners.
+func (c *Client) emitShare(t EventType, data *ShareEvent) {
+	ev := &Event{
+		Type: t,
+		Time: time.Now(),
+		Data: data,
+	}
+	c.events.Broadcast(ev)
+	c.shares.NotifyCtx(c.ctx, ev)
+}
+
+func (c *Client) listenConnection(l *broadcast.Listener[bool]) {
+	defer l.Close()
+	for {
+		select {
+		case connected, ok := <-l.Ch():
+			if !ok {
+				return
+			}
+			if connected {
+				c.emit(EventConnected, &PoolEvent{Pool: c.GetPoolURL()})
+			} else {
+				c.emit(EventDisconnected, &PoolEvent{Pool: c.GetPoolURL()})
+			}
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Client) emitHashrate() {
+	ticker := time.NewTicker(hashrateEventInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.emit(EventHashrate, &HashrateEvent{
+				Hashrate: c.GetHashrateAverage(hashrateEventInterval),
+				Threads:  c.GetThreadHashrates(hashrateEventInterval),
+			})
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package miner
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	shortSampleInterval = time.Second
+	shortSampleCount    = 61
+	longSampleInterval  = time.Second * 15
+	longSampleCount     = 61
+)
+
+type hashSample struct {
+	t       time.Time
+	total   uint64
+	threads []uint64
+}
+
+// hashrateMeter keeps a history of the hash counters to calculate average hashrates.
+// The short history has a resolution of one second and also tracks the counters of
+// every thread, the long history covers the last 15 minutes.
+type hashrateMeter struct {
+	mu    sync.RWMutex
+	short []hashSample
+	long  []hashSample
+}
+
+func (h *hashrateMeter) add(s hashSample) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.short = appendSample(h.short, s, shortSampleCount)
+	if len(h.long) == 0 || s.t.Sub(h.long[len(h.long)-1].t) >= longSampleInterval {
+		h.long = appendSample(h.long, hashSample{t: s.t, total: s.total}, longSampleCount)
+	}
+}
+
+func appendSample(samples []hashSample, s hashSample, max int) []hashSample {
+	if len(samples) >= max {
+		copy(samples, samples[1:])
+		samples = samples[:len(samples)-1]
+	}
+	return append(samples, s)
+}
+
+// average returns the hashrate over the given window.
+func (h *hashrateMeter) average(window time.Duration) uint64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	samples := h.short
+	if window > shortSampleInterval*(shortSampleCount-1) && len(h.short) > 0 {
+		// the long history lags up to one interval behind, so the latest sample is taken from the short one
+		samples = append(append(make([]hashSample, 0, len(h.long)+1), h.long...), h.short[len(h.short)-1])
+	}
+	first, last, ok := sampleWindow(samples, window)
+	if !ok {
+		return 0
+	}
+	return rate(last.total-first.total, last.t.Sub(first.t))
+}
+
+// threads returns the hashrate of every thread over the given window.
+// The window is limited to the short history.
+func (h *hashrateMeter) threads(window time.Duration) []uint64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	first, last, ok := sampleWindow(h.short, window)
+	if !ok {
+		return nil
+	}
+	rates := make([]uint64, len(last.threads))
+	for i := range last.threads {
+		if i >= len(first.threads) {
+			break
+		}
+		rates[i] = rate(last.threads[i]-first.threads[i], last.t.Sub(first.t))
+	}
+	return rates
+}
+
+// history returns the hashrate between each of the samples of the long history.
+func (h *hashrateMeter) history() []uint64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if len(h.long) < 2 {
+		return nil
+	}
+	rates := make([]uint64, 0, len(h.long)-1)
+	for i := 1; i < len(h.long); i++ {
+		rates = append(rates, rate(h.long[i].total-h.long[i-1].total, h.long[i].t.Sub(h.long[i-1].t)))
+	}
+	return rates
+}
+
+func sampleWindow(samples []hashSample, window time.Duration) (hashSample, hashSample, bool) {
+	if len(samples) < 2 {
+		return hashSample{}, hashSample{}, false
+	}
+	last := samples[len(samples)-1]
+	first := samples[0]
+	for i := len(samples) - 2; i >= 0; i-- {
+		first = samples[i]
+		if last.t.Sub(samples[i].t) >= window {
+			break
+		}
+	}
+	return first, last, true
+}
+
+func rate(hashes uint64, d time.Duration) uint64 {
+	if d <= 0 {
+		return 0
+	}
+	return uint64(float64(hashes) / d.Seconds())
+}
+
+func (c *Client) sampleHashrate() {
+	ticker := time.NewTicker(shortSampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			threads := make([]uint64, c.GetThreads())
+			for i := range threads {
+				threads[i] = atomic.LoadUint64(&c.threadCounters[i])
+			}
+			c.hashrateMeter.add(hashSample{
+				t:       now,
+				total:   atomic.LoadUint64(&c.counter),
+				threads: threads,
+			})
+			if h := c.hashrateMeter.average(time.Second * 10); h > atomic.LoadUint64(&c.highestHashrate) {
+				atomic.StoreUint64(&c.highestHashrate, h)
+			}
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
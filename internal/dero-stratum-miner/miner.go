@@ -4,12 +4,14 @@ import (
 	"context"
 	"encoding/binary"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"math/big"
 	"math/rand"
 	"runtime"
 	"runtime/debug"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -21,13 +23,24 @@ import (
 	"github.com/jpillora/backoff"
 	"github.com/teivah/broadcast"
 	"github.com/whalesburg/dero-stratum-miner/internal/config"
+	"github.com/whalesburg/dero-stratum-miner/internal/console"
+	"github.com/whalesburg/dero-stratum-miner/internal/logging"
 	"github.com/whalesburg/dero-stratum-miner/internal/stratum"
 )
 
 var reportHashrateInterval = time.Second * 30
 
+// maxThreads is the maximum number of supported mining threads.
+const maxThreads = 255
+
 type Client struct {
 	counter uint64 // Must be the first field. Otherwise atomic operations panic on arm7
+	// fields which are accessed atomically have to follow the counter for the same reason
+	submittedCounter uint64
+	highestHashrate  uint64
+	acceptedCounter  uint64
+	rejectedCounter  uint64
+
 	ctx     context.Context
 	cancel  context.CancelFunc
 	config  *config.Miner
@@ -45,20 +58,47 @@ type Client struct {
 	diffString   string
 	heightString string
 
-	shareCounter    uint64
-	rejectedCounter uint64
+	rejectReasons map[string]uint64
+	latencySum    time.Duration
+	latencyCount  int64
+	connShares    connectionShares
+	startTime     time.Time
+
+	threadsMu      sync.Mutex
+	threadCancels  []context.CancelFunc
+	threadDone     []chan struct{}
+	threadCounters []uint64
+	hashrateMeter  hashrateMeter
+	paused         int32
+
+	reload   func() (*config.Miner, error)
+	events   *broadcast.Relay[*Event]
+	shares   *broadcast.Relay[*Event]
+	commands *console.Registry
+	hotkeys  *console.Hotkeys
+	logLevel LogLevel
+	watchdog *watchdog
+	err      error
 }
 
-func New(ctx context.Context, cancel context.CancelFunc, config *config.Miner, stratum *stratum.Client, console *readline.Instance, logger logr.Logger) (*Client, error) {
+func New(ctx context.Context, cancel context.CancelFunc, config *config.Miner, stratum *stratum.Client, cli *readline.Instance, logger logr.Logger) (*Client, error) {
 	c := &Client{
-		ctx:        ctx,
-		cancel:     cancel,
-		config:     config,
-		stratum:    stratum,
-		iterations: 100,
-		console:    console,
+		ctx:            ctx,
+		cancel:         cancel,
+		config:         config,
+		stratum:        stratum,
+		iterations:     100,
+		console:        cli,
+		rejectReasons:  make(map[string]uint64),
+		threadDone:     make([]chan struct{}, maxThreads),
+		threadCounters: make([]uint64, maxThreads),
+		events:         broadcast.NewRelay[*Event](),
+		shares:         broadcast.NewRelay[*Event](),
+		commands:       console.NewRegistry(),
 	}
 	c.setLogger(logger)
+	c.registerCommands()
+	c.registerHotkeys()
 	return c, nil
 }
 
@@ -73,25 +113,36 @@ func (c *Client) Start() error {
 	if c.config.Threads < 1 || c.iterations < 1 || c.config.Threads > 2048 {
 		panic("Invalid parameters\n")
 	}
-	if c.config.Threads > 255 {
+	if c.config.Threads > maxThreads {
 		c.logger.Error(nil, "This program supports maximum 256 CPU cores.", "available", c.config.Threads)
-		c.config.Threads = 255
+		c.config.Threads = maxThreads
 	}
 
+	c.mu.Lock()
+	c.startTime = time.Now()
+	c.mu.Unlock()
+
+	go c.listenConnection(c.stratum.NewConnectionListener(4))
 	go c.gatherStats()
+	go c.sampleHashrate()
+	go c.emitHashrate()
 	if c.config.NonInteractive {
 		go c.noniSummary()
 	}
 
 	go c.getwork()
 
-	for i := 0; i < c.config.Threads; i++ {
-		go c.mineblock(i)
+	if err := c.SetThreads(c.config.Threads); err != nil {
+		return err
 	}
 
 	go c.reportHashrate()
 
-	if !c.config.NonInteractive {
+	if c.watchdog != nil {
+		go c.runWatchdog(c.watchdog)
+	}
+
+	if c.console != nil {
 		c.startConsole()
 	}
 	return nil
@@ -112,8 +163,9 @@ func (c *Client) getwork() {
 	for {
 		if err := c.stratum.Dial(); err != nil {
 			waitDuration := b.Duration()
-			c.logger.Error(err, "Error connecting to server", "server adress", c.config.PoolURL)
-			c.logger.Info(fmt.Sprintf("Will try again in %f seconds", waitDuration.Seconds()))
+			c.logger.Error(err, "Error connecting to server", logging.KeyPool, c.GetPoolURL())
+			// the error is collapsed if the pool stays unreachable, this line would repeat for every retry
+			c.logger.V(1).Info(fmt.Sprintf("Will try again in %f seconds", waitDuration.Seconds()))
 			time.Sleep(waitDuration)
 			continue
 		}
@@ -124,6 +176,9 @@ func (c *Client) getwork() {
 		respListener := c.stratum.NewResponseListener(0)
 		go c.listenStratumResponses(respListener)
 
+		shareListener := c.stratum.NewShareListener(0)
+		go c.listenShareResults(shareListener)
+
 		for {
 			select {
 			case j := <-jobListener.Ch():
@@ -131,6 +186,8 @@ func (c *Client) getwork() {
 				c.job = j
 				c.jobCounter++
 				c.mu.Unlock()
+				c.logger.V(1).Info("New job", logging.KeyJobID, j.ID, logging.KeyHeight, j.Height, logging.KeyDifficulty, j.Difficulty)
+				c.emit(EventJob, &JobEvent{JobID: j.ID, Height: j.Height, Difficulty: j.Difficulty})
 			case <-c.ctx.Done():
 				return
 			}
@@ -141,12 +198,46 @@ func (c *Client) getwork() {
 func (c *Client) listenStratumResponses(l *broadcast.Listener[*stratum.Response]) {
 	defer l.Close()
 	for range l.Ch() {
-		c.shareCounter = uint64(c.stratum.GetTotalShares())
-		c.rejectedCounter = uint64(c.stratum.GetTotalShares() - c.stratum.GetAcceptedShares())
+		total, accepted := c.stratum.GetTotalShares(), c.stratum.GetAcceptedShares()
+		atomic.StoreUint64(&c.acceptedCounter, uint64(accepted))
+		atomic.StoreUint64(&c.rejectedCounter, uint64(total-accepted))
+	}
+}
+
+func (c *Client) listenShareResults(l *broadcast.Listener[*stratum.ShareResult]) {
+	defer l.Close()
+	for r := range l.Ch() {
+		c.mu.Lock()
+		c.latencySum += r.Latency
+		c.latencyCount++
+		c.connShares.add(c.stratum.GetConnectedSince(), r.Accepted)
+		if !r.Accepted {
+			c.rejectReasons[r.Reason]++
+		}
+		c.mu.Unlock()
+
+		ev := &ShareEvent{
+			Pool:       c.GetPoolURL(),
+			JobID:      r.Share.JobID,
+			Nonce:      r.Share.Nonce,
+			Result:     r.Share.Result,
+			Height:     r.Share.Height,
+			Difficulty: r.Share.Difficulty,
+			Reason:     r.Reason,
+			LatencyMS:  r.Latency.Milliseconds(),
+		}
+		kv := []any{logging.KeyJobID, ev.JobID, logging.KeyHeight, ev.Height, logging.KeyDifficulty, ev.Difficulty, logging.KeyLatency, ev.LatencyMS}
+		if r.Accepted {
+			c.logger.Info("Share accepted", kv...)
+			c.emitShare(EventShareAccepted, ev)
+		} else {
+			c.logger.Info("Share rejected", append(kv, logging.KeyReason, ev.Reason)...)
+			c.emitShare(EventShareRejected, ev)
+		}
 	}
 }
 
-func (c *Client) mineblock(tid int) {
+func (c *Client) mineblock(ctx context.Context, tid int) {
 	var diff big.Int
 	var work [block.MINIBLOCK_SIZE]byte
 
@@ -165,6 +256,12 @@ func (c *Client) mineblock(tid int) {
 	i := uint32(0)
 
 	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
 		c.mu.RLock()
 		myjob := c.job
 		localJobCounter = c.jobCounter
@@ -176,7 +273,7 @@ func (c *Client) mineblock(tid int) {
 
 		n, err := hex.Decode(work[:], []byte(myjob.Blob))
 		if err != nil || n != block.MINIBLOCK_SIZE {
-			c.logger.Error(err, "Blockwork could not be decoded successfully", "blockwork", myjob.Blob, "n", n, "job", myjob)
+			c.logger.Error(err, "Blockwork could not be decoded successfully", "blockwork", myjob.Blob, "n", n, logging.KeyJobID, myjob.ID)
 			time.Sleep(time.Millisecond * 500)
 			continue
 		}
@@ -192,7 +289,10 @@ func (c *Client) mineblock(tid int) {
 		}
 
 		for localJobCounter == c.jobCounter { // update job when it comes, expected rate 2 per second
-			if !c.stratum.IsConnected() {
+			if ctx.Err() != nil {
+				return
+			}
+			if !c.stratum.IsConnected() || c.IsPaused() {
 				time.Sleep(time.Millisecond * 500)
 				continue
 			}
@@ -201,16 +301,31 @@ func (c *Client) mineblock(tid int) {
 
 			powhash := astrobwtv3.AstroBWTv3(work[:])
 			atomic.AddUint64(&c.counter, 1)
+			atomic.AddUint64(&c.threadCounters[tid], 1)
 
 			if CheckPowHashBig(powhash, &diff) { // note we are doing a local, NW might have moved meanwhile
-				c.logger.V(1).Info("Successfully found share (going to submit)", "difficulty", myjob.Difficulty, "height", myjob.Height)
+				c.logger.V(1).Info("Successfully found share (going to submit)", logging.KeyJobID, myjob.ID, logging.KeyDifficulty, myjob.Difficulty, logging.KeyHeight, myjob.Height)
 				func() {
 					defer c.recover(1) // nolint: errcheck
 					nonce := work[len(work)-12:]
 					share := stratum.NewShare(myjob.ID, fmt.Sprintf("%x", nonce), fmt.Sprintf("%x", powhash[:]))
+					share.Height = myjob.Height
+					share.Difficulty = myjob.Difficulty
 					if err := c.stratum.SubmitShare(share); err != nil {
-						c.logger.Error(err, "Failed to submit share")
+						if !errors.Is(err, stratum.ErrDuplicateShare) {
+							c.logger.Error(err, "Failed to submit share")
+						}
+						return
 					}
+					atomic.AddUint64(&c.submittedCounter, 1)
+					c.emitShare(EventShareSubmitted, &ShareEvent{
+						Pool:       c.GetPoolURL(),
+						JobID:      share.JobID,
+						Nonce:      share.Nonce,
+						Result:     share.Result,
+						Height:     share.Height,
+						Difficulty: share.Difficulty,
+					})
 				}()
 			}
 		}
@@ -246,17 +361,177 @@ func (c *Client) GetHashrate() uint64 {
 }
 
 func (c *Client) GetTotalShares() uint64 {
-	return c.shareCounter
+	return atomic.LoadUint64(&c.acceptedCounter) + atomic.LoadUint64(&c.rejectedCounter)
 }
 
 func (c *Client) GetAcceptedShares() uint64 {
-	return c.shareCounter - c.rejectedCounter
+	return atomic.LoadUint64(&c.acceptedCounter)
 }
 
 func (c *Client) GetRejectedShares() uint64 {
-	return c.rejectedCounter
+	return atomic.LoadUint64(&c.rejectedCounter)
+}
+
+// GetConnectionShares returns the accepted and rejected shares of the current connection to the pool.
+func (c *Client) GetConnectionShares() (accepted, rejected uint64) {
+	since := c.GetConnectedSince()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.connShares.get(since)
+}
+
+// connectionShares counts the shares of the connection which was established at since.
+type connectionShares struct {
+	since    time.Time
+	accepted uint64
+	rejected uint64
+}
+
+// add counts a share of the connection established at since, the counts of a previous connection are dropped.
+func (s *connectionShares) add(since time.Time, accepted bool) {
+	if !since.Equal(s.since) {
+		*s = connectionShares{since: since}
+	}
+	if accepted {
+		s.accepted++
+	} else {
+		s.rejected++
+	}
+}
+
+// get returns the counts of the connection established at since.
+func (s *connectionShares) get(since time.Time) (accepted, rejected uint64) {
+	if since.IsZero() || !since.Equal(s.since) {
+		return 0, 0
+	}
+	return s.accepted, s.rejected
 }
 
 func (c *Client) GetPoolURL() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.config.PoolURL
 }
+
+// GetWallet returns the configured wallet address without the worker name.
+func (c *Client) GetWallet() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	wallet, _, _ := strings.Cut(c.config.Wallet, ".")
+	return wallet
+}
+
+// GetWorker returns the worker name which is appended to the wallet address, if any.
+func (c *Client) GetWorker() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, worker, _ := strings.Cut(c.config.Wallet, ".")
+	return worker
+}
+
+// GetHashes returns the total number of computed hashes.
+func (c *Client) GetHashes() uint64 {
+	return atomic.LoadUint64(&c.counter)
+}
+
+// GetHashrateAverage returns the average hashrate over the given window.
+// Windows up to one minute have a resolution of one second, longer windows a resolution of 15 seconds.
+func (c *Client) GetHashrateAverage(window time.Duration) uint64 {
+	return c.hashrateMeter.average(window)
+}
+
+// GetThreadHashrates returns the hashrate of every mining thread over the given window.
+// The window is limited to one minute.
+func (c *Client) GetThreadHashrates(window time.Duration) []uint64 {
+	rates := c.hashrateMeter.threads(window)
+	if threads := c.GetThreads(); len(rates) > threads {
+		rates = rates[:threads]
+	}
+	return rates
+}
+
+// GetHighestHashrate returns the highest 10 second average hashrate since the start.
+func (c *Client) GetHighestHashrate() uint64 {
+	return atomic.LoadUint64(&c.highestHashrate)
+}
+
+// GetHashrateHistory returns the hashrate of the last 15 minutes with a resolution of 15 seconds, oldest first.
+func (c *Client) GetHashrateHistory() []uint64 {
+	return c.hashrateMeter.history()
+}
+
+// GetShareLatency returns the average time the pool needed to answer a submitted share.
+func (c *Client) GetShareLatency() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.latencyCount == 0 {
+		return 0
+	}
+	return c.latencySum / time.Duration(c.latencyCount)
+}
+
+// GetUptime returns how long the miner is running.
+func (c *Client) GetUptime() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.startTime.IsZero() {
+		return 0
+	}
+	return time.Since(c.startTime)
+}
+
+// GetConnectedSince returns when the current connection to the pool was established.
+// The returned time is zero if the miner isn't connected.
+func (c *Client) GetConnectedSince() time.Time {
+	return c.stratum.GetConnectedSince()
+}
+
+// GetSubmittedShares returns the number of shares which were sent to the pool.
+func (c *Client) GetSubmittedShares() uint64 {
+	return atomic.LoadUint64(&c.submittedCounter)
+}
+
+// GetRejectReasons returns the number of rejected shares grouped by the reason the pool gave.
+func (c *Client) GetRejectReasons() map[string]uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	reasons := make(map[string]uint64, len(c.rejectReasons))
+	for k, v := range c.rejectReasons {
+		reasons[k] = v
+	}
+	return reasons
+}
+
+// GetJob returns the current job or nil if no job was received yet.
+func (c *Client) GetJob() *stratum.Job {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.job
+}
+
+// GetJobsReceived returns the number of jobs received from the pool.
+func (c *Client) GetJobsReceived() int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.jobCounter
+}
+
+// GetReconnects returns the number of reconnects to the pool.
+func (c *Client) GetReconnects() int {
+	return c.stratum.GetReconnects()
+}
+
+// IsConnected reports whether the miner is connected to the pool.
+func (c *Client) IsConnected() bool {
+	return c.stratum.IsConnected()
+}
+
+// IsTestnet reports whether the miner runs on the testnet.
+func (c *Client) IsTestnet() bool {
+	return c.config.Testnet
+}
+
+// NewShareListener returns a listener which receives the results of all submitted shares.
+func (c *Client) NewShareListener(buff int) *broadcast.Listener[*stratum.ShareResult] {
+	return c.stratum.NewShareListener(buff)
+}
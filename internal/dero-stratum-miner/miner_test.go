@@ -0,0 +1,142 @@
+package miner
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/zapr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalesburg/dero-stratum-miner/internal/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// servePool answers the login of every connection and sends a new job every 100ms.
+func servePool(ln net.Listener) {
+	blob := "01" + strings.Repeat("00", 47)
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			var login struct {
+				ID any `json:"id"`
+			}
+			line, err := bufio.NewReader(conn).ReadBytes('\n')
+			if err != nil || json.Unmarshal(line, &login) != nil {
+				return
+			}
+			job := `{"job_id":"j%d","blob":"` + blob + `","height":100,"extra_nonce":"","pool_wallet":"","target":"ffffffffffffff00"}`
+			res, _ := json.Marshal(login.ID)
+			fmt.Fprintf(conn, `{"id":%s,"jsonrpc":"2.0","result":{"id":"sess","status":"OK","job":`+job+"}}\n", res, 1) // nolint: errcheck
+			for i := 2; ; i++ {
+				time.Sleep(time.Millisecond * 100)
+				if _, err := fmt.Fprintf(conn, `{"jsonrpc":"2.0","method":"job","params":`+job+"}\n", i); err != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+func TestGetworkRetry(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	ln.Close()
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	c := newTestClient(t, &config.Miner{Wallet: "wallet", PoolURL: addr, Threads: 1}, zapr.NewLogger(zap.New(core)))
+	go c.getwork()
+
+	require.Eventually(t, func() bool {
+		return logs.FilterMessage("Error connecting to server").Len() > 0
+	}, time.Second*5, time.Millisecond*10)
+	// the pool comes up before the next try
+	ln, err = net.Listen("tcp", addr)
+	require.NoError(t, err)
+	defer ln.Close()
+	go servePool(ln)
+
+	require.Eventually(t, func() bool { return c.GetJob() != nil }, time.Second*10, time.Millisecond*10)
+	assert.True(t, c.IsConnected())
+
+	retries := logs.FilterMessageSnippet("Will try again in")
+	require.NotZero(t, retries.Len())
+	// only the error is logged by default, it's collapsed if the pool stays unreachable
+	for _, e := range retries.All() {
+		assert.Equal(t, zapcore.DebugLevel, e.Level)
+	}
+}
+
+func TestEmitShare(t *testing.T) {
+	c := newTestClient(t, &config.Miner{PoolURL: testPool, Threads: 1}, logr.Discard())
+	l := c.NewShareEventListener(1)
+	defer l.Close()
+
+	// the miner waits for the share listeners instead of dropping shares
+	const n = 10
+	go func() {
+		for i := 0; i < n; i++ {
+			c.emitShare(EventShareAccepted, &ShareEvent{JobID: strconv.Itoa(i)})
+		}
+	}()
+	for i := 0; i < n; i++ {
+		select {
+		case ev := <-l.Ch():
+			assert.Equal(t, strconv.Itoa(i), ev.Data.(*ShareEvent).JobID)
+		case <-time.After(time.Second * 5):
+			t.Fatal("share missing")
+		}
+	}
+
+	// but not once it's stopped
+	c.cancel()
+	emitted := make(chan struct{})
+	go func() {
+		c.emitShare(EventShareAccepted, &ShareEvent{})
+		c.emitShare(EventShareAccepted, &ShareEvent{})
+		close(emitted)
+	}()
+	select {
+	case <-emitted:
+	case <-time.After(time.Second * 5):
+		t.Fatal("the miner waits for the listener after it stopped")
+	}
+}
+
+func TestConnectionShares(t *testing.T) {
+	first := time.Date(2022, 11, 19, 12, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+	var s connectionShares
+
+	s.add(first, true)
+	s.add(first, true)
+	s.add(first, false)
+	accepted, rejected := s.get(first)
+	assert.Equal(t, uint64(2), accepted)
+	assert.Equal(t, uint64(1), rejected)
+
+	// a reconnect starts from zero, even before the first share of the new connection
+	accepted, rejected = s.get(second)
+	assert.Zero(t, accepted+rejected)
+	s.add(second, true)
+	accepted, rejected = s.get(second)
+	assert.Equal(t, uint64(1), accepted)
+	assert.Zero(t, rejected)
+
+	// nothing while disconnected
+	accepted, rejected = s.get(time.Time{})
+	assert.Zero(t, accepted+rejected)
+}
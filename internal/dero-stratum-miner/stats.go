@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/jon4hz/hashconv"
+	"github.com/whalesburg/dero-stratum-miner/internal/color"
+	"github.com/whalesburg/dero-stratum-miner/internal/logging"
 )
 
 func (c *Client) gatherStats() {
@@ -27,10 +29,13 @@ func (c *Client) gatherStats() {
 		// we assume that the miner stopped if the conolse wasn't updated within the last five seconds.
 		if time.Since(lastUpdate) > time.Second*5 {
 			if c.mining {
-				miningString = "\033[31mNot Mining"
+				miningString = color.Code(color.Red) + "Not Mining"
+				if c.IsPaused() {
+					miningString = color.Code(color.Yellow) + "Paused"
+				}
 				testnetString := ""
 				if c.config.Testnet {
-					testnetString = "\033[31m Testnet"
+					testnetString = color.Code(color.Red) + " Testnet"
 				}
 				c.setPrompt(heightString, diffString, miningString, testnetString)
 				c.mining = false
@@ -43,21 +48,21 @@ func (c *Client) gatherStats() {
 		if lastCounter != c.counter {
 			if c.mining {
 				c.heightString = fmt.Sprintf("%.0f", c.job.Height)
-				heightString = fmt.Sprintf("\033[33mHeight %s", c.heightString)
+				heightString = color.Code(color.Yellow) + "Height " + c.heightString
 
 				switch {
 				case c.job.Difficulty > 1_000_000_000:
 					c.diffString = fmt.Sprintf("%.1fG", float32(c.job.Difficulty)/1_000_000_000.0)
-					diffString = fmt.Sprintf("\033[32mDiff %s", c.diffString)
+					diffString = color.Code(color.Green) + "Diff " + c.diffString
 				case c.job.Difficulty > 1_000_000:
 					c.diffString = fmt.Sprintf("%.1fM", float32(c.job.Difficulty)/1_000_000.0)
-					diffString = fmt.Sprintf("\033[32mDiff %s", c.diffString)
+					diffString = color.Code(color.Green) + "Diff " + c.diffString
 				case c.job.Difficulty > 1000:
 					c.diffString = fmt.Sprintf("%.1fK", float32(c.job.Difficulty)/1000.0)
-					diffString = fmt.Sprintf("\033[32mDiff %s", c.diffString)
+					diffString = color.Code(color.Green) + "Diff " + c.diffString
 				case c.job.Difficulty > 0:
 					c.diffString = fmt.Sprintf("%d", c.job.Difficulty)
-					diffString = fmt.Sprintf("\033[32mDiff %s", c.diffString)
+					diffString = color.Code(color.Green) + "Diff " + c.diffString
 				}
 
 				miningSpeed := float64(c.counter-lastCounter) / (float64(uint64(time.Since(lastCounterTime))) / 1_000_000_000.0)
@@ -70,7 +75,7 @@ func (c *Client) gatherStats() {
 
 			testnetString := ""
 			if c.config.Testnet {
-				testnetString = "\033[31m Testnet"
+				testnetString = color.Code(color.Red) + " Testnet"
 			}
 
 			c.setPrompt(heightString, diffString, miningString, testnetString)
@@ -92,11 +97,13 @@ func (c *Client) noniSummary() {
 }
 
 func (c *Client) printSummary() {
-	c.logger.Info("Summary",
-		"height", c.heightString,
-		"diff", c.diffString,
+	kv := []any{
 		"accepted", c.GetAcceptedShares(),
 		"rejected", c.GetRejectedShares(),
 		"hashrate", c.miningString,
-	)
+	}
+	if job := c.GetJob(); job != nil {
+		kv = append(kv, logging.KeyHeight, job.Height, logging.KeyDifficulty, job.Difficulty)
+	}
+	c.logger.Info("Summary", kv...)
 }
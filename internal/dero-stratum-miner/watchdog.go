@@ -0,0 +1,173 @@
+package miner
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/whalesburg/dero-stratum-miner/internal/config"
+)
+
+// ErrWatchdog is returned by Err if the watchdog stopped the miner because it couldn't recover it.
+var ErrWatchdog = errors.New("watchdog: the miner could not be recovered")
+
+// minShareWindow is the shortest time the watchdog waits for an accepted share,
+// so the variance of easy shares doesn't cause reconnects.
+const minShareWindow = time.Minute
+
+type watchdogAction int
+
+const (
+	watchdogNone watchdogAction = iota
+	watchdogRestart
+	watchdogReconnect
+	watchdogExit
+)
+
+// watchdogState is what the watchdog looks at every second.
+type watchdogState struct {
+	mining         bool // connected, not paused and a job is available
+	connectedSince time.Time
+	hashes         uint64
+	accepted       uint64
+	hashrate       uint64
+	difficulty     uint64
+}
+
+// watchdog detects a miner which stopped hashing or doesn't find any shares.
+// Hashing threads which stall are restarted and the pool is reconnected if no share was accepted
+// within ShareFactor times the expected time. After Retries recoveries which didn't help, the miner exits.
+type watchdog struct {
+	cfg config.Watchdog
+	now func() time.Time
+
+	hashes   uint64
+	hashesAt time.Time
+	restarts int
+
+	accepted   uint64
+	acceptedAt time.Time
+	reconnects int
+}
+
+func newWatchdog(cfg *config.Watchdog) *watchdog {
+	return &watchdog{cfg: *cfg, now: time.Now}
+}
+
+func (w *watchdog) check(s watchdogState) watchdogAction {
+	now := w.now()
+	if w.hashesAt.IsZero() {
+		w.hashesAt, w.acceptedAt = now, now
+	}
+
+	if s.hashes != w.hashes {
+		w.hashes, w.hashesAt = s.hashes, now
+		w.restarts = 0
+	}
+	if s.accepted != w.accepted {
+		w.accepted, w.acceptedAt = s.accepted, now
+		w.reconnects = 0
+	}
+	// the windows only run while the miner is supposed to hash
+	if !s.mining {
+		w.hashesAt, w.acceptedAt = now, now
+		return watchdogNone
+	}
+	if s.connectedSince.After(w.acceptedAt) {
+		w.acceptedAt = s.connectedSince
+	}
+
+	if w.cfg.Stall > 0 && now.Sub(w.hashesAt) >= time.Duration(w.cfg.Stall) {
+		w.hashesAt = now
+		if w.restarts >= w.cfg.Retries {
+			return watchdogExit
+		}
+		w.restarts++
+		return watchdogRestart
+	}
+	if w.cfg.ShareFactor > 0 && now.Sub(w.acceptedAt) >= shareWindow(s.difficulty, s.hashrate, w.cfg.ShareFactor) {
+		w.acceptedAt = now
+		if w.reconnects >= w.cfg.Retries {
+			return watchdogExit
+		}
+		w.reconnects++
+		return watchdogReconnect
+	}
+	return watchdogNone
+}
+
+// shareWindow returns factor times the expected time to find a share. On average a share needs
+// difficulty hashes. Without hashrate or difficulty the window is infinite.
+func shareWindow(difficulty, hashrate uint64, factor int) time.Duration {
+	if difficulty == 0 || hashrate == 0 {
+		return time.Duration(1<<63 - 1)
+	}
+	window := time.Duration(float64(difficulty) / float64(hashrate) * float64(factor) * float64(time.Second))
+	if window < minShareWindow {
+		return minShareWindow
+	}
+	return window
+}
+
+func (c *Client) watchdogState() watchdogState {
+	s := watchdogState{
+		mining:         c.IsConnected() && !c.IsPaused(),
+		connectedSince: c.GetConnectedSince(),
+		hashes:         c.GetHashes(),
+		accepted:       c.GetAcceptedShares(),
+		hashrate:       c.GetHashrateAverage(time.Minute),
+	}
+	if job := c.GetJob(); job != nil {
+		s.difficulty = job.Difficulty
+	} else {
+		s.mining = false
+	}
+	return s
+}
+
+func (c *Client) runWatchdog(w *watchdog) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			switch w.check(c.watchdogState()) {
+			case watchdogRestart:
+				c.logger.Error(nil, "Watchdog: no hashes computed, restarting the mining threads", "stalled", time.Duration(w.cfg.Stall), "attempt", w.restarts)
+				c.restartThreads()
+			case watchdogReconnect:
+				c.logger.Error(nil, "Watchdog: no share accepted, reconnecting", "attempt", w.reconnects)
+				c.Reconnect()
+			case watchdogExit:
+				c.logger.Error(ErrWatchdog, fmt.Sprintf("Watchdog: giving up after %d recoveries", w.cfg.Retries))
+				c.fail(ErrWatchdog)
+				return
+			}
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// SetWatchdog enables the watchdog. It must be called before Start.
+func (c *Client) SetWatchdog(cfg *config.Watchdog) {
+	c.watchdog = newWatchdog(cfg)
+}
+
+// fail stops the miner with an error.
+func (c *Client) fail(err error) {
+	c.mu.Lock()
+	if c.err == nil {
+		c.err = err
+	}
+	c.mu.Unlock()
+	c.cancel()
+}
+
+// Err returns the error which stopped the miner, if any.
+func (c *Client) Err() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.err
+}
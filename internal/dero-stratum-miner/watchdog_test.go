@@ -0,0 +1,113 @@
+package miner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/whalesburg/dero-stratum-miner/internal/config"
+	"github.com/whalesburg/dero-stratum-miner/internal/testutil"
+)
+
+func newTestWatchdog(cfg config.Watchdog) (*watchdog, *testutil.Clock) {
+	w := newWatchdog(&cfg)
+	clk := testutil.NewClock()
+	w.now = clk.Now
+	return w, clk
+}
+
+func TestWatchdogStall(t *testing.T) {
+	w, clk := newTestWatchdog(config.Watchdog{Stall: config.Duration(time.Minute), Retries: 1})
+	s := watchdogState{mining: true, hashes: 100}
+
+	assert.Equal(t, watchdogNone, w.check(s))
+	clk.Add(time.Second * 59)
+	assert.Equal(t, watchdogNone, w.check(s))
+	clk.Add(time.Second)
+	assert.Equal(t, watchdogRestart, w.check(s))
+
+	// hashing again resets the retries
+	s.hashes = 200
+	clk.Add(time.Second)
+	assert.Equal(t, watchdogNone, w.check(s))
+	clk.Add(time.Minute)
+	assert.Equal(t, watchdogRestart, w.check(s))
+	clk.Add(time.Minute)
+	assert.Equal(t, watchdogExit, w.check(s))
+}
+
+func TestWatchdogNotMining(t *testing.T) {
+	w, clk := newTestWatchdog(config.Watchdog{Stall: config.Duration(time.Minute), ShareFactor: 10})
+	s := watchdogState{mining: false, hashes: 100, hashrate: 1000, difficulty: 1000}
+
+	assert.Equal(t, watchdogNone, w.check(s))
+	clk.Add(time.Hour)
+	assert.Equal(t, watchdogNone, w.check(s))
+
+	// the windows start when the miner is supposed to hash
+	s.mining = true
+	assert.Equal(t, watchdogNone, w.check(s))
+	clk.Add(time.Second * 59)
+	assert.Equal(t, watchdogNone, w.check(s))
+}
+
+func TestWatchdogShares(t *testing.T) {
+	w, clk := newTestWatchdog(config.Watchdog{ShareFactor: 10, Retries: 1})
+	// a share is expected every 10 seconds
+	s := watchdogState{mining: true, hashrate: 1000, difficulty: 10_000, connectedSince: clk.Now()}
+
+	assert.Equal(t, watchdogNone, w.check(s))
+	clk.Add(time.Second * 99)
+	assert.Equal(t, watchdogNone, w.check(s))
+	s.accepted++
+	assert.Equal(t, watchdogNone, w.check(s))
+	clk.Add(time.Second * 100)
+	assert.Equal(t, watchdogReconnect, w.check(s))
+
+	// the window starts again with the new connection
+	clk.Add(time.Second * 10)
+	s.connectedSince = clk.Now()
+	assert.Equal(t, watchdogNone, w.check(s))
+	clk.Add(time.Second * 99)
+	assert.Equal(t, watchdogNone, w.check(s))
+	clk.Add(time.Second)
+	assert.Equal(t, watchdogExit, w.check(s))
+}
+
+func TestShareWindow(t *testing.T) {
+	assert.Equal(t, time.Minute, shareWindow(100, 1000, 10))
+	assert.Equal(t, time.Minute*10, shareWindow(60_000, 1000, 10))
+	assert.Greater(t, shareWindow(0, 1000, 10), time.Hour*24*365)
+	assert.Greater(t, shareWindow(100, 0, 10), time.Hour*24*365)
+}
+
+func TestRestartThreads(t *testing.T) {
+	c := newTestClient(t, &config.Miner{PoolURL: testPool, Threads: 1}, logr.Discard())
+	// the thread hangs in a hash
+	hung := make(chan struct{})
+	c.threadsMu.Lock()
+	c.threadCancels = []context.CancelFunc{func() {}}
+	c.threadDone[0] = hung
+	c.threadsMu.Unlock()
+
+	c.restartThreads()
+	c.threadsMu.Lock()
+	c.threadCancels[0]()
+	done := c.threadDone[0]
+	c.threadsMu.Unlock()
+
+	// the replacement only starts once the hung thread is done
+	isDone := func() bool {
+		select {
+		case <-done:
+			return true
+		default:
+			return false
+		}
+	}
+	assert.Never(t, isDone, time.Second, time.Millisecond*10)
+	close(hung)
+	assert.Eventually(t, isDone, time.Second*5, time.Millisecond*10)
+}
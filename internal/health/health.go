@@ -0,0 +1,225 @@
+// Package health serves liveness and readiness probes for container orchestrators like Kubernetes.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jon4hz/hashconv"
+	"github.com/whalesburg/dero-stratum-miner/internal/config"
+)
+
+// hashrateWindow is the window of the hashrate which is checked for readiness.
+const hashrateWindow = time.Second * 10
+
+// Miner is the state of the miner which is checked by the probes.
+type Miner interface {
+	IsConnected() bool
+	IsPaused() bool
+	GetJobsReceived() int64
+	GetHashes() uint64
+	GetHashrateAverage(window time.Duration) uint64
+}
+
+// Status is the JSON body of a probe.
+type Status struct {
+	Status string  `json:"status"` // ok or fail
+	Checks []Check `json:"checks"`
+}
+
+// Check is the result of a single check.
+type Check struct {
+	Name    string `json:"name"`
+	OK      bool   `json:"ok"`
+	Message string `json:"message"`
+}
+
+// OK reports whether all checks passed.
+func (s *Status) OK() bool {
+	return s.Status == "ok"
+}
+
+func newStatus(checks ...Check) *Status {
+	s := &Status{Status: "ok", Checks: checks}
+	for _, c := range checks {
+		if !c.OK {
+			s.Status = "fail"
+		}
+	}
+	return s
+}
+
+// Checker checks the health of the miner. The hash and job counters are sampled on every probe,
+// progress is measured from one probe to the next.
+type Checker struct {
+	m   Miner
+	cfg *config.Health
+	now func() time.Time
+
+	mu         sync.Mutex
+	hashes     uint64
+	lastHashes time.Time
+	jobs       int64
+	lastJob    time.Time
+}
+
+// NewChecker creates a checker with the thresholds of cfg.
+func NewChecker(m Miner, cfg *config.Health) *Checker {
+	return newChecker(m, cfg, time.Now)
+}
+
+func newChecker(m Miner, cfg *config.Health, now func() time.Time) *Checker {
+	started := now()
+	return &Checker{
+		m:          m,
+		cfg:        cfg,
+		now:        now,
+		lastHashes: started,
+		lastJob:    started,
+	}
+}
+
+// sample updates the counters and returns how long they didn't change.
+// The time without hashes only counts while the miner should be hashing.
+func (c *Checker) sample() (hashesIdle, jobAge time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	jobs := c.m.GetJobsReceived()
+	mining := c.m.IsConnected() && !c.m.IsPaused() && jobs > 0
+	if hashes := c.m.GetHashes(); hashes != c.hashes || !mining {
+		c.hashes, c.lastHashes = hashes, now
+	}
+	if jobs != c.jobs {
+		c.jobs, c.lastJob = jobs, now
+	}
+	return now.Sub(c.lastHashes), now.Sub(c.lastJob)
+}
+
+// Live checks whether the mining threads are wedged. A paused or disconnected miner is alive,
+// there's nothing to hash and a restart wouldn't help.
+func (c *Checker) Live() *Status {
+	idle, _ := c.sample()
+	check := Check{Name: "hashing", OK: true}
+	switch {
+	case c.m.IsPaused():
+		check.Message = "paused"
+	case !c.m.IsConnected():
+		check.Message = "not connected to the pool"
+	case c.m.GetJobsReceived() == 0:
+		check.Message = "waiting for a job"
+	case c.cfg.Stall > 0 && idle >= time.Duration(c.cfg.Stall):
+		check.OK = false
+		check.Message = fmt.Sprintf("no hashes computed for %s", idle.Truncate(time.Second))
+	default:
+		check.Message = "hashes are computed"
+	}
+	return newStatus(check)
+}
+
+// Ready checks whether the miner is authorized with the pool, has a current job and is hashing.
+func (c *Checker) Ready() *Status {
+	_, jobAge := c.sample()
+
+	pool := Check{Name: "pool", OK: c.m.IsConnected(), Message: "connected"}
+	if !pool.OK {
+		pool.Message = "not connected to the pool"
+	}
+
+	job := Check{Name: "job", OK: true, Message: fmt.Sprintf("last job received %s ago", jobAge.Truncate(time.Second))}
+	switch {
+	case c.m.GetJobsReceived() == 0:
+		job.OK = false
+		job.Message = "no job received"
+	case c.cfg.JobAge > 0 && jobAge >= time.Duration(c.cfg.JobAge):
+		job.OK = false
+		job.Message = fmt.Sprintf("no new job for %s", jobAge.Truncate(time.Second))
+	}
+
+	hashrate := c.m.GetHashrateAverage(hashrateWindow)
+	hashing := Check{Name: "hashing", OK: true, Message: fmt.Sprintf("hashing at %s/s", hashconv.Format(int64(hashrate)))}
+	switch {
+	case c.m.IsPaused():
+		hashing.OK = false
+		hashing.Message = "paused"
+	case hashrate == 0:
+		hashing.OK = false
+		hashing.Message = fmt.Sprintf("no hashes computed in the last %s", hashrateWindow)
+	case hashrate < c.cfg.MinHashrate:
+		hashing.OK = false
+		hashing.Message = fmt.Sprintf("hashrate of %s/s is below %s/s", hashconv.Format(int64(hashrate)), hashconv.Format(int64(c.cfg.MinHashrate)))
+	}
+	return newStatus(pool, job, hashing)
+}
+
+// LiveHandler serves the liveness probe, it responds with 503 if a check failed.
+func (c *Checker) LiveHandler() http.Handler {
+	return handler(c.Live)
+}
+
+// ReadyHandler serves the readiness probe, it responds with 503 if a check failed.
+func (c *Checker) ReadyHandler() http.Handler {
+	return handler(c.Ready)
+}
+
+func handler(probe func() *Status) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		s := probe()
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if !s.OK() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(s) // nolint: errcheck
+	})
+}
+
+// Server serves the probes on their own listener, without the authentication of the API.
+type Server struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	srv    *http.Server
+}
+
+func New(ctx context.Context, c *Checker, cfg *config.Health) *Server {
+	ctx, cancel := context.WithCancel(ctx)
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", c.LiveHandler())
+	mux.Handle("/readyz", c.ReadyHandler())
+	return &Server{
+		ctx:    ctx,
+		cancel: cancel,
+		srv: &http.Server{
+			Addr:              cfg.Listen,
+			Handler:           mux,
+			ReadHeaderTimeout: time.Second * 10,
+		},
+	}
+}
+
+func (s *Server) Serve() error {
+	go func() {
+		<-s.ctx.Done()
+		s.srv.Close() // nolint: errcheck
+	}()
+	if err := s.srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) Close() error {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalesburg/dero-stratum-miner/internal/config"
+	"github.com/whalesburg/dero-stratum-miner/internal/minertest"
+	"github.com/whalesburg/dero-stratum-miner/internal/testutil"
+)
+
+func testConfig() *config.Health {
+	return &config.Health{
+		Stall:       config.Duration(time.Minute),
+		JobAge:      config.Duration(time.Minute * 5),
+		MinHashrate: 1000,
+	}
+}
+
+func TestLive(t *testing.T) {
+	m := minertest.New()
+	m.Connected = false
+	clk := testutil.NewClock()
+	c := newChecker(m, testConfig(), clk.Now)
+
+	// idle miners are alive
+	clk.Add(time.Hour)
+	s := c.Live()
+	assert.True(t, s.OK())
+	assert.Equal(t, "not connected to the pool", s.Checks[0].Message)
+
+	m.Connected, m.Jobs = true, 1
+	assert.True(t, c.Live().OK())
+
+	// hashes computed between the probes
+	m.Hashes = 100
+	clk.Add(time.Second * 30)
+	assert.True(t, c.Live().OK())
+	clk.Add(time.Second * 59)
+	assert.True(t, c.Live().OK())
+
+	clk.Add(time.Second)
+	s = c.Live()
+	assert.False(t, s.OK())
+	assert.Equal(t, "fail", s.Status)
+	assert.Equal(t, "no hashes computed for 1m0s", s.Checks[0].Message)
+
+	m.Paused = true
+	assert.True(t, c.Live().OK())
+}
+
+func TestReady(t *testing.T) {
+	m := minertest.New()
+	m.Connected = false
+	clk := testutil.NewClock()
+	c := newChecker(m, testConfig(), clk.Now)
+
+	s := c.Ready()
+	assert.False(t, s.OK())
+	assert.Equal(t, []Check{
+		{Name: "pool", Message: "not connected to the pool"},
+		{Name: "job", Message: "no job received"},
+		{Name: "hashing", Message: "no hashes computed in the last 10s"},
+	}, s.Checks)
+
+	m.Connected, m.Jobs, m.Hashrate = true, 1, 1500
+	clk.Add(time.Second * 20)
+	s = c.Ready()
+	assert.True(t, s.OK())
+	assert.Equal(t, []Check{
+		{Name: "pool", OK: true, Message: "connected"},
+		{Name: "job", OK: true, Message: "last job received 0s ago"},
+		{Name: "hashing", OK: true, Message: "hashing at 1.5 kH/s"},
+	}, s.Checks)
+
+	m.Hashrate = 500
+	s = c.Ready()
+	assert.False(t, s.OK())
+	assert.Equal(t, "hashrate of 500 H/s is below 1 kH/s", s.Checks[2].Message)
+
+	m.Hashrate = 1500
+	clk.Add(time.Minute * 5)
+	s = c.Ready()
+	assert.False(t, s.OK())
+	assert.Equal(t, "no new job for 5m0s", s.Checks[1].Message)
+
+	m.Jobs = 2
+	assert.True(t, c.Ready().OK())
+
+	m.Paused = true
+	s = c.Ready()
+	assert.False(t, s.OK())
+	assert.Equal(t, "paused", s.Checks[2].Message)
+}
+
+func TestHandler(t *testing.T) {
+	m := minertest.New()
+	m.Jobs, m.Hashrate = 1, 1500
+	c := NewChecker(m, testConfig())
+
+	for _, tc := range []struct {
+		name   string
+		h      http.Handler
+		status int
+	}{
+		{"healthz", c.LiveHandler(), http.StatusOK},
+		{"readyz", c.ReadyHandler(), http.StatusOK},
+	} {
+		rec := httptest.NewRecorder()
+		tc.h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+tc.name, nil))
+		assert.Equal(t, tc.status, rec.Code, tc.name)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	}
+
+	m.Connected = false
+	rec := httptest.NewRecorder()
+	c.ReadyHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	var s Status
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &s))
+	assert.Equal(t, "fail", s.Status)
+	assert.Len(t, s.Checks, 3)
+
+	rec = httptest.NewRecorder()
+	c.LiveHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/healthz", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -0,0 +1,47 @@
+// Package hiveos integrates the miner as custom miner into HiveOS.
+package hiveos
+
+import (
+	"github.com/whalesburg/dero-stratum-miner/internal/api"
+	"github.com/whalesburg/dero-stratum-miner/internal/sensors"
+)
+
+// Algo is the name of the algorithm in HiveOS flight sheets.
+const Algo = "astrobwt"
+
+// Stats is the JSON h-stats.sh reports to the HiveOS agent. The arrays hold one value per mining thread.
+type Stats struct {
+	Hashrates []float64 `json:"hs"`
+	Units     string    `json:"hs_units"`
+	Temps     []int     `json:"temp"`
+	Fans      []int     `json:"fan"`
+	Uptime    int64     `json:"uptime"`
+	Version   string    `json:"ver"`
+	Shares    []uint64  `json:"ar"` // accepted, rejected and invalid
+	Algo      string    `json:"algo"`
+	TotalKHs  float64   `json:"total_khs"` // read by h-stats.sh into $khs
+}
+
+// NewStats converts the stats of the miner. The temperatures are the ones of the CPU packages
+// and the fans are in percent.
+func NewStats(s *api.Stats, temps []float64, fans []int) *Stats {
+	stats := &Stats{
+		Hashrates: make([]float64, 0, len(s.Threads)),
+		Units:     "hs",
+		Temps:     sensors.Celsius(temps),
+		Fans:      make([]int, 0, len(fans)),
+		Uptime:    s.Uptime,
+		Version:   s.Version,
+		Shares:    []uint64{s.Accepted, s.Late(), s.Invalid},
+		Algo:      Algo,
+		TotalKHs:  float64(s.Hashrate) / 1000,
+	}
+	for _, h := range s.Threads {
+		stats.Hashrates = append(stats.Hashrates, float64(h))
+	}
+	if len(stats.Hashrates) == 0 {
+		stats.Hashrates = append(stats.Hashrates, float64(s.Hashrate))
+	}
+	stats.Fans = append(stats.Fans, fans...)
+	return stats
+}
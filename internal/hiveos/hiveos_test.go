@@ -0,0 +1,37 @@
+package hiveos
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalesburg/dero-stratum-miner/internal/api/apitest"
+)
+
+func TestStats(t *testing.T) {
+	want, err := os.ReadFile("testdata/hiveos_stats.json")
+	require.NoError(t, err)
+
+	got, err := json.Marshal(NewStats(apitest.Stats(t), []float64{61.5, 58.6}, []int{100, 50}))
+	require.NoError(t, err)
+	assert.Equal(t, strings.TrimSpace(string(want)), string(got))
+}
+
+func TestStatsWithoutThreads(t *testing.T) {
+	s := apitest.Stats(t)
+	s.Threads = nil
+
+	got, err := json.Marshal(NewStats(s, nil, nil))
+	require.NoError(t, err)
+	// HiveOS expects arrays, not null
+	assert.Contains(t, string(got), `"hs":[5342],"hs_units":"hs","temp":[],"fan":[]`)
+}
+
+func TestStatsInvalidAhead(t *testing.T) {
+	s := apitest.Stats(t)
+	s.Rejected, s.Invalid = 1, 2
+	assert.Equal(t, []uint64{s.Accepted, 0, 2}, NewStats(s, nil, nil).Shares)
+}
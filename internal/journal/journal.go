@@ -0,0 +1,338 @@
+// Package journal keeps an append-only record of every share, so payouts can be checked against the pool.
+// The entries are stored as JSON lines in one file per day (UTC), old files are removed after the retention.
+package journal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/teivah/broadcast"
+	"github.com/whalesburg/dero-stratum-miner/internal/config"
+	miner "github.com/whalesburg/dero-stratum-miner/internal/dero-stratum-miner"
+)
+
+// Outcomes of a share. Every submitted share gets a second entry once the pool answered.
+const (
+	OutcomeSubmitted = "submitted"
+	OutcomeAccepted  = "accepted"
+	OutcomeRejected  = "rejected"
+)
+
+const (
+	filePrefix = "shares-"
+	fileSuffix = ".jsonl"
+	dayLayout  = "2006-01-02"
+
+	// eventBuffer holds bursts of shares while the disk is slow, so the miner doesn't wait for the journal.
+	eventBuffer   = 256
+	pruneInterval = time.Hour
+)
+
+// Entry is a single line of the journal.
+type Entry struct {
+	Time       time.Time `json:"time"`
+	Pool       string    `json:"pool"`
+	JobID      string    `json:"job_id"`
+	Height     float64   `json:"height"`
+	Difficulty uint64    `json:"difficulty"`
+	Nonce      string    `json:"nonce"`
+	Result     string    `json:"result"`
+	Outcome    string    `json:"outcome"`
+	Reason     string    `json:"reason,omitempty"`
+	LatencyMS  int64     `json:"latency_ms,omitempty"`
+}
+
+// Miner is the source of the shares.
+type Miner interface {
+	NewShareEventListener(buff int) *broadcast.Listener[*miner.Event]
+}
+
+// Journal appends entries to the file of the current day.
+type Journal struct {
+	dir       string
+	retention time.Duration
+	now       func() time.Time
+
+	mu   sync.Mutex
+	file *os.File
+	day  string
+}
+
+// DefaultDir returns the default directory of the journal. systemd sets $STATE_DIRECTORY
+// for units with StateDirectory=.
+func DefaultDir() string {
+	dir := os.Getenv("STATE_DIRECTORY")
+	if dir == "" {
+		userDir, err := os.UserConfigDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(userDir, "dero-stratum-miner")
+	}
+	return filepath.Join(dir, "shares")
+}
+
+// Open opens the journal in the directory of cfg and removes the files which are older than the retention.
+func Open(cfg *config.Journal) (*Journal, error) {
+	return open(cfg, time.Now)
+}
+
+func open(cfg *config.Journal, now func() time.Time) (*Journal, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o700); err != nil {
+		return nil, err
+	}
+	j := &Journal{
+		dir:       cfg.Dir,
+		retention: time.Duration(cfg.Retention),
+		now:       now,
+	}
+	if err := j.Prune(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+func fileName(day string) string {
+	return filePrefix + day + fileSuffix
+}
+
+// Append writes e to the file of its day. The file is synced, shares are rare and shouldn't get lost.
+func (j *Journal) Append(e *Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	day := e.Time.UTC().Format(dayLayout)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil || j.day != day {
+		if j.file != nil {
+			j.file.Close() // nolint: errcheck
+			j.file = nil
+		}
+		f, err := os.OpenFile(filepath.Join(j.dir, fileName(day)), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			return err
+		}
+		j.file, j.day = f, day
+	}
+	if _, err := j.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return j.file.Sync()
+}
+
+// Prune removes the files whose entries are all older than the retention. A retention of 0 keeps all files.
+func (j *Journal) Prune() error {
+	if j.retention <= 0 {
+		return nil
+	}
+	cutoff := j.now().Add(-j.retention)
+	files, err := files(j.dir)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if !f.end.After(cutoff) {
+			if err := os.Remove(f.path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Close closes the current file.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file = nil
+	return err
+}
+
+// Start records the shares of the miner until the context is cancelled. It subscribes to the shares before
+// it returns, so it has to be called before the miner is started to record all shares. The returned channel
+// is closed once the shares which were received until the cancellation are recorded.
+func (j *Journal) Start(ctx context.Context, m Miner, logger logr.Logger) <-chan struct{} {
+	l := m.NewShareEventListener(eventBuffer)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer l.Close()
+		j.run(ctx, l, logger.WithName("journal"))
+	}()
+	return done
+}
+
+func (j *Journal) run(ctx context.Context, l *broadcast.Listener[*miner.Event], logger logr.Logger) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case ev, ok := <-l.Ch():
+			if !ok {
+				return
+			}
+			j.record(ev, logger)
+		case <-ticker.C:
+			if err := j.Prune(); err != nil {
+				logger.Error(err, "Failed to remove old shares")
+			}
+		case <-ctx.Done():
+			// the miner doesn't wait for the listener anymore, record what's left in the buffer
+			for {
+				select {
+				case ev, ok := <-l.Ch():
+					if !ok {
+						return
+					}
+					j.record(ev, logger)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (j *Journal) record(ev *miner.Event, logger logr.Logger) {
+	e := entry(ev)
+	if e == nil {
+		return
+	}
+	if err := j.Append(e); err != nil {
+		logger.Error(err, "Failed to record share", "job_id", e.JobID, "outcome", e.Outcome)
+	}
+}
+
+// entry converts a share event, other events return nil.
+func entry(ev *miner.Event) *Entry {
+	var outcome string
+	switch ev.Type {
+	case miner.EventShareSubmitted:
+		outcome = OutcomeSubmitted
+	case miner.EventShareAccepted:
+		outcome = OutcomeAccepted
+	case miner.EventShareRejected:
+		outcome = OutcomeRejected
+	default:
+		return nil
+	}
+	s, ok := ev.Data.(*miner.ShareEvent)
+	if !ok {
+		return nil
+	}
+	return &Entry{
+		Time:       ev.Time,
+		Pool:       s.Pool,
+		JobID:      s.JobID,
+		Height:     s.Height,
+		Difficulty: s.Difficulty,
+		Nonce:      s.Nonce,
+		Result:     s.Result,
+		Outcome:    outcome,
+		Reason:     s.Reason,
+		LatencyMS:  s.LatencyMS,
+	}
+}
+
+type file struct {
+	path       string
+	start, end time.Time
+}
+
+// files returns the files of the journal, oldest first.
+func files(dir string) ([]file, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []file
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+		start, err := time.Parse(dayLayout, strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix))
+		if err != nil {
+			continue
+		}
+		files = append(files, file{path: filepath.Join(dir, name), start: start, end: start.AddDate(0, 0, 1)})
+	}
+	sort.Slice(files, func(i, k int) bool { return files[i].start.Before(files[k].start) })
+	return files, nil
+}
+
+// Filter selects entries. Zero values match everything.
+type Filter struct {
+	From time.Time // inclusive
+	To   time.Time // exclusive
+	Pool string    // part of the pool url
+}
+
+func (f *Filter) match(e *Entry) bool {
+	if !f.From.IsZero() && e.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !e.Time.Before(f.To) {
+		return false
+	}
+	return f.Pool == "" || strings.Contains(e.Pool, f.Pool)
+}
+
+// Read returns the entries of the journal in dir which match the filter. Lines which can't be parsed,
+// e.g. a partial line after a crash, are skipped.
+func Read(dir string, f *Filter) ([]*Entry, error) {
+	files, err := files(dir)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*Entry
+	for _, file := range files {
+		if (!f.From.IsZero() && !file.end.After(f.From)) || (!f.To.IsZero() && !file.start.Before(f.To)) {
+			continue
+		}
+		data, err := os.ReadFile(file.path)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			var e Entry
+			if line == "" || json.Unmarshal([]byte(line), &e) != nil {
+				continue
+			}
+			if f.match(&e) {
+				entries = append(entries, &e)
+			}
+		}
+	}
+	return entries, nil
+}
+
+// ParseTime parses an absolute time in RFC 3339, a date like 2022-07-01 in the local time zone
+// or a duration like 24h which is subtracted from now.
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(dayLayout, s, now.Location()); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, use a duration like 24h, a date like 2022-07-01 or RFC 3339", s)
+}
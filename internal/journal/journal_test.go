@@ -0,0 +1,220 @@
+package journal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalesburg/dero-stratum-miner/internal/config"
+	miner "github.com/whalesburg/dero-stratum-miner/internal/dero-stratum-miner"
+	"github.com/whalesburg/dero-stratum-miner/internal/minertest"
+)
+
+const (
+	poolA = "stratum+tls://pool.whalesburg.com:4300"
+	poolB = "stratum+tcp://backup.example.com:10300"
+)
+
+var day = time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC)
+
+func share(t time.Time, pool, jobID, outcome string) *Entry {
+	e := &Entry{Time: t, Pool: pool, JobID: jobID, Height: 1234, Difficulty: 50000, Nonce: "n" + jobID, Result: "r" + jobID, Outcome: outcome}
+	switch outcome {
+	case OutcomeAccepted:
+		e.LatencyMS = 40
+	case OutcomeRejected:
+		e.LatencyMS = 60
+		e.Reason = "low difficulty share"
+	}
+	return e
+}
+
+func TestAppend(t *testing.T) {
+	dir := t.TempDir()
+	j, err := open(&config.Journal{Dir: dir}, func() time.Time { return day })
+	require.NoError(t, err)
+	defer j.Close()
+
+	require.NoError(t, j.Append(share(day.Add(time.Hour*23), poolA, "1", OutcomeSubmitted)))
+	require.NoError(t, j.Append(share(day.Add(time.Hour*23+time.Second), poolA, "1", OutcomeAccepted)))
+	// the next day starts a new file
+	require.NoError(t, j.Append(share(day.Add(time.Hour*24), poolA, "2", OutcomeSubmitted)))
+
+	data, err := os.ReadFile(filepath.Join(dir, "shares-2022-07-01.jsonl"))
+	require.NoError(t, err)
+	assert.Equal(t, `{"time":"2022-07-01T23:00:00Z","pool":"stratum+tls://pool.whalesburg.com:4300","job_id":"1","height":1234,"difficulty":50000,"nonce":"n1","result":"r1","outcome":"submitted"}
+{"time":"2022-07-01T23:00:01Z","pool":"stratum+tls://pool.whalesburg.com:4300","job_id":"1","height":1234,"difficulty":50000,"nonce":"n1","result":"r1","outcome":"accepted","latency_ms":40}
+`, string(data))
+	assert.FileExists(t, filepath.Join(dir, "shares-2022-07-02.jsonl"))
+
+	// a restarted miner appends to the existing file
+	require.NoError(t, j.Close())
+	j, err = open(&config.Journal{Dir: dir}, func() time.Time { return day })
+	require.NoError(t, err)
+	require.NoError(t, j.Append(share(day.Add(time.Hour*23+time.Minute), poolA, "3", OutcomeSubmitted)))
+	entries, err := Read(dir, &Filter{})
+	require.NoError(t, err)
+	assert.Len(t, entries, 4)
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"shares-2022-06-01.jsonl", "shares-2022-06-23.jsonl", "shares-2022-06-24.jsonl", "notes.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o600))
+	}
+	now := day.Add(time.Hour * 12)
+	_, err := open(&config.Journal{Dir: dir, Retention: config.Duration(time.Hour * 24 * 7)}, func() time.Time { return now })
+	require.NoError(t, err)
+
+	names, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	// 2022-06-24 still holds entries of the last 7 days
+	assert.Equal(t, []string{filepath.Join(dir, "notes.txt"), filepath.Join(dir, "shares-2022-06-24.jsonl")}, names)
+
+	// a retention of 0 keeps all files
+	_, err = open(&config.Journal{Dir: dir}, func() time.Time { return now.AddDate(1, 0, 0) })
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, "shares-2022-06-24.jsonl"))
+}
+
+func TestRead(t *testing.T) {
+	dir := t.TempDir()
+	j, err := open(&config.Journal{Dir: dir}, time.Now)
+	require.NoError(t, err)
+	defer j.Close()
+	for i, e := range []*Entry{
+		share(day.Add(-time.Hour), poolA, "1", OutcomeAccepted),
+		share(day.Add(time.Hour), poolA, "2", OutcomeAccepted),
+		share(day.Add(time.Hour*2), poolB, "3", OutcomeRejected),
+		share(day.Add(time.Hour*25), poolA, "4", OutcomeAccepted),
+	} {
+		require.NoError(t, j.Append(e), i)
+	}
+	// a partial line after a crash is skipped
+	f, err := os.OpenFile(filepath.Join(dir, "shares-2022-07-01.jsonl"), os.O_WRONLY|os.O_APPEND, 0o600)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"time":"2022-07-01T03:00:00Z","pool":"str`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	jobs := func(f *Filter) []string {
+		entries, err := Read(dir, f)
+		require.NoError(t, err)
+		var ids []string
+		for _, e := range entries {
+			ids = append(ids, e.JobID)
+		}
+		return ids
+	}
+	assert.Equal(t, []string{"1", "2", "3", "4"}, jobs(&Filter{}))
+	assert.Equal(t, []string{"2", "3"}, jobs(&Filter{From: day, To: day.Add(time.Hour * 24)}))
+	assert.Equal(t, []string{"2", "4"}, jobs(&Filter{From: day, Pool: "whalesburg"}))
+	assert.Equal(t, []string{"1"}, jobs(&Filter{To: day.Add(time.Hour)}))
+}
+
+func TestSummarize(t *testing.T) {
+	entries := []*Entry{
+		share(day, poolA, "1", OutcomeSubmitted),
+		share(day.Add(time.Second), poolA, "1", OutcomeAccepted),
+		share(day.Add(time.Minute), poolA, "2", OutcomeSubmitted),
+		share(day.Add(time.Minute+time.Second), poolA, "2", OutcomeRejected),
+		share(day.Add(time.Minute*2), poolA, "3", OutcomeSubmitted),
+		share(day.Add(time.Hour), poolB, "4", OutcomeSubmitted),
+		share(day.Add(time.Hour+time.Second), poolB, "4", OutcomeAccepted),
+	}
+	assert.Equal(t, []*Summary{
+		{
+			Pool:       poolB,
+			From:       day.Add(time.Hour),
+			To:         day.Add(time.Hour + time.Second),
+			Submitted:  1,
+			Accepted:   1,
+			Difficulty: 50000,
+			LatencyMS:  40,
+		},
+		{
+			Pool:       poolA,
+			From:       day,
+			To:         day.Add(time.Minute * 2),
+			Submitted:  3,
+			Accepted:   1,
+			Rejected:   1,
+			Unanswered: 1,
+			Difficulty: 50000,
+			LatencyMS:  50,
+			Reasons:    map[string]int{"low difficulty share": 1},
+		},
+	}, Summarize(entries))
+}
+
+func TestStart(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Open(&config.Journal{Dir: dir})
+	require.NoError(t, err)
+	defer j.Close()
+	m := minertest.New()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := j.Start(ctx, m, logr.Discard())
+
+	m.Shares.Notify(&miner.Event{Type: miner.EventJob, Time: time.Now()})
+	now := time.Now().UTC().Truncate(time.Second)
+	m.Shares.Notify(&miner.Event{Type: miner.EventShareRejected, Time: now, Data: &miner.ShareEvent{
+		Pool: poolA, JobID: "7", Nonce: "n7", Result: "r7", Height: 1234, Difficulty: 50000, Reason: "stale", LatencyMS: 42,
+	}})
+	cancel()
+	<-done
+
+	entries, err := Read(dir, &Filter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, &Entry{
+		Time: now, Pool: poolA, JobID: "7", Height: 1234, Difficulty: 50000, Nonce: "n7", Result: "r7",
+		Outcome: OutcomeRejected, Reason: "stale", LatencyMS: 42,
+	}, entries[0])
+}
+
+func TestStartFlood(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Open(&config.Journal{Dir: dir})
+	require.NoError(t, err)
+	defer j.Close()
+	m := minertest.New()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := j.Start(ctx, m, logr.Discard())
+
+	// more shares than the buffer holds, the miner waits for the journal
+	n := eventBuffer * 4
+	for i := 0; i < n; i++ {
+		m.Shares.Notify(&miner.Event{Type: miner.EventShareSubmitted, Time: time.Now(), Data: &miner.ShareEvent{Pool: poolA, JobID: strconv.Itoa(i)}})
+	}
+	cancel()
+	<-done
+
+	entries, err := Read(dir, &Filter{})
+	require.NoError(t, err)
+	require.Len(t, entries, n)
+	for i, e := range entries {
+		assert.Equal(t, strconv.Itoa(i), e.JobID)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2022, 7, 1, 12, 0, 0, 0, time.UTC)
+	for s, want := range map[string]time.Time{
+		"24h":                  now.Add(-time.Hour * 24),
+		"2022-06-15":           time.Date(2022, 6, 15, 0, 0, 0, 0, time.UTC),
+		"2022-06-15T08:30:00Z": time.Date(2022, 6, 15, 8, 30, 0, 0, time.UTC),
+	} {
+		got, err := ParseTime(s, now)
+		require.NoError(t, err, s)
+		assert.True(t, want.Equal(got), s)
+	}
+	_, err := ParseTime("yesterday", now)
+	assert.Error(t, err)
+}
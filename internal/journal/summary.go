@@ -0,0 +1,81 @@
+package journal
+
+import (
+	"sort"
+	"time"
+)
+
+// Summary sums up the shares of a pool.
+type Summary struct {
+	Pool       string         `json:"pool"`
+	From       time.Time      `json:"from"`
+	To         time.Time      `json:"to"`
+	Submitted  int            `json:"submitted"`
+	Accepted   int            `json:"accepted"`
+	Rejected   int            `json:"rejected"`
+	Unanswered int            `json:"unanswered"` // submitted shares without an answer of the pool
+	Difficulty uint64         `json:"difficulty"` // sum of the difficulty of the accepted shares
+	LatencyMS  int64          `json:"latency_ms"` // average latency of the answered shares
+	Reasons    map[string]int `json:"reasons,omitempty"`
+}
+
+type shareKey struct {
+	pool, jobID, nonce string
+}
+
+// Summarize sums up the entries by pool, sorted by the pool url.
+func Summarize(entries []*Entry) []*Summary {
+	pools := make(map[string]*Summary)
+	answered := make(map[shareKey]bool)
+	latency := make(map[string]int64)
+	for _, e := range entries {
+		s, ok := pools[e.Pool]
+		if !ok {
+			s = &Summary{Pool: e.Pool, From: e.Time, To: e.Time}
+			pools[e.Pool] = s
+		}
+		if e.Time.Before(s.From) {
+			s.From = e.Time
+		}
+		if e.Time.After(s.To) {
+			s.To = e.Time
+		}
+		key := shareKey{e.Pool, e.JobID, e.Nonce}
+		switch e.Outcome {
+		case OutcomeSubmitted:
+			s.Submitted++
+			if !answered[key] {
+				answered[key] = false
+			}
+			continue
+		case OutcomeAccepted:
+			s.Accepted++
+			s.Difficulty += e.Difficulty
+		case OutcomeRejected:
+			s.Rejected++
+			if s.Reasons == nil {
+				s.Reasons = make(map[string]int)
+			}
+			s.Reasons[e.Reason]++
+		default:
+			continue
+		}
+		answered[key] = true
+		latency[e.Pool] += e.LatencyMS
+	}
+	for key, ok := range answered {
+		if !ok {
+			pools[key.pool].Unanswered++
+		}
+	}
+
+	summaries := make([]*Summary, 0, len(pools))
+	for _, s := range pools {
+		if n := s.Accepted + s.Rejected; n > 0 {
+			s.LatencyMS = latency[s.Pool] / int64(n)
+		}
+		summaries = append(summaries, s)
+	}
+	sort.Slice(summaries, func(i, k int) bool { return summaries[i].Pool < summaries[k].Pool })
+	return summaries
+}
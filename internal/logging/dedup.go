@@ -0,0 +1,194 @@
+package logging
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// dedupLevel is the lowest level which is deduplicated. Info messages like accepted shares repeat on purpose.
+const dedupLevel = zapcore.WarnLevel
+
+// dedupCore collapses repeated warnings and errors. Every message gets its own window, the first
+// burst entries of a window are written and the rest are only counted. Once the window is over,
+// "N similar messages suppressed" is written instead.
+type dedupCore struct {
+	zapcore.Core
+	d *deduper
+}
+
+func (c *dedupCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if entry.Level < dedupLevel {
+		return c.Core.Check(entry, ce)
+	}
+	// only count the entries which would be written
+	if c.Core.Check(entry, nil) == nil {
+		return ce
+	}
+	if !c.d.allow(entry) {
+		return ce
+	}
+	return c.Core.Check(entry, ce)
+}
+
+func (c *dedupCore) With(fields []zapcore.Field) zapcore.Core {
+	return &dedupCore{c.Core.With(fields), c.d}
+}
+
+// dedupKey identifies similar messages. Numbers in the message are ignored, so "will try again in 2s"
+// and "will try again in 4s" are the same message.
+type dedupKey struct {
+	level zapcore.Level
+	name  string
+	msg   string
+}
+
+type dedupWindow struct {
+	start      time.Time
+	count      int
+	suppressed int
+	last       string // the latest suppressed message
+}
+
+type deduper struct {
+	core   zapcore.Core // writes the summaries
+	window time.Duration
+	burst  int
+	now    func() time.Time
+
+	mu      sync.Mutex
+	windows map[dedupKey]*dedupWindow
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newDeduper(core zapcore.Core, window time.Duration, burst int) *deduper {
+	if burst < 1 {
+		burst = 1
+	}
+	return &deduper{
+		core:    core,
+		window:  window,
+		burst:   burst,
+		now:     time.Now,
+		windows: make(map[dedupKey]*dedupWindow),
+		done:    make(chan struct{}),
+	}
+}
+
+// start reports the suppressed messages of finished windows, even if the messages stopped.
+func (d *deduper) start() {
+	interval := time.Second
+	if d.window < interval {
+		interval = d.window
+	}
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				d.flush(false)
+			case <-d.done:
+				return
+			}
+		}
+	}()
+}
+
+func (d *deduper) allow(entry zapcore.Entry) bool {
+	k := dedupKey{level: entry.Level, name: entry.LoggerName, msg: withoutNumbers(entry.Message)}
+	now := d.now()
+
+	d.mu.Lock()
+	w := d.windows[k]
+	var finished *dedupWindow
+	if w == nil || now.Sub(w.start) >= d.window {
+		if w != nil && w.suppressed > 0 {
+			finished = w
+		}
+		w = &dedupWindow{start: now}
+		d.windows[k] = w
+	}
+	w.count++
+	ok := w.count <= d.burst
+	if !ok {
+		w.suppressed++
+		w.last = entry.Message
+	}
+	d.mu.Unlock()
+
+	if finished != nil {
+		d.writeSummary(k, finished, now)
+	}
+	return ok
+}
+
+// flush reports the suppressed messages of the finished windows, or of all windows if all is set.
+// Finished windows are removed.
+func (d *deduper) flush(all bool) {
+	type summary struct {
+		k dedupKey
+		w *dedupWindow
+	}
+	now := d.now()
+	var summaries []summary
+
+	d.mu.Lock()
+	for k, w := range d.windows {
+		if !all && now.Sub(w.start) < d.window {
+			continue
+		}
+		if w.suppressed > 0 {
+			summaries = append(summaries, summary{k, w})
+		}
+		delete(d.windows, k)
+	}
+	d.mu.Unlock()
+
+	for _, s := range summaries {
+		d.writeSummary(s.k, s.w, now)
+	}
+}
+
+func (d *deduper) writeSummary(k dedupKey, w *dedupWindow, now time.Time) {
+	entry := zapcore.Entry{
+		Level:      k.level,
+		LoggerName: k.name,
+		Time:       now,
+		Message:    fmt.Sprintf("%d similar messages suppressed", w.suppressed),
+	}
+	if ce := d.core.Check(entry, nil); ce != nil {
+		ce.Write(zap.String("message", w.last), zap.Int("suppressed", w.suppressed))
+	}
+}
+
+// Close stops the background flush and reports all suppressed messages.
+func (d *deduper) Close() error {
+	close(d.done)
+	d.wg.Wait()
+	d.flush(true)
+	return nil
+}
+
+// withoutNumbers replaces all digits by '#'.
+func withoutNumbers(s string) string {
+	b := []byte(s)
+	changed := false
+	for i, c := range b {
+		if c >= '0' && c <= '9' {
+			b[i] = '#'
+			changed = true
+		}
+	}
+	if !changed {
+		return s
+	}
+	return string(b)
+}
@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/zapr"
+	"github.com/stretchr/testify/assert"
+	"github.com/whalesburg/dero-stratum-miner/internal/testutil"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newTestDeduper(clk *testutil.Clock, window time.Duration, burst int, level zapcore.Level) (*deduper, *observer.ObservedLogs) {
+	core, logs := observer.New(level)
+	d := newDeduper(core, window, burst)
+	d.now = clk.Now
+	return d, logs
+}
+
+func messages(logs *observer.ObservedLogs) []string {
+	var msgs []string
+	for _, e := range logs.All() {
+		msgs = append(msgs, e.Message)
+	}
+	return msgs
+}
+
+func TestDedup(t *testing.T) {
+	clk := testutil.NewClock()
+	d, logs := newTestDeduper(clk, time.Second*10, 1, zapcore.InfoLevel)
+	logger := zapr.NewLogger(zap.New(&dedupCore{d.core, d}))
+
+	for i := 0; i < 32; i++ {
+		logger.Error(errors.New("invalid"), "Blockwork could not be decoded successfully", "n", i)
+	}
+	assert.Equal(t, []string{"Blockwork could not be decoded successfully"}, messages(logs))
+
+	// numbers are ignored
+	logger.Error(nil, "dial error, will try again in 1.000000 seconds")
+	logger.Error(nil, "dial error, will try again in 2.000000 seconds")
+	// info messages are never suppressed
+	logger.Info("Share accepted")
+	logger.Info("Share accepted")
+	assert.Len(t, logs.All(), 4)
+
+	// the next message after the window reports the suppressed ones first
+	clk.Add(time.Second * 10)
+	logger.Error(nil, "Blockwork could not be decoded successfully")
+	all := logs.TakeAll()
+	assert.Len(t, all, 6)
+	assert.Equal(t, "31 similar messages suppressed", all[4].Message)
+	assert.Equal(t, zapcore.ErrorLevel, all[4].Level)
+	assert.Equal(t, "Blockwork could not be decoded successfully", all[4].ContextMap()["message"])
+	assert.Equal(t, "Blockwork could not be decoded successfully", all[5].Message)
+
+	// windows which are over are reported by the flush
+	clk.Add(time.Second * 10)
+	d.flush(false)
+	assert.Equal(t, []string{"1 similar messages suppressed"}, messages(logs))
+	assert.Empty(t, d.windows)
+}
+
+func TestDedupBurstAndKeys(t *testing.T) {
+	d, logs := newTestDeduper(testutil.NewClock(), time.Minute, 3, zapcore.InfoLevel)
+	logger := zapr.NewLogger(zap.New(&dedupCore{d.core, d}))
+
+	for i := 0; i < 5; i++ {
+		logger.WithName("stratum").Error(nil, "failed to read line")
+		logger.WithName("miner").Error(nil, "failed to read line")
+	}
+	assert.Len(t, logs.TakeAll(), 6, "each logger has its own window")
+
+	d.flush(false)
+	assert.Empty(t, logs.All(), "the window isn't over yet")
+
+	assert.NoError(t, d.Close())
+	all := logs.All()
+	assert.Len(t, all, 2)
+	for _, e := range all {
+		assert.Equal(t, "2 similar messages suppressed", e.Message)
+	}
+}
+
+func TestDedupIgnoresDisabledEntries(t *testing.T) {
+	d, logs := newTestDeduper(testutil.NewClock(), time.Minute, 1, zapcore.ErrorLevel)
+	logger := zap.New(&dedupCore{d.core, d})
+
+	for i := 0; i < 3; i++ {
+		logger.Warn("warning which isn't logged")
+	}
+	assert.Empty(t, d.windows)
+	assert.Empty(t, logs.All())
+}
@@ -0,0 +1,119 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// JournaldSocket is the socket of the native journald protocol.
+const JournaldSocket = "/run/systemd/journal/socket"
+
+// journald sends the entries to the systemd journal with the native protocol,
+// every field of an entry becomes a journal field.
+type journald struct {
+	identifier string
+	mu         sync.Mutex
+	conn       *net.UnixConn
+}
+
+func newJournald(socket, identifier string) (*journald, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &journald{identifier: identifier, conn: conn}, nil
+}
+
+func (j *journald) write(entry zapcore.Entry, fields []field) error {
+	var b bytes.Buffer
+	appendJournalField(&b, "MESSAGE", entry.Message)
+	appendJournalField(&b, "PRIORITY", strconv.Itoa(severity(entry.Level)))
+	appendJournalField(&b, "SYSLOG_IDENTIFIER", j.identifier)
+	if entry.LoggerName != "" {
+		appendJournalField(&b, "LOGGER", entry.LoggerName)
+	}
+	if entry.Caller.Defined {
+		appendJournalField(&b, "CODE_FILE", entry.Caller.File)
+		appendJournalField(&b, "CODE_LINE", strconv.Itoa(entry.Caller.Line))
+		if entry.Caller.Function != "" {
+			appendJournalField(&b, "CODE_FUNC", entry.Caller.Function)
+		}
+	}
+	if entry.Stack != "" {
+		appendJournalField(&b, "STACKTRACE", entry.Stack)
+	}
+	for _, f := range fields {
+		appendJournalField(&b, journalFieldName(f.key), f.value)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, err := j.conn.Write(b.Bytes())
+	return err
+}
+
+func (j *journald) Close() error {
+	return j.conn.Close()
+}
+
+// appendJournalField writes KEY=value, values with newlines are written as binary with their length.
+func appendJournalField(b *bytes.Buffer, key, value string) {
+	b.WriteString(key)
+	if !strings.Contains(value, "\n") {
+		b.WriteByte('=')
+		b.WriteString(value)
+		b.WriteByte('\n')
+		return
+	}
+	b.WriteByte('\n')
+	binary.Write(b, binary.LittleEndian, uint64(len(value))) // nolint: errcheck
+	b.WriteString(value)
+	b.WriteByte('\n')
+}
+
+// journalFieldName converts a key to a valid journal field name.
+// Only uppercase letters, digits and underscores are allowed and names must not start with an underscore or a digit.
+func journalFieldName(key string) string {
+	name := []byte(strings.ToUpper(key))
+	for i, c := range name {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			name[i] = '_'
+		}
+	}
+	s := strings.TrimLeft(string(name), "_")
+	if s == "" || (s[0] >= '0' && s[0] <= '9') {
+		s = "F_" + s
+	}
+	if len(s) > 64 {
+		s = s[:64]
+	}
+	return s
+}
+
+// journaldAvailable reports whether the journal socket exists.
+func journaldAvailable() bool {
+	_, err := os.Stat(JournaldSocket)
+	return err == nil
+}
+
+// severity maps the zap levels to syslog severities.
+func severity(l zapcore.Level) int {
+	switch {
+	case l < zapcore.InfoLevel:
+		return 7 // debug
+	case l == zapcore.InfoLevel:
+		return 6 // info
+	case l == zapcore.WarnLevel:
+		return 4 // warning
+	case l == zapcore.ErrorLevel:
+		return 3 // err
+	}
+	return 2 // crit
+}
@@ -0,0 +1,90 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/go-logr/zapr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// parseJournalFields decodes a datagram of the native journald protocol.
+func parseJournalFields(t *testing.T, b []byte) map[string]string {
+	t.Helper()
+	fields := map[string]string{}
+	for len(b) > 0 {
+		i := bytes.IndexAny(b, "=\n")
+		require.GreaterOrEqual(t, i, 0)
+		key := string(b[:i])
+		if b[i] == '=' {
+			end := bytes.IndexByte(b, '\n')
+			fields[key] = string(b[i+1 : end])
+			b = b[end+1:]
+			continue
+		}
+		n := binary.LittleEndian.Uint64(b[i+1 : i+9])
+		fields[key] = string(b[i+9 : i+9+int(n)])
+		b = b[i+9+int(n)+1:]
+	}
+	return fields
+}
+
+func TestJournald(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix datagram sockets are not supported")
+	}
+	socket := filepath.Join(t.TempDir(), "journal.sock")
+	ln, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	require.NoError(t, err)
+	defer ln.Close()
+
+	j, err := newJournald(socket, AppName)
+	require.NoError(t, err)
+	defer j.Close()
+
+	z := zap.New(newSinkCore(j, zapcore.DebugLevel), zap.AddCaller())
+	logger := zapr.NewLogger(z).WithName("stratum").WithValues(KeyPool, "pool.example:4300")
+
+	read := func() map[string]string {
+		buf := make([]byte, 65536)
+		ln.SetReadDeadline(time.Now().Add(time.Second)) // nolint: errcheck
+		n, err := ln.Read(buf)
+		require.NoError(t, err)
+		return parseJournalFields(t, buf[:n])
+	}
+
+	logger.Info("Share accepted", KeyJobID, "abc", KeyHeight, 42)
+	fields := read()
+	assert.Equal(t, "Share accepted", fields["MESSAGE"])
+	assert.Equal(t, "6", fields["PRIORITY"])
+	assert.Equal(t, AppName, fields["SYSLOG_IDENTIFIER"])
+	assert.Equal(t, "stratum", fields["LOGGER"])
+	assert.Equal(t, "pool.example:4300", fields["POOL"])
+	assert.Equal(t, "abc", fields["JOB_ID"])
+	assert.Equal(t, "42", fields["HEIGHT"])
+	assert.Contains(t, fields["CODE_FILE"], "journald_test.go")
+
+	logger.Error(errors.New("line one\nline two"), "Failed")
+	fields = read()
+	assert.Equal(t, "3", fields["PRIORITY"])
+	assert.Equal(t, "line one\nline two", fields["ERROR"])
+
+	logger.V(1).Info("debug")
+	assert.Equal(t, "7", read()["PRIORITY"])
+}
+
+func TestJournalFieldName(t *testing.T) {
+	assert.Equal(t, "JOB_ID", journalFieldName("job_id"))
+	assert.Equal(t, "SERVER_ADDRESS", journalFieldName("server address"))
+	assert.Equal(t, "HIDDEN", journalFieldName("_hidden"))
+	assert.Equal(t, "F_1ST", journalFieldName("1st"))
+}
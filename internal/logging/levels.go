@@ -0,0 +1,190 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	ErrUnknownLevel = errors.New("unknown log level, use debug, info, warn, error or a verbosity like 2")
+	ErrNoComponent  = errors.New("component name missing")
+)
+
+// DefaultLevel removes the level of a component, so it follows the console level again.
+const DefaultLevel = "default"
+
+// ParseLevel parses debug, info, warn and error as well as logr verbosities, where 0 is info, 1 debug and so on.
+func ParseLevel(s string) (zapcore.Level, error) {
+	switch strings.ToLower(s) {
+	case "trace":
+		return zapcore.Level(-2), nil
+	case "debug":
+		return zapcore.DebugLevel, nil
+	case "info":
+		return zapcore.InfoLevel, nil
+	case "warn", "warning":
+		return zapcore.WarnLevel, nil
+	case "error":
+		return zapcore.ErrorLevel, nil
+	}
+	n, err := strconv.ParseInt(s, 10, 8)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownLevel, s)
+	}
+	return zapcore.Level(-n), nil
+}
+
+// FormatLevel is the counterpart of ParseLevel. Verbosities above debug are written as number.
+func FormatLevel(l zapcore.Level) string {
+	if l < zapcore.DebugLevel {
+		return strconv.Itoa(int(-l))
+	}
+	return l.String()
+}
+
+// Levels are the log levels of the console. Components, which are the names of the loggers like
+// stratum or api, can have their own level. Sub loggers like miner.foo inherit the level of miner.
+// All levels can be changed while the miner is running.
+type Levels struct {
+	console zap.AtomicLevel
+
+	mu         sync.RWMutex
+	components map[string]zapcore.Level
+}
+
+func newLevels(console zapcore.Level) *Levels {
+	return &Levels{
+		console:    zap.NewAtomicLevelAt(console),
+		components: make(map[string]zapcore.Level),
+	}
+}
+
+// Level returns the console level.
+func (l *Levels) Level() string {
+	return FormatLevel(l.console.Level())
+}
+
+// SetLevel changes the console level, which applies to all components without their own level.
+func (l *Levels) SetLevel(level string) error {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	l.console.SetLevel(lvl)
+	return nil
+}
+
+// Components returns the levels of the components which have their own.
+func (l *Levels) Components() map[string]string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	components := make(map[string]string, len(l.components))
+	for name, lvl := range l.components {
+		components[name] = FormatLevel(lvl)
+	}
+	return components
+}
+
+// SetComponent changes the level of a component. The level "default" removes it.
+func (l *Levels) SetComponent(name, level string) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return ErrNoComponent
+	}
+	if level == DefaultLevel {
+		l.mu.Lock()
+		delete(l.components, name)
+		l.mu.Unlock()
+		return nil
+	}
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	l.components[name] = lvl
+	l.mu.Unlock()
+	return nil
+}
+
+// Enabled reports whether the level is enabled for any component.
+func (l *Levels) Enabled(lvl zapcore.Level) bool {
+	if l.console.Enabled(lvl) {
+		return true
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, c := range l.components {
+		if c.Enabled(lvl) {
+			return true
+		}
+	}
+	return false
+}
+
+// enabledFor reports whether the level is enabled for a logger. The most specific component wins.
+func (l *Levels) enabledFor(name string, lvl zapcore.Level) bool {
+	l.mu.RLock()
+	if len(l.components) > 0 {
+		name = strings.ToLower(name)
+		for {
+			if c, ok := l.components[name]; ok {
+				l.mu.RUnlock()
+				return c.Enabled(lvl)
+			}
+			i := strings.LastIndexByte(name, '.')
+			if i < 0 {
+				break
+			}
+			name = name[:i]
+		}
+	}
+	l.mu.RUnlock()
+	return l.console.Enabled(lvl)
+}
+
+// String returns the levels like the --log-levels flag, e.g. "api=warn,stratum=debug".
+func (l *Levels) String() string {
+	components := l.Components()
+	names := make([]string, 0, len(components))
+	for name := range components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		names[i] = name + "=" + components[name]
+	}
+	return strings.Join(names, ",")
+}
+
+// levelsCore filters the entries of the wrapped core by the level of their component.
+// The wrapped core must not filter the levels on its own.
+type levelsCore struct {
+	zapcore.Core
+	levels *Levels
+}
+
+func (c *levelsCore) Enabled(lvl zapcore.Level) bool {
+	return c.levels.Enabled(lvl)
+}
+
+func (c *levelsCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.levels.enabledFor(entry.LoggerName, entry.Level) {
+		return ce
+	}
+	return c.Core.Check(entry, ce)
+}
+
+func (c *levelsCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelsCore{c.Core.With(fields), c.levels}
+}
+
+// allLevels is used for the cores behind a levelsCore.
+var allLevels = zap.LevelEnablerFunc(func(zapcore.Level) bool { return true })
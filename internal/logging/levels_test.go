@@ -0,0 +1,75 @@
+package logging
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalesburg/dero-stratum-miner/internal/config"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestParseLevel(t *testing.T) {
+	for s, want := range map[string]zapcore.Level{
+		"debug": zapcore.DebugLevel,
+		"INFO":  zapcore.InfoLevel,
+		"warn":  zapcore.WarnLevel,
+		"error": zapcore.ErrorLevel,
+		"0":     zapcore.InfoLevel,
+		"1":     zapcore.DebugLevel,
+		"3":     zapcore.Level(-3),
+	} {
+		l, err := ParseLevel(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, want, l, s)
+	}
+	for _, s := range []string{"", "loud", "-1"} {
+		_, err := ParseLevel(s)
+		assert.ErrorIs(t, err, ErrUnknownLevel, s)
+	}
+	assert.Equal(t, "warn", FormatLevel(zapcore.WarnLevel))
+	assert.Equal(t, "3", FormatLevel(zapcore.Level(-3)))
+}
+
+func TestComponentLevels(t *testing.T) {
+	var out bytes.Buffer
+	l, err := New(&out, &config.Logger{
+		Format: FormatLogfmt,
+		Levels: map[string]string{"stratum": "debug", "api": "warn"},
+	})
+	require.NoError(t, err)
+
+	logger := l.Logr()
+	logged := func(fn func()) bool {
+		out.Reset()
+		fn()
+		return out.Len() > 0
+	}
+
+	assert.True(t, logged(func() { logger.WithName("stratum").V(1).Info("got message") }))
+	assert.False(t, logged(func() { logger.WithName("stratum").V(2).Info("too verbose") }))
+	assert.True(t, logged(func() { logger.WithName("stratum").WithName("conn").V(1).Info("sub logger") }))
+	assert.False(t, logged(func() { logger.WithName("miner").V(1).Info("console level") }))
+	assert.True(t, logged(func() { logger.WithName("miner").Info("console level") }))
+	assert.False(t, logged(func() { logger.WithName("api").Info("below warn") }))
+	assert.True(t, logged(func() { logger.WithName("api").Error(nil, "error") }))
+	assert.True(t, logger.WithName("miner").V(1).Enabled(), "any component is enabled")
+
+	levels := l.Levels()
+	assert.Equal(t, map[string]string{"stratum": "debug", "api": "warn"}, levels.Components())
+	assert.Equal(t, "api=warn,stratum=debug", levels.String())
+
+	// change them at runtime
+	require.NoError(t, levels.SetComponent("stratum", DefaultLevel))
+	require.NoError(t, levels.SetComponent("Miner", "debug"))
+	assert.False(t, logged(func() { logger.WithName("stratum").V(1).Info("got message") }))
+	assert.True(t, logged(func() { logger.WithName("miner").V(1).Info("debug") }))
+
+	require.NoError(t, levels.SetLevel("error"))
+	assert.Equal(t, "error", levels.Level())
+	assert.False(t, logged(func() { logger.WithName("stratum").Info("info") }))
+
+	assert.ErrorIs(t, levels.SetComponent("", "debug"), ErrNoComponent)
+	assert.ErrorIs(t, levels.SetComponent("api", "loud"), ErrUnknownLevel)
+}
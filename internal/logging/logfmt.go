@@ -0,0 +1,119 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var logfmtPool = buffer.NewPool()
+
+// logfmtEncoder writes entries as key=value pairs. It lets the JSON encoder build the entry
+// and converts the result, so all the field types of zap are supported.
+// Nested objects and arrays are written as quoted JSON.
+type logfmtEncoder struct {
+	zapcore.Encoder
+}
+
+func newLogfmtEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	return &logfmtEncoder{zapcore.NewJSONEncoder(cfg)}
+}
+
+func (e *logfmtEncoder) Clone() zapcore.Encoder {
+	return &logfmtEncoder{e.Encoder.Clone()}
+}
+
+func (e *logfmtEncoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	buf, err := e.Encoder.EncodeEntry(entry, fields)
+	if err != nil {
+		return nil, err
+	}
+	defer buf.Free()
+
+	out := logfmtPool.Get()
+	if err := jsonToLogfmt(out, buf.Bytes()); err != nil {
+		out.Free()
+		return nil, err
+	}
+	return out, nil
+}
+
+func jsonToLogfmt(out *buffer.Buffer, data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return errors.New("logfmt: expected a JSON object")
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := t.(string)
+		if !ok {
+			return errors.New("logfmt: expected a key")
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return err
+		}
+		if out.Len() > 0 {
+			out.AppendByte(' ')
+		}
+		out.AppendString(logfmtKey(key))
+		out.AppendByte('=')
+		if err := appendLogfmtValue(out, raw); err != nil {
+			return err
+		}
+	}
+	out.AppendByte('\n')
+	return nil
+}
+
+func appendLogfmtValue(out *buffer.Buffer, raw json.RawMessage) error {
+	switch raw[0] {
+	case '"':
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return err
+		}
+		out.AppendString(logfmtQuote(s))
+	case '{', '[':
+		var b bytes.Buffer
+		if err := json.Compact(&b, raw); err != nil {
+			return err
+		}
+		out.AppendString(strconv.Quote(b.String()))
+	default: // numbers, booleans and null
+		out.Write(raw) // nolint: errcheck
+	}
+	return nil
+}
+
+// logfmtKey replaces the characters which aren't allowed in keys.
+func logfmtKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+func logfmtQuote(s string) string {
+	if s == "" {
+		return `""`
+	}
+	for _, r := range s {
+		if r == ' ' || r == '=' || r == '"' || r == '\\' || !unicode.IsPrint(r) {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
@@ -1,43 +1,214 @@
 package logging
 
 import (
+	"errors"
+	"fmt"
 	"io"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/go-logr/zapr"
+	"github.com/whalesburg/dero-stratum-miner/internal/color"
 	"github.com/whalesburg/dero-stratum-miner/internal/config"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-func New(console io.Writer, cfg *config.Logger) logr.Logger {
-	var logLevelConsole zap.AtomicLevel
+var (
+	ErrUnknownFormat = errors.New("unknown log format")
+	ErrNoJournald    = errors.New("journald is not available")
+)
+
+const (
+	// AppName identifies the miner in journald and syslog.
+	AppName = "dero-stratum-miner"
+	// DefaultFacility is the syslog facility if none is configured.
+	DefaultFacility = "daemon"
+)
+
+// Log formats
+const (
+	FormatConsole = "console"
+	FormatJSON    = "json"
+	FormatLogfmt  = "logfmt"
+)
+
+// Field names of the structured logs. They match the JSON of the event stream.
+const (
+	KeyPool       = "pool"
+	KeyJobID      = "job_id"
+	KeyHeight     = "height"
+	KeyDifficulty = "difficulty"
+	KeyReason     = "reason"
+	KeyLatency    = "latency_ms"
+)
+
+// Logger owns the zap cores and the log file and sinks they write to.
+type Logger struct {
+	logr    logr.Logger
+	levels  *Levels
+	closers []io.Closer
+}
+
+// New creates the logger. The console always gets a log, the file only if cfg.File is set.
+// The file has its own log level, so it can be more verbose than the console. Both use cfg.Format.
+// Journald and syslog get the fields of every entry one by one and follow the console levels.
+// Repeated warnings and errors are collapsed if cfg.DedupWindow is set.
+func New(console io.Writer, cfg *config.Logger) (*Logger, error) {
 	if cfg.Debug { // setup debug mode if requested
 		cfg.CLogLevel = 1
 	}
-
-	if cfg.CLogLevel < 0 {
-		cfg.CLogLevel = 0
+	levels := newLevels(zapcore.Level(-clampLevel(cfg.CLogLevel)))
+	for name, level := range cfg.Levels {
+		if err := levels.SetComponent(name, level); err != nil {
+			return nil, err
+		}
 	}
-	if cfg.CLogLevel > 127 {
-		cfg.CLogLevel = 127
+
+	consoleCore, err := newCore(cfg.Format, zapcore.AddSync(console), allLevels, true)
+	if err != nil {
+		return nil, err
 	}
-	logLevelConsole = zap.NewAtomicLevelAt(zapcore.Level(0 - cfg.CLogLevel))
+	consoleCores := []zapcore.Core{consoleCore}
+	var cores []zapcore.Core
 
-	zc := zap.NewDevelopmentEncoderConfig()
-	zc.EncodeLevel = zapcore.CapitalColorLevelEncoder
-	zc.EncodeTime = zapcore.TimeEncoderOfLayout("02/01 15:04:05")
+	l := &Logger{
+		levels: levels,
+	}
+	if cfg.File != "" {
+		f, err := openRotatingFile(cfg.File, int64(cfg.FileMaxSize)*1024*1024, time.Duration(cfg.FileMaxAge), cfg.FileMaxBackups, cfg.FileCompress)
+		if err != nil {
+			return nil, err
+		}
+		l.closers = append(l.closers, f)
 
-	consoleEncoder := zapcore.NewConsoleEncoder(zc)
+		logLevelFile := zap.NewAtomicLevelAt(zapcore.Level(-clampLevel(cfg.FileLevel)))
+		fileCore, err := newCore(cfg.Format, f, logLevelFile, false)
+		if err != nil {
+			l.Close() // nolint: errcheck
+			return nil, err
+		}
+		cores = append(cores, fileCore)
+	}
+	if cfg.Journald {
+		if !journaldAvailable() {
+			l.Close() // nolint: errcheck
+			return nil, ErrNoJournald
+		}
+		j, err := newJournald(JournaldSocket, AppName)
+		if err != nil {
+			l.Close() // nolint: errcheck
+			return nil, fmt.Errorf("journald: %w", err)
+		}
+		l.closers = append(l.closers, j)
+		consoleCores = append(consoleCores, newSinkCore(j, allLevels))
+	}
+	if cfg.Syslog != "" {
+		facility := cfg.SyslogFacility
+		if facility == "" {
+			facility = DefaultFacility
+		}
+		s, err := newSyslog(cfg.Syslog, facility, AppName)
+		if err != nil {
+			l.Close() // nolint: errcheck
+			return nil, fmt.Errorf("syslog: %w", err)
+		}
+		l.closers = append(l.closers, s)
+		consoleCores = append(consoleCores, newSinkCore(s, allLevels))
+	}
+	cores = append(cores, &levelsCore{zapcore.NewTee(consoleCores...), levels})
 
-	coreConsole := zapcore.NewCore(consoleEncoder, zapcore.AddSync(console), logLevelConsole)
-	removecore := &removeCallerCore{coreConsole}
-	core := zapcore.NewTee(
-		removecore,
-	)
+	core := zapcore.NewTee(cores...)
+	if cfg.DedupWindow > 0 {
+		d := newDeduper(core, time.Duration(cfg.DedupWindow), cfg.DedupBurst)
+		d.start()
+		// flush the suppressed messages before the file and sinks are closed
+		l.closers = append([]io.Closer{d}, l.closers...)
+		core = &dedupCore{core, d}
+	}
 
 	zcore := zap.New(core, zap.AddCaller()) // add caller info to every record which is then trimmed from console
-	return zapr.NewLogger(zcore)
+	l.logr = zapr.NewLogger(zcore)
+	return l, nil
+}
+
+// newCore creates a core which writes in the given format. On the console the console format is
+// short and colorized, in files and in the structured formats all the details are kept.
+func newCore(format string, w zapcore.WriteSyncer, level zapcore.LevelEnabler, console bool) (zapcore.Core, error) {
+	switch format {
+	case FormatConsole, "":
+		zc := zap.NewDevelopmentEncoderConfig()
+		zc.EncodeLevel = zapcore.CapitalLevelEncoder
+		if !console {
+			zc.EncodeTime = zapcore.ISO8601TimeEncoder
+			return zapcore.NewCore(zapcore.NewConsoleEncoder(zc), w, level), nil
+		}
+		if color.Enabled() {
+			zc.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		}
+		zc.EncodeTime = zapcore.TimeEncoderOfLayout("02/01 15:04:05")
+		return &removeCallerCore{zapcore.NewCore(zapcore.NewConsoleEncoder(zc), w, level)}, nil
+	case FormatJSON:
+		return zapcore.NewCore(zapcore.NewJSONEncoder(structuredEncoderConfig()), w, level), nil
+	case FormatLogfmt:
+		return zapcore.NewCore(newLogfmtEncoder(structuredEncoderConfig()), w, level), nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+}
+
+// structuredEncoderConfig uses the field names most log pipelines expect.
+func structuredEncoderConfig() zapcore.EncoderConfig {
+	return zapcore.EncoderConfig{
+		TimeKey:        "time",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		FunctionKey:    zapcore.OmitKey,
+		MessageKey:     "msg",
+		StacktraceKey:  "stacktrace",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    levelEncoder,
+		EncodeTime:     zapcore.RFC3339TimeEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+}
+
+// levelEncoder writes the logr verbosity levels below debug as trace instead of zap's "level(-2)".
+func levelEncoder(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	if l < zapcore.DebugLevel {
+		enc.AppendString("trace")
+		return
+	}
+	zapcore.LowercaseLevelEncoder(l, enc)
+}
+
+func clampLevel(level int8) int8 {
+	if level < 0 {
+		return 0
+	}
+	return level
+}
+
+// Logr returns the logger used by the rest of the miner.
+func (l *Logger) Logr() logr.Logger {
+	return l.logr
+}
+
+// Levels returns the log levels of the console.
+func (l *Logger) Levels() *Levels {
+	return l.levels
+}
+
+// Close flushes and closes the log file and the connections of the sinks.
+func (l *Logger) Close() error {
+	var err error
+	for _, c := range l.closers {
+		if e := c.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
 }
 
 // remove caller information from console
@@ -0,0 +1,93 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalesburg/dero-stratum-miner/internal/color"
+	"github.com/whalesburg/dero-stratum-miner/internal/config"
+	"go.uber.org/zap/buffer"
+)
+
+func TestJSONFormat(t *testing.T) {
+	var out bytes.Buffer
+	l, err := New(&out, &config.Logger{Format: FormatJSON, CLogLevel: 1})
+	require.NoError(t, err)
+
+	logger := l.Logr().WithName("miner")
+	logger.Info("Share accepted", KeyJobID, "abc", KeyHeight, 42.0, KeyLatency, 12)
+	logger.V(1).Info("New job")
+	logger.Error(errors.New("boom"), "Failed to submit share")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "info", entry["level"])
+	assert.Equal(t, "miner", entry["logger"])
+	assert.Equal(t, "Share accepted", entry["msg"])
+	assert.Equal(t, "abc", entry[KeyJobID])
+	assert.Equal(t, 42.0, entry[KeyHeight])
+	assert.Contains(t, entry["caller"], "logging/logging_test.go:")
+	_, err = time.Parse(time.RFC3339, entry["time"].(string))
+	assert.NoError(t, err)
+
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &entry))
+	assert.Equal(t, "debug", entry["level"])
+
+	require.NoError(t, json.Unmarshal([]byte(lines[2]), &entry))
+	assert.Equal(t, "error", entry["level"])
+	assert.Equal(t, "boom", entry["error"])
+}
+
+func TestConsoleFormatRemovesCaller(t *testing.T) {
+	color.Enable(false)
+	defer color.Enable(true)
+
+	var out bytes.Buffer
+	l, err := New(&out, &config.Logger{})
+	require.NoError(t, err)
+
+	l.Logr().Info("hello")
+	assert.Contains(t, out.String(), "INFO\thello")
+	assert.NotContains(t, out.String(), "logging_test.go")
+}
+
+func TestUnknownFormat(t *testing.T) {
+	_, err := New(&bytes.Buffer{}, &config.Logger{Format: "xml"})
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestJSONToLogfmt(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want string
+	}{
+		{`{"level":"info","msg":"Share accepted","job_id":"abc","height":42}`, `level=info msg="Share accepted" job_id=abc height=42`},
+		{`{"msg":"","ok":true,"err":null}`, `msg="" ok=true err=null`},
+		{`{"msg":"a=b","quote":"say \"hi\"","nl":"a\nb"}`, `msg="a=b" quote="say \"hi\"" nl="a\nb"`},
+		{`{"obj":{"a": 1},"list":[1, 2]}`, `obj="{\"a\":1}" list="[1,2]"`},
+		{`{"key with space":1}`, `key_with_space=1`},
+	} {
+		out := buffer.NewPool().Get()
+		require.NoError(t, jsonToLogfmt(out, []byte(tc.in)))
+		assert.Equal(t, tc.want+"\n", out.String())
+	}
+}
+
+func TestLogfmtFormat(t *testing.T) {
+	var out bytes.Buffer
+	l, err := New(&out, &config.Logger{Format: FormatLogfmt})
+	require.NoError(t, err)
+
+	l.Logr().WithName("stratum").WithValues(KeyPool, "pool.example:4300").Info("connected")
+	line := out.String()
+	assert.Regexp(t, `^level=info time=\S+ logger=stratum caller=\S+ msg=connected pool=pool.example:4300\n$`, line)
+}
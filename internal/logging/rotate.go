@@ -0,0 +1,249 @@
+package logging
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// rotatingFile is a log file which is rotated if it gets too large or too old.
+// Rotated files are renamed to name-<time>.ext and optionally compressed with gzip,
+// only the newest maxBackups of them are kept.
+type rotatingFile struct {
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+	compress   bool
+	now        func() time.Time
+
+	mu     sync.Mutex
+	f      *os.File
+	size   int64
+	opened time.Time
+
+	millMu sync.Mutex
+	mill   chan struct{}
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func openRotatingFile(path string, maxSize int64, maxAge time.Duration, maxBackups int, compress bool) (*rotatingFile, error) {
+	r := &rotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		maxAge:     maxAge,
+		maxBackups: maxBackups,
+		compress:   compress,
+		now:        time.Now,
+		mill:       make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	r.wg.Add(1)
+	go r.millLoop()
+	// clean up the backups of previous runs
+	r.mill <- struct{}{}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close() // nolint: errcheck
+		return err
+	}
+	r.f = f
+	r.size = info.Size()
+	r.opened = r.now()
+	if r.size > 0 {
+		// the age of an existing log counts from its last write
+		r.opened = info.ModTime()
+	}
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.f == nil {
+		return 0, os.ErrClosed
+	}
+	var rotateErr error
+	if r.needsRotation(len(p)) {
+		rotateErr = r.rotate()
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	if err == nil {
+		err = rotateErr
+	}
+	return n, err
+}
+
+func (r *rotatingFile) needsRotation(n int) bool {
+	if r.size == 0 {
+		return false
+	}
+	if r.maxSize > 0 && r.size+int64(n) > r.maxSize {
+		return true
+	}
+	return r.maxAge > 0 && r.now().Sub(r.opened) >= r.maxAge
+}
+
+// rotate renames the file to a backup and opens a new one. If the file can't be renamed,
+// it's opened again, so the log goes on in the current file. As long as the file can't be
+// opened, the writes fail and rotating is tried again with the next write.
+func (r *rotatingFile) rotate() error {
+	r.f.Close() // nolint: errcheck
+	renameErr := os.Rename(r.path, r.backupName(r.now()))
+	if err := r.open(); err != nil {
+		return err
+	}
+	if renameErr != nil {
+		return renameErr
+	}
+	select {
+	case r.mill <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (r *rotatingFile) backupName(t time.Time) string {
+	ext := filepath.Ext(r.path)
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(r.path, ext), t.Format(backupTimeFormat), ext)
+}
+
+// millLoop compresses and removes old backups in the background, so writing the log doesn't block.
+func (r *rotatingFile) millLoop() {
+	defer r.wg.Done()
+	for {
+		select {
+		case <-r.mill:
+			r.millRun() // nolint: errcheck
+		case <-r.done:
+			return
+		}
+	}
+}
+
+type backup struct {
+	path string
+	t    time.Time
+}
+
+// backups returns the rotated files, newest first.
+func (r *rotatingFile) backups() ([]backup, error) {
+	dir := filepath.Dir(r.path)
+	ext := filepath.Ext(r.path)
+	prefix := strings.TrimSuffix(filepath.Base(r.path), ext) + "-"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var backups []backup
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		ts := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".gz"), ext)
+		t, err := time.Parse(backupTimeFormat, ts)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{path: filepath.Join(dir, name), t: t})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].t.After(backups[j].t) })
+	return backups, nil
+}
+
+func (r *rotatingFile) millRun() error {
+	r.millMu.Lock()
+	defer r.millMu.Unlock()
+
+	backups, err := r.backups()
+	if err != nil {
+		return err
+	}
+	for i, b := range backups {
+		if r.maxBackups > 0 && i >= r.maxBackups {
+			os.Remove(b.path) // nolint: errcheck
+			continue
+		}
+		if r.compress && !strings.HasSuffix(b.path, ".gz") {
+			if err := compressFile(b.path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close() // nolint: errcheck
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close() // nolint: errcheck
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+func (r *rotatingFile) Sync() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return nil
+	}
+	return r.f.Sync()
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return nil
+	}
+	close(r.done)
+	r.wg.Wait()
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
@@ -0,0 +1,114 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalesburg/dero-stratum-miner/internal/testutil"
+)
+
+func openTestFile(t *testing.T, clk *testutil.Clock, maxSize int64, maxAge time.Duration, maxBackups int, compress bool) *rotatingFile {
+	t.Helper()
+	r, err := openRotatingFile(filepath.Join(t.TempDir(), "miner.log"), maxSize, maxAge, maxBackups, compress)
+	require.NoError(t, err)
+	t.Cleanup(func() { r.Close() }) // nolint: errcheck
+	r.mu.Lock()
+	r.now, r.opened = clk.Now, clk.Now()
+	r.mu.Unlock()
+	return r
+}
+
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func write(t *testing.T, r *rotatingFile, s string) {
+	t.Helper()
+	_, err := r.Write([]byte(s))
+	require.NoError(t, err)
+}
+
+func TestRotate(t *testing.T) {
+	clk := testutil.NewClock()
+	r := openTestFile(t, clk, 10, time.Hour, 0, false)
+	dir := filepath.Dir(r.path)
+
+	write(t, r, "12345678\n")
+	clk.Add(time.Second)
+	write(t, r, "abc\n")
+	assert.Equal(t, []string{"miner-2022-01-01T12-00-01.000.log", "miner.log"}, listDir(t, dir))
+	b, err := os.ReadFile(r.path)
+	require.NoError(t, err)
+	assert.Equal(t, "abc\n", string(b))
+
+	clk.Add(time.Hour)
+	write(t, r, "def\n")
+	assert.Equal(t, []string{"miner-2022-01-01T12-00-01.000.log", "miner-2022-01-01T13-00-01.000.log", "miner.log"}, listDir(t, dir))
+}
+
+func TestMill(t *testing.T) {
+	clk := testutil.NewClock()
+	r := openTestFile(t, clk, 1, 0, 2, true)
+	for i := 0; i < 4; i++ {
+		write(t, r, "line\n")
+		clk.Add(time.Second)
+	}
+	require.NoError(t, r.millRun())
+	assert.Equal(t, []string{
+		"miner-2022-01-01T12-00-02.000.log.gz",
+		"miner-2022-01-01T12-00-03.000.log.gz",
+		"miner.log",
+	}, listDir(t, filepath.Dir(r.path)))
+}
+
+func TestRotateFailure(t *testing.T) {
+	clk := testutil.NewClock()
+	r := openTestFile(t, clk, 10, 0, 0, false)
+	write(t, r, "12345678\n")
+
+	// the backup name is taken, so the file can't be renamed
+	blocked := r.backupName(clk.Now())
+	require.NoError(t, os.Mkdir(blocked, 0o755))
+	// the lines are still written, the error is reported until the rotation succeeds
+	for _, line := range []string{"abc\n", "def\n"} {
+		n, err := r.Write([]byte(line))
+		assert.Error(t, err)
+		assert.Equal(t, len(line), n)
+	}
+	b, err := os.ReadFile(r.path)
+	require.NoError(t, err)
+	assert.Equal(t, "12345678\nabc\ndef\n", string(b))
+
+	// rotating works again once the name is free
+	require.NoError(t, os.Remove(blocked))
+	write(t, r, "ghi\n")
+	b, err = os.ReadFile(r.path)
+	require.NoError(t, err)
+	assert.Equal(t, "ghi\n", string(b))
+}
+
+func TestRotateExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "miner.log")
+	require.NoError(t, os.WriteFile(path, []byte("old\n"), 0o644))
+	written := time.Now().Add(-time.Hour * 2)
+	require.NoError(t, os.Chtimes(path, written, written))
+
+	r, err := openRotatingFile(path, 0, time.Hour, 0, false)
+	require.NoError(t, err)
+	defer r.Close()
+	assert.True(t, r.opened.Equal(written))
+	assert.True(t, r.needsRotation(1))
+}
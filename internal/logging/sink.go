@@ -0,0 +1,91 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// sink receives the log entries of a sinkCore with all the fields of the entry.
+type sink interface {
+	write(entry zapcore.Entry, fields []field) error
+	Close() error
+}
+
+type field struct {
+	key   string
+	value string
+}
+
+// sinkCore is a zap core for sinks which need the fields one by one instead of an encoded line,
+// like journald or syslog structured data.
+type sinkCore struct {
+	zapcore.LevelEnabler
+	fields []zapcore.Field
+	sink   sink
+}
+
+func newSinkCore(s sink, level zapcore.LevelEnabler) zapcore.Core {
+	return &sinkCore{LevelEnabler: level, sink: s}
+}
+
+func (c *sinkCore) With(fields []zapcore.Field) zapcore.Core {
+	return &sinkCore{
+		LevelEnabler: c.LevelEnabler,
+		fields:       append(c.fields[:len(c.fields):len(c.fields)], fields...),
+		sink:         c.sink,
+	}
+}
+
+func (c *sinkCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return ce.AddCore(entry, c)
+	}
+	return ce
+}
+
+func (c *sinkCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	all := fields
+	if len(c.fields) > 0 {
+		all = append(c.fields[:len(c.fields):len(c.fields)], fields...)
+	}
+	return c.sink.write(entry, flattenFields(all))
+}
+
+func (c *sinkCore) Sync() error {
+	return nil
+}
+
+// flattenFields turns the zap fields into strings sorted by key.
+// Strings are kept as they are, everything else is written as JSON.
+func flattenFields(fields []zapcore.Field) []field {
+	if len(fields) == 0 {
+		return nil
+	}
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	flat := make([]field, 0, len(enc.Fields))
+	for k, v := range enc.Fields {
+		flat = append(flat, field{key: k, value: fieldValue(v)})
+	}
+	sort.Slice(flat, func(i, j int) bool { return flat[i].key < flat[j].key })
+	return flat
+}
+
+func fieldValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
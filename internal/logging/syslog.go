@@ -0,0 +1,307 @@
+package logging
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	ErrUnknownFacility   = errors.New("unknown syslog facility")
+	ErrUnknownTransport  = errors.New("unknown syslog transport, use udp, tcp or tls")
+	ErrSyslogUnavailable = errors.New("syslog server unavailable")
+)
+
+const (
+	syslogTimeout = time.Second * 5
+	syslogBackoff = time.Second * 5
+	// syslogQueue is the number of entries which wait to be sent, further entries are dropped.
+	syslogQueue = 1024
+	// structured data id of the fields, 32473 is the enterprise number reserved for examples and private use.
+	syslogSDID = "fields@32473"
+)
+
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslog sends RFC5424 messages to a remote server. UDP sends one message per datagram,
+// TCP and TLS use octet counting framing (RFC6587). The messages are sent in the background,
+// so a slow or unreachable server never blocks the logger. Stream connections are redialed if they break,
+// while the server is unavailable or the queue is full the entries are dropped.
+type syslog struct {
+	network  string
+	addr     string
+	tls      *tls.Config
+	facility int
+	appName  string
+	hostname string
+	pid      string
+
+	queue     chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+
+	mu  sync.Mutex
+	err error // reported by the next write
+
+	// only used by the sender
+	conn      net.Conn
+	failed    bool
+	nextRetry time.Time
+}
+
+// newSyslog sends to a server given as udp://host:port, tcp://host:port or tls://host:port.
+// Without scheme UDP is used, without port the default port of the transport. The sender dials
+// the server, so an unreachable server doesn't keep the miner from starting.
+func newSyslog(server, facility, appName string) (*syslog, error) {
+	f, ok := facilities[facility]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFacility, facility)
+	}
+	if !strings.Contains(server, "://") {
+		server = "udp://" + server
+	}
+	u, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &syslog{
+		addr:     u.Host,
+		facility: f,
+		appName:  appName,
+		hostname: "-",
+		pid:      strconv.Itoa(os.Getpid()),
+		queue:    make(chan []byte, syslogQueue),
+		done:     make(chan struct{}),
+	}
+	if h, err := os.Hostname(); err == nil && h != "" {
+		s.hostname = h
+	}
+
+	port := ""
+	switch u.Scheme {
+	case "udp":
+		s.network, port = "udp", "514"
+	case "tcp":
+		s.network, port = "tcp", "514"
+	case "tls":
+		s.network, port = "tcp", "6514"
+		s.tls = &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTransport, u.Scheme)
+	}
+	if u.Port() == "" {
+		s.addr = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	s.wg.Add(1)
+	go s.run()
+	return s, nil
+}
+
+func (s *syslog) connect() error {
+	var (
+		conn net.Conn
+		err  error
+	)
+	dialer := &net.Dialer{Timeout: syslogTimeout}
+	if s.tls != nil {
+		conn, err = tls.DialWithDialer(dialer, s.network, s.addr, s.tls)
+	} else {
+		conn, err = dialer.Dial(s.network, s.addr)
+	}
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	return nil
+}
+
+// write queues the entry. Errors of the sender are returned by the next write.
+func (s *syslog) write(entry zapcore.Entry, fields []field) error {
+	select {
+	case s.queue <- s.format(entry, fields):
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.err
+	s.err = nil
+	return err
+}
+
+// run sends the queued messages until the syslog is closed.
+func (s *syslog) run() {
+	defer s.wg.Done()
+	for {
+		select {
+		case msg := <-s.queue:
+			s.deliver(msg)
+		case <-s.done:
+			// flush the queue over the current connection without waiting for a new one
+			for {
+				select {
+				case msg := <-s.queue:
+					if s.conn != nil && s.send(msg) != nil {
+						s.conn.Close() // nolint: errcheck
+						s.conn = nil
+					}
+				default:
+					if s.conn != nil {
+						s.conn.Close() // nolint: errcheck
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *syslog) deliver(msg []byte) {
+	if s.conn == nil {
+		if time.Now().Before(s.nextRetry) {
+			return
+		}
+		if err := s.connect(); err != nil {
+			s.fail(err)
+			return
+		}
+	}
+	if err := s.send(msg); err != nil {
+		s.conn.Close() // nolint: errcheck
+		s.conn = nil
+		// the server might have closed an idle connection, try again once
+		if err := s.connect(); err != nil {
+			s.fail(err)
+			return
+		}
+		if err := s.send(msg); err != nil {
+			s.fail(err)
+			return
+		}
+	}
+	s.failed = false
+}
+
+// fail drops the connection for a while. Only the first error is reported, so an unavailable
+// server doesn't cause an error for every entry.
+func (s *syslog) fail(err error) {
+	if s.conn != nil {
+		s.conn.Close() // nolint: errcheck
+		s.conn = nil
+	}
+	s.nextRetry = time.Now().Add(syslogBackoff)
+	if s.failed {
+		return
+	}
+	s.failed = true
+	s.mu.Lock()
+	s.err = fmt.Errorf("%w: %v", ErrSyslogUnavailable, err)
+	s.mu.Unlock()
+}
+
+func (s *syslog) send(msg []byte) error {
+	s.conn.SetWriteDeadline(time.Now().Add(syslogTimeout)) // nolint: errcheck
+	if s.network == "udp" {
+		_, err := s.conn.Write(msg)
+		return err
+	}
+	_, err := s.conn.Write(append([]byte(strconv.Itoa(len(msg))+" "), msg...))
+	return err
+}
+
+// format writes an RFC5424 message:
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [STRUCTURED-DATA] MSG
+// The logger name is used as MSGID and the fields as structured data.
+func (s *syslog) format(entry zapcore.Entry, fields []field) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %s %s ", // nolint: errcheck
+		s.facility*8+severity(entry.Level),
+		entry.Time.Format(time.RFC3339Nano),
+		syslogHeader(s.hostname, 255),
+		syslogHeader(s.appName, 48),
+		s.pid,
+		syslogHeader(entry.LoggerName, 32),
+	)
+
+	if entry.Caller.Defined {
+		fields = append(fields, field{key: "caller", value: entry.Caller.TrimmedPath()})
+	}
+	if len(fields) == 0 {
+		b.WriteByte('-')
+	} else {
+		b.WriteString("[" + syslogSDID)
+		for _, f := range fields {
+			b.WriteString(" " + syslogParamName(f.key) + `="` + syslogParamValue(f.value) + `"`)
+		}
+		b.WriteByte(']')
+	}
+
+	b.WriteByte(' ')
+	b.WriteString(entry.Message)
+	if entry.Stack != "" {
+		b.WriteString("\n" + entry.Stack)
+	}
+	return b.Bytes()
+}
+
+// Close sends the queued messages and closes the connection.
+func (s *syslog) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+	})
+	return nil
+}
+
+// syslogHeader returns a valid header field, which is printable ASCII without spaces or "-" if empty.
+func syslogHeader(s string, maxLen int) string {
+	s = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return '_'
+		}
+		return r
+	}, s)
+	if s == "" {
+		return "-"
+	}
+	if len(s) > maxLen {
+		s = s[:maxLen]
+	}
+	return s
+}
+
+// syslogParamName returns a valid SD-NAME, which mustn't contain '=', ' ', ']' or '"'.
+func syslogParamName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, s)
+	if len(s) > 32 {
+		s = s[:32]
+	}
+	return s
+}
+
+// syslogParamValue escapes '"', '\' and ']'.
+func syslogParamValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(s)
+}
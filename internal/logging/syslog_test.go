@@ -0,0 +1,169 @@
+package logging
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/zapr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var rfc5424 = regexp.MustCompile(`^<(\d+)>1 (\S+) (\S+) (\S+) (\d+) (\S+) (-|\[.*\]) (.*)$`)
+
+func newTestSyslogLogger(t *testing.T, server string) *syslog {
+	t.Helper()
+	s, err := newSyslog(server, "local0", AppName)
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() }) // nolint: errcheck
+	return s
+}
+
+func TestSyslogUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	s := newTestSyslogLogger(t, "udp://"+conn.LocalAddr().String())
+	logger := zapr.NewLogger(zap.New(newSinkCore(s, zapcore.InfoLevel), zap.AddCaller())).WithName("miner")
+
+	logger.Info("Share accepted", KeyJobID, "abc", "note", `say "hi" [ok]`)
+	logger.V(1).Info("not enabled")
+	logger.Error(nil, "Failed")
+
+	read := func() []string {
+		buf := make([]byte, 65536)
+		conn.SetReadDeadline(time.Now().Add(time.Second)) // nolint: errcheck
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+		m := rfc5424.FindStringSubmatch(string(buf[:n]))
+		require.NotNil(t, m, string(buf[:n]))
+		return m
+	}
+
+	m := read()
+	assert.Equal(t, strconv.Itoa(16*8+6), m[1])
+	_, err = time.Parse(time.RFC3339Nano, m[2])
+	assert.NoError(t, err)
+	assert.Equal(t, AppName, m[4])
+	assert.Equal(t, "miner", m[6])
+	assert.Regexp(t, `^\[fields@32473 job_id="abc" note="say \\"hi\\" \[ok\\]" caller="logging/syslog_test.go:\d+"\]$`, m[7])
+	assert.Equal(t, "Share accepted", m[8])
+
+	m = read()
+	assert.Equal(t, strconv.Itoa(16*8+3), m[1])
+	assert.Equal(t, "Failed", m[8])
+}
+
+// readFrame reads a message with octet counting framing.
+func readFrame(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+	length, err := r.ReadString(' ')
+	require.NoError(t, err)
+	n, err := strconv.Atoi(strings.TrimSpace(length))
+	require.NoError(t, err)
+	msg := make([]byte, n)
+	_, err = io.ReadFull(r, msg)
+	require.NoError(t, err)
+	return string(msg)
+}
+
+func TestSyslogTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	s := newTestSyslogLogger(t, "tcp://"+ln.Addr().String())
+	now := time.Date(2022, 11, 19, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, s.write(zapcore.Entry{Level: zapcore.WarnLevel, Time: now, Message: "first\nsecond line"}, nil))
+	require.NoError(t, s.write(zapcore.Entry{Level: zapcore.InfoLevel, Time: now, LoggerName: "api", Message: "ok"}, []field{{key: "pool", value: "x"}}))
+
+	// the connection is dialed by the sender
+	conn, err := ln.Accept()
+	require.NoError(t, err)
+	r := bufio.NewReader(conn)
+
+	host := s.hostname
+	pid := s.pid
+	assert.Equal(t, "<132>1 2022-11-19T12:00:00Z "+host+" "+AppName+" "+pid+" - - first\nsecond line", readFrame(t, r))
+	assert.Equal(t, "<134>1 2022-11-19T12:00:00Z "+host+" "+AppName+" "+pid+` api [fields@32473 pool="x"] ok`, readFrame(t, r))
+
+	// the server closes the connection, the next entry is sent over a new one
+	conn.Close()
+	time.Sleep(time.Millisecond * 50)
+	go func() {
+		for i := 0; i < 2; i++ {
+			s.write(zapcore.Entry{Level: zapcore.InfoLevel, Time: now, Message: "again"}, nil) // nolint: errcheck
+			time.Sleep(time.Millisecond * 50)
+		}
+	}()
+	conn, err = ln.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Contains(t, readFrame(t, bufio.NewReader(conn)), " again")
+}
+
+func TestSyslogUnavailable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ln.Close()
+	// an unreachable server doesn't fail the logger
+	s := newTestSyslogLogger(t, "tcp://"+ln.Addr().String())
+
+	// the error of the sender is returned by a later write, only the first one is reported
+	entry := zapcore.Entry{Level: zapcore.InfoLevel, Time: time.Now(), Message: "lost"}
+	var errs []error
+	assert.Eventually(t, func() bool {
+		if err := s.write(entry, nil); err != nil {
+			errs = append(errs, err)
+		}
+		return len(errs) > 0
+	}, time.Second*5, time.Millisecond*10)
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, s.write(entry, nil))
+		time.Sleep(time.Millisecond)
+	}
+	require.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], ErrSyslogUnavailable)
+}
+
+func TestSyslogStalledServer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	s := newTestSyslogLogger(t, "tcp://"+ln.Addr().String())
+
+	// the connection is never read, so the sender blocks once the socket buffers are full
+	entry := zapcore.Entry{Level: zapcore.InfoLevel, Time: time.Now(), Message: strings.Repeat("x", 1000)}
+	s.write(entry, nil) // nolint: errcheck
+	conn, err := ln.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+	start := time.Now()
+	for i := 0; i < 20_000; i++ {
+		s.write(entry, nil) // nolint: errcheck
+	}
+	assert.Less(t, time.Since(start), syslogTimeout)
+}
+
+func TestSyslogConfig(t *testing.T) {
+	_, err := newSyslog("udp://127.0.0.1:514", "nope", AppName)
+	assert.ErrorIs(t, err, ErrUnknownFacility)
+	_, err = newSyslog("http://127.0.0.1:514", "daemon", AppName)
+	assert.ErrorIs(t, err, ErrUnknownTransport)
+
+	s, err := newSyslog("127.0.0.1", "daemon", AppName)
+	require.NoError(t, err)
+	defer s.Close()
+	assert.Equal(t, "udp", s.network)
+	assert.Equal(t, "127.0.0.1:514", s.addr)
+}
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"sync"
+)
+
+// latencyBuckets are the upper bounds of the share latency histogram in seconds.
+var latencyBuckets = []float64{0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)),
+	}
+}
+
+func (h *histogram) observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, b := range h.buckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+func (h *histogram) write(w *writer, name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, b := range h.buckets {
+		w.sample(name+"_bucket", float64(h.counts[i]), "le", formatFloat(b))
+	}
+	w.sample(name+"_bucket", float64(h.count), "le", "+Inf")
+	w.sample(name+"_sum", h.sum)
+	w.sample(name+"_count", float64(h.count))
+}
@@ -0,0 +1,189 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	miner "github.com/whalesburg/dero-stratum-miner/internal/dero-stratum-miner"
+	"github.com/whalesburg/dero-stratum-miner/internal/version"
+)
+
+const namespace = "dero_miner_"
+
+var hashrateWindows = []struct {
+	name   string
+	window time.Duration
+}{
+	{"10s", time.Second * 10},
+	{"60s", time.Minute},
+	{"15m", time.Minute * 15},
+}
+
+// rejectReasons are the values of the reason label. The pools answer with free text,
+// which would give a new time series for every variation of the message.
+var rejectReasons = []string{"stale", "duplicate", "low_difficulty", "invalid", "other"}
+
+// rejectReason maps the reason the pool gave to one of rejectReasons.
+func rejectReason(reason string) string {
+	reason = strings.ToLower(reason)
+	switch {
+	case strings.Contains(reason, "stale"), strings.Contains(reason, "expired"), strings.Contains(reason, "outdated"):
+		return "stale"
+	case strings.Contains(reason, "duplicate"):
+		return "duplicate"
+	case strings.Contains(reason, "low difficulty"), strings.Contains(reason, "low diff"):
+		return "low_difficulty"
+	case strings.Contains(reason, "invalid"), strings.Contains(reason, "bad"):
+		return "invalid"
+	}
+	return "other"
+}
+
+// Collector exposes the stats of the miner in the prometheus text format.
+type Collector struct {
+	m       *miner.Client
+	latency *histogram
+}
+
+// NewCollector creates a new collector and starts observing the share latency until the context is cancelled.
+func NewCollector(ctx context.Context, m *miner.Client) *Collector {
+	c := &Collector{
+		m:       m,
+		latency: newHistogram(latencyBuckets),
+	}
+	go c.observeShares(ctx)
+	return c
+}
+
+func (c *Collector) observeShares(ctx context.Context) {
+	l := c.m.NewShareListener(16)
+	defer l.Close()
+	for {
+		select {
+		case r, ok := <-l.Ch():
+			if !ok {
+				return
+			}
+			c.latency.observe(r.Latency.Seconds())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	mw := &writer{
+		w:      w,
+		labels: []string{"wallet", c.m.GetWallet(), "worker", c.m.GetWorker(), "pool", c.m.GetPoolURL()},
+	}
+	c.write(mw)
+}
+
+func (c *Collector) write(w *writer) {
+	w.header(namespace+"build_info", "gauge", "Version of the miner.")
+	w.sample(namespace+"build_info", 1, "version", version.Version, "commit", version.Commit)
+
+	w.header(namespace+"hashes_total", "counter", "Total number of computed hashes.")
+	w.sample(namespace+"hashes_total", float64(c.m.GetHashes()))
+
+	w.header(namespace+"hashrate", "gauge", "Current hashrate in hashes per second.")
+	w.sample(namespace+"hashrate", float64(c.m.GetHashrate()))
+
+	w.header(namespace+"hashrate_average", "gauge", "Average hashrate in hashes per second over a time window.")
+	for _, hw := range hashrateWindows {
+		w.sample(namespace+"hashrate_average", float64(c.m.GetHashrateAverage(hw.window)), "window", hw.name)
+	}
+
+	w.header(namespace+"thread_hashrate", "gauge", "Hashrate of a single mining thread in hashes per second.")
+	for i, h := range c.m.GetThreadHashrates(time.Second * 10) {
+		w.sample(namespace+"thread_hashrate", float64(h), "thread", strconv.Itoa(i))
+	}
+
+	w.header(namespace+"shares_submitted_total", "counter", "Total number of shares submitted to the pool.")
+	w.sample(namespace+"shares_submitted_total", float64(c.m.GetSubmittedShares()))
+
+	w.header(namespace+"shares_accepted_total", "counter", "Total number of shares accepted by the pool.")
+	w.sample(namespace+"shares_accepted_total", float64(c.m.GetAcceptedShares()))
+
+	w.header(namespace+"shares_rejected_total", "counter", "Total number of shares rejected by the pool.")
+	rejected := make(map[string]uint64, len(rejectReasons))
+	for reason, n := range c.m.GetRejectReasons() {
+		rejected[rejectReason(reason)] += n
+	}
+	for _, reason := range rejectReasons {
+		w.sample(namespace+"shares_rejected_total", float64(rejected[reason]), "reason", reason)
+	}
+
+	w.header(namespace+"share_latency_seconds", "histogram", "Round-trip time between submitting a share and the answer of the pool.")
+	c.latency.write(w, namespace+"share_latency_seconds")
+
+	var difficulty, height float64
+	if job := c.m.GetJob(); job != nil {
+		difficulty = float64(job.Difficulty)
+		height = job.Height
+	}
+	w.header(namespace+"job_difficulty", "gauge", "Difficulty of the current job.")
+	w.sample(namespace+"job_difficulty", difficulty)
+
+	w.header(namespace+"job_height", "gauge", "Block height of the current job.")
+	w.sample(namespace+"job_height", height)
+
+	w.header(namespace+"jobs_received_total", "counter", "Total number of jobs received from the pool.")
+	w.sample(namespace+"jobs_received_total", float64(c.m.GetJobsReceived()))
+
+	w.header(namespace+"reconnects_total", "counter", "Total number of reconnects to the pool.")
+	w.sample(namespace+"reconnects_total", float64(c.m.GetReconnects()))
+
+	var connected float64
+	if c.m.IsConnected() {
+		connected = 1
+	}
+	w.header(namespace+"connected", "gauge", "Whether the miner is connected to the pool.")
+	w.sample(namespace+"connected", connected)
+}
+
+// Server serves the metrics on a dedicated listener. The handler should be protected
+// like the API, the labels contain the wallet and the pool.
+type Server struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	srv    *http.Server
+}
+
+func New(ctx context.Context, handler http.Handler, listen string) *Server {
+	ctx, cancel := context.WithCancel(ctx)
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", handler)
+	return &Server{
+		ctx:    ctx,
+		cancel: cancel,
+		srv: &http.Server{
+			Addr:              listen,
+			Handler:           mux,
+			ReadHeaderTimeout: time.Second * 10,
+		},
+	}
+}
+
+func (s *Server) Serve() error {
+	go func() {
+		<-s.ctx.Done()
+		s.srv.Close() // nolint: errcheck
+	}()
+	if err := s.srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) Close() error {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// writer renders metrics in the prometheus text exposition format.
+type writer struct {
+	w      io.Writer
+	labels []string
+	err    error
+}
+
+func (w *writer) header(name, typ, help string) {
+	w.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// sample writes a single sample. Labels are passed as key value pairs
+// and are appended to the common labels of the writer.
+func (w *writer) sample(name string, value float64, labels ...string) {
+	var b strings.Builder
+	b.WriteString(name)
+	all := append(append([]string{}, w.labels...), labels...)
+	if len(all) > 0 {
+		b.WriteByte('{')
+		for i := 0; i+1 < len(all); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(all[i])
+			b.WriteString(`="`)
+			b.WriteString(escapeLabel(all[i+1]))
+			b.WriteByte('"')
+		}
+		b.WriteByte('}')
+	}
+	w.printf("%s %s\n", b.String(), formatFloat(value))
+}
+
+func (w *writer) printf(format string, args ...any) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = fmt.Fprintf(w.w, format, args...)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
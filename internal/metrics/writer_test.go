@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var exprHistogram = `# HELP latency_seconds test histogram
+# TYPE latency_seconds histogram
+latency_seconds_bucket{worker="rig\"1",le="0.1"} 1
+latency_seconds_bucket{worker="rig\"1",le="1"} 2
+latency_seconds_bucket{worker="rig\"1",le="+Inf"} 3
+latency_seconds_sum{worker="rig\"1"} 5.55
+latency_seconds_count{worker="rig\"1"} 3
+`
+
+func TestHistogram(t *testing.T) {
+	var b strings.Builder
+	w := &writer{w: &b, labels: []string{"worker", `rig"1`}}
+
+	h := newHistogram([]float64{0.1, 1})
+	h.observe(0.05)
+	h.observe(0.5)
+	h.observe(5)
+
+	w.header("latency_seconds", "histogram", "test histogram")
+	h.write(w, "latency_seconds")
+	assert.NoError(t, w.err)
+	assert.Equal(t, exprHistogram, b.String())
+}
+
+func TestRejectReason(t *testing.T) {
+	for reason, want := range map[string]string{
+		"Stale share":          "stale",
+		"job expired":          "stale",
+		"Duplicate share":      "duplicate",
+		"low difficulty share": "low_difficulty",
+		"Low diff":             "low_difficulty",
+		"Invalid nonce":        "invalid",
+		"bad result":           "invalid",
+		"unauthenticated":      "other",
+		"":                     "other",
+	} {
+		assert.Equal(t, want, rejectReason(reason), reason)
+	}
+}
@@ -0,0 +1,227 @@
+// Package minertest provides a fake miner for the tests of the packages which watch or control the miner.
+package minertest
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/teivah/broadcast"
+	miner "github.com/whalesburg/dero-stratum-miner/internal/dero-stratum-miner"
+	"github.com/whalesburg/dero-stratum-miner/internal/stratum"
+)
+
+// Miner returns the values of its fields. The fields may be changed directly as long as
+// the miner isn't used by another goroutine, otherwise with Set.
+type Miner struct {
+	mu sync.Mutex
+
+	Worker          string
+	Pool            string
+	Connected       bool
+	ConnectedSince  time.Time
+	Testnet         bool
+	Paused          bool
+	Stopped         bool // by Shutdown
+	Threads         int
+	MaxThreads      int // SetThreads fails above, 0 is no limit
+	Reconnects      int
+	Jobs            int64
+	Hashes          uint64
+	Accepted        uint64
+	Rejected        uint64
+	Hashrate        uint64 // average of the windows up to one minute
+	Baseline        uint64 // average of longer windows
+	Highest         uint64
+	History         []uint64
+	ThreadHashrates []uint64
+	Latency         time.Duration
+	Job             *stratum.Job
+	Uptime          time.Duration
+	Events          *broadcast.Relay[*miner.Event]
+	Shares          *broadcast.Relay[*miner.Event] // use Notify like the miner
+}
+
+// New returns a connected miner with one thread.
+func New() *Miner {
+	return &Miner{
+		Pool:      "stratum+tcp://pool.example.com:10300",
+		Connected: true,
+		Threads:   1,
+		Events:    broadcast.NewRelay[*miner.Event](),
+		Shares:    broadcast.NewRelay[*miner.Event](),
+	}
+}
+
+// NewMining returns a miner which mines with two threads on a job of the pool and has some shares:
+// 1 kH/s over up to one minute and 900 H/s over longer windows, 412 accepted and 7 rejected shares.
+func NewMining() *Miner {
+	m := New()
+	m.Worker = "rig1"
+	m.Pool = "stratum+tls://pool.whalesburg.com:4300"
+	m.Threads = 2
+	m.Hashrate, m.Baseline, m.ThreadHashrates = 1000, 900, []uint64{510, 490}
+	m.Accepted, m.Rejected, m.Latency = 412, 7, time.Millisecond*42
+	m.Job = &stratum.Job{Height: 1234, Difficulty: 50000}
+	m.Uptime = time.Hour
+	return m
+}
+
+// Set changes the fields while the miner is in use.
+func (m *Miner) Set(fn func(m *Miner)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	fn(m)
+}
+
+func (m *Miner) GetWorker() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Worker
+}
+
+func (m *Miner) GetPoolURL() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Pool
+}
+
+func (m *Miner) IsConnected() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Connected
+}
+
+func (m *Miner) GetConnectedSince() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ConnectedSince
+}
+
+func (m *Miner) IsTestnet() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Testnet
+}
+
+func (m *Miner) IsPaused() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Paused
+}
+
+func (m *Miner) Pause() {
+	m.Set(func(m *Miner) { m.Paused = true })
+}
+
+func (m *Miner) Resume() {
+	m.Set(func(m *Miner) { m.Paused = false })
+}
+
+// Reconnect counts a reconnect.
+func (m *Miner) Reconnect() {
+	m.Set(func(m *Miner) { m.Reconnects++ })
+}
+
+func (m *Miner) Shutdown() {
+	m.Set(func(m *Miner) { m.Stopped = true })
+}
+
+func (m *Miner) GetThreads() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Threads
+}
+
+func (m *Miner) SetThreads(n int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if n < 1 || (m.MaxThreads > 0 && n > m.MaxThreads) {
+		return fmt.Errorf("invalid thread count %d", n)
+	}
+	m.Threads = n
+	return nil
+}
+
+func (m *Miner) GetReconnects() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Reconnects
+}
+
+func (m *Miner) GetJobsReceived() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Jobs
+}
+
+func (m *Miner) GetHashes() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Hashes
+}
+
+func (m *Miner) GetHashrateAverage(window time.Duration) uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if window > time.Minute {
+		return m.Baseline
+	}
+	return m.Hashrate
+}
+
+func (m *Miner) GetHighestHashrate() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Highest
+}
+
+func (m *Miner) GetHashrateHistory() []uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.History
+}
+
+func (m *Miner) GetThreadHashrates(time.Duration) []uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ThreadHashrates
+}
+
+func (m *Miner) GetAcceptedShares() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Accepted
+}
+
+func (m *Miner) GetRejectedShares() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Rejected
+}
+
+func (m *Miner) GetShareLatency() time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Latency
+}
+
+func (m *Miner) GetJob() *stratum.Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Job
+}
+
+func (m *Miner) GetUptime() time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Uptime
+}
+
+func (m *Miner) NewEventListener(buff int) *broadcast.Listener[*miner.Event] {
+	return m.Events.Listener(buff)
+}
+
+func (m *Miner) NewShareEventListener(buff int) *broadcast.Listener[*miner.Event] {
+	return m.Shares.Listener(buff)
+}
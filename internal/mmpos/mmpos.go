@@ -0,0 +1,32 @@
+// Package mmpos integrates the miner as external miner into mmpOS.
+package mmpos
+
+import (
+	"github.com/whalesburg/dero-stratum-miner/internal/api"
+)
+
+// Name is the name of the miner in mmp-external.conf.
+const Name = "dero-stratum-miner"
+
+// Stats is the JSON mmp-stats.sh reports to the mmpOS agent. mmpOS reads the CPU temperature on its own,
+// the CPU is reported as a single device.
+type Stats struct {
+	BusIDs  []string `json:"busid"`
+	Hashes  []uint64 `json:"hash"`
+	Units   string   `json:"units"`
+	Shares  []uint64 `json:"air"` // accepted, invalid and rejected
+	Name    string   `json:"miner_name"`
+	Version string   `json:"miner_version"`
+}
+
+// NewStats converts the stats of the miner.
+func NewStats(s *api.Stats) *Stats {
+	return &Stats{
+		BusIDs:  []string{"cpu"},
+		Hashes:  []uint64{s.Hashrate},
+		Units:   "hs",
+		Shares:  []uint64{s.Accepted, s.Invalid, s.Late()},
+		Name:    Name,
+		Version: s.Version,
+	}
+}
@@ -0,0 +1,27 @@
+package mmpos
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalesburg/dero-stratum-miner/internal/api/apitest"
+)
+
+func TestStats(t *testing.T) {
+	want, err := os.ReadFile("testdata/mmpos_stats.json")
+	require.NoError(t, err)
+
+	got, err := json.Marshal(NewStats(apitest.Stats(t)))
+	require.NoError(t, err)
+	assert.Equal(t, strings.TrimSpace(string(want)), string(got))
+}
+
+func TestStatsInvalidAhead(t *testing.T) {
+	s := apitest.Stats(t)
+	s.Rejected, s.Invalid = 1, 2
+	assert.Equal(t, []uint64{s.Accepted, 2, 0}, NewStats(s).Shares)
+}
@@ -0,0 +1,401 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// packet types of MQTT 3.1.1, see https://docs.oasis-open.org/mqtt/mqtt/v3.1.1/os/mqtt-v3.1.1-os.html
+const (
+	packetConnect    byte = 1
+	packetConnack    byte = 2
+	packetPublish    byte = 3
+	packetPuback     byte = 4
+	packetSubscribe  byte = 8
+	packetSuback     byte = 9
+	packetPingreq    byte = 12
+	packetPingresp   byte = 13
+	packetDisconnect byte = 14
+)
+
+// maxRemainingLength is the largest body of a packet.
+const maxRemainingLength = 268435455
+
+var (
+	ErrUnknownScheme      = errors.New("mqtt: unknown scheme, use mqtt, mqtts, tcp or tls")
+	ErrConnectionRefused  = errors.New("mqtt: connection refused")
+	ErrSubscribeRejected  = errors.New("mqtt: subscription rejected")
+	ErrMalformedPacket    = errors.New("mqtt: malformed packet")
+	ErrConnectionTimedOut = errors.New("mqtt: connection timed out")
+)
+
+var connackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// Message is a message which is published or received.
+type Message struct {
+	Topic   string
+	Payload []byte
+	Retain  bool
+}
+
+// Options configure the connection to the broker.
+type Options struct {
+	Broker    string // url like mqtt://127.0.0.1:1883 or mqtts://broker:8883
+	ClientID  string
+	Username  string
+	Password  string
+	KeepAlive time.Duration
+	Will      *Message // published by the broker if the connection is lost
+}
+
+// Client is a minimal MQTT 3.1.1 client. Messages are published and received with QoS 0.
+type Client struct {
+	conn      net.Conn
+	r         *bufio.Reader
+	keepAlive time.Duration
+	onMessage func(*Message)
+
+	writeMu  sync.Mutex
+	packetID uint16
+
+	done chan struct{}
+	once sync.Once
+	err  error
+}
+
+// Dial connects to the broker. onMessage is called for every message on a subscribed topic,
+// from the goroutine which reads the connection.
+func Dial(ctx context.Context, opts *Options, onMessage func(*Message)) (*Client, error) {
+	addr, tlsConfig, err := parseBroker(opts.Broker)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		d    net.Dialer
+		conn net.Conn
+	)
+	if tlsConfig != nil {
+		conn, err = (&tls.Dialer{NetDialer: &d, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{
+		conn:      conn,
+		r:         bufio.NewReader(conn),
+		keepAlive: opts.KeepAlive,
+		onMessage: onMessage,
+		done:      make(chan struct{}),
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline) // nolint: errcheck
+	}
+	if err := c.connect(opts); err != nil {
+		conn.Close() // nolint: errcheck
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{}) // nolint: errcheck
+	go c.read()
+	if c.keepAlive > 0 {
+		go c.ping()
+	}
+	return c, nil
+}
+
+func parseBroker(broker string) (string, *tls.Config, error) {
+	u, err := url.Parse(broker)
+	if err != nil {
+		return "", nil, err
+	}
+	var (
+		port      string
+		tlsConfig *tls.Config
+	)
+	switch u.Scheme {
+	case "mqtt", "tcp":
+		port = "1883"
+	case "mqtts", "tls", "ssl":
+		port = "8883"
+		tlsConfig = &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}
+	default:
+		return "", nil, fmt.Errorf("%w: %s", ErrUnknownScheme, u.Scheme)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	return net.JoinHostPort(u.Hostname(), port), tlsConfig, nil
+}
+
+func (c *Client) connect(opts *Options) error {
+	var flags byte = 0x02 // clean session
+	payload := appendString(nil, opts.ClientID)
+	if opts.Will != nil {
+		flags |= 0x04
+		if opts.Will.Retain {
+			flags |= 0x20
+		}
+		payload = appendString(payload, opts.Will.Topic)
+		payload = appendBytes(payload, opts.Will.Payload)
+	}
+	if opts.Username != "" {
+		flags |= 0x80
+		payload = appendString(payload, opts.Username)
+		if opts.Password != "" {
+			flags |= 0x40
+			payload = appendBytes(payload, []byte(opts.Password))
+		}
+	}
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, flags)
+	body = appendUint16(body, uint16(opts.KeepAlive/time.Second))
+	body = append(body, payload...)
+	if err := c.write(packetConnect<<4, body); err != nil {
+		return err
+	}
+
+	header, body, err := readPacket(c.r)
+	if err != nil {
+		return err
+	}
+	if header>>4 != packetConnack || len(body) != 2 {
+		return ErrMalformedPacket
+	}
+	if code := body[1]; code != 0 {
+		reason, ok := connackErrors[code]
+		if !ok {
+			reason = fmt.Sprintf("code %d", code)
+		}
+		return fmt.Errorf("%w: %s", ErrConnectionRefused, reason)
+	}
+	return nil
+}
+
+// Publish sends a message with QoS 0.
+func (c *Client) Publish(topic string, payload []byte, retain bool) error {
+	var header byte = packetPublish << 4
+	if retain {
+		header |= 0x01
+	}
+	body := appendString(nil, topic)
+	body = append(body, payload...)
+	return c.write(header, body)
+}
+
+// Subscribe subscribes to a topic filter with QoS 0. A rejected subscription closes the client.
+func (c *Client) Subscribe(filter string) error {
+	c.writeMu.Lock()
+	c.packetID++
+	if c.packetID == 0 {
+		c.packetID = 1
+	}
+	id := c.packetID
+	c.writeMu.Unlock()
+
+	body := appendUint16(nil, id)
+	body = appendString(body, filter)
+	body = append(body, 0)
+	return c.write(packetSubscribe<<4|0x02, body)
+}
+
+// Disconnect closes the connection cleanly, the will isn't published.
+func (c *Client) Disconnect() error {
+	err := c.write(packetDisconnect<<4, nil)
+	c.close(nil)
+	return err
+}
+
+// Done is closed when the connection is lost or closed.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns the reason why the connection was lost, after Done was closed.
+func (c *Client) Err() error {
+	<-c.done
+	return c.err
+}
+
+func (c *Client) close(err error) {
+	c.once.Do(func() {
+		c.err = err
+		c.conn.Close() // nolint: errcheck
+		close(c.done)
+	})
+}
+
+func (c *Client) write(header byte, body []byte) error {
+	if len(body) > maxRemainingLength {
+		return ErrMalformedPacket
+	}
+	packet := append([]byte{header}, appendLength(nil, len(body))...)
+	packet = append(packet, body...)
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.keepAlive > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(c.keepAlive)) // nolint: errcheck
+	}
+	if _, err := c.conn.Write(packet); err != nil {
+		c.close(err)
+		return err
+	}
+	return nil
+}
+
+func (c *Client) read() {
+	for {
+		if c.keepAlive > 0 {
+			// the broker answers the pings, so something must arrive within the keep alive
+			c.conn.SetReadDeadline(time.Now().Add(c.keepAlive * 3 / 2)) // nolint: errcheck
+		}
+		header, body, err := readPacket(c.r)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				err = ErrConnectionTimedOut
+			}
+			c.close(err)
+			return
+		}
+		switch header >> 4 {
+		case packetPublish:
+			msg, id, err := parsePublish(header, body)
+			if err != nil {
+				c.close(err)
+				return
+			}
+			if id != 0 {
+				c.write(packetPuback<<4, appendUint16(nil, id)) // nolint: errcheck
+			}
+			if c.onMessage != nil {
+				c.onMessage(msg)
+			}
+		case packetSuback:
+			if len(body) < 3 {
+				c.close(ErrMalformedPacket)
+				return
+			}
+			for _, code := range body[2:] {
+				if code == 0x80 {
+					c.close(ErrSubscribeRejected)
+					return
+				}
+			}
+		}
+	}
+}
+
+func (c *Client) ping() {
+	ticker := time.NewTicker(c.keepAlive / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.write(packetPingreq<<4, nil); err != nil {
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// parsePublish returns the message and, for QoS 1 and 2, its packet id.
+func parsePublish(header byte, body []byte) (*Message, uint16, error) {
+	topic, rest, err := readString(body)
+	if err != nil {
+		return nil, 0, err
+	}
+	var id uint16
+	if qos := header >> 1 & 0x03; qos > 0 {
+		if len(rest) < 2 {
+			return nil, 0, ErrMalformedPacket
+		}
+		id = binary.BigEndian.Uint16(rest)
+		rest = rest[2:]
+	}
+	return &Message{Topic: topic, Payload: rest, Retain: header&0x01 == 1}, id, nil
+}
+
+// readPacket reads the fixed header and the body of a packet.
+func readPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	var length, shift int
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, ErrMalformedPacket
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length |= int(b&0x7f) << shift
+		if b&0x80 == 0 {
+			break
+		}
+		shift += 7
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
+
+func appendLength(b []byte, n int) []byte {
+	for {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		b = append(b, digit)
+		if n == 0 {
+			return b
+		}
+	}
+}
+
+func appendUint16(b []byte, n uint16) []byte {
+	return append(b, byte(n>>8), byte(n))
+}
+
+func appendString(b []byte, s string) []byte {
+	return appendBytes(b, []byte(s))
+}
+
+func appendBytes(b, data []byte) []byte {
+	b = appendUint16(b, uint16(len(data)))
+	return append(b, data...)
+}
+
+func readString(b []byte) (string, []byte, error) {
+	if len(b) < 2 {
+		return "", nil, ErrMalformedPacket
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+n {
+		return "", nil, ErrMalformedPacket
+	}
+	return string(b[2 : 2+n]), b[2+n:], nil
+}
@@ -0,0 +1,112 @@
+package mqtt
+
+import (
+	"runtime"
+
+	"github.com/whalesburg/dero-stratum-miner/internal/version"
+)
+
+// discoveryConfig is the discovery payload of a Home Assistant entity,
+// see https://www.home-assistant.io/integrations/mqtt/#mqtt-discovery
+type discoveryConfig struct {
+	Name              string  `json:"name"`
+	UniqueID          string  `json:"unique_id"`
+	Icon              string  `json:"icon,omitempty"`
+	DeviceClass       string  `json:"device_class,omitempty"`
+	StateClass        string  `json:"state_class,omitempty"`
+	Unit              string  `json:"unit_of_measurement,omitempty"`
+	StateTopic        string  `json:"state_topic"`
+	ValueTemplate     string  `json:"value_template"`
+	CommandTopic      string  `json:"command_topic,omitempty"`
+	CommandTemplate   string  `json:"command_template,omitempty"`
+	PayloadOn         string  `json:"payload_on,omitempty"`
+	PayloadOff        string  `json:"payload_off,omitempty"`
+	StateOn           string  `json:"state_on,omitempty"`
+	StateOff          string  `json:"state_off,omitempty"`
+	Min               int     `json:"min,omitempty"`
+	Max               int     `json:"max,omitempty"`
+	Mode              string  `json:"mode,omitempty"`
+	AvailabilityTopic string  `json:"availability_topic"`
+	Device            *device `json:"device"`
+}
+
+type device struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer"`
+	Model        string   `json:"model"`
+	SWVersion    string   `json:"sw_version"`
+}
+
+type entity struct {
+	component string
+	object    string
+	config    *discoveryConfig // nil removes the entity
+}
+
+// entities returns the sensors and controls of the miner. Without commands the controls
+// are replaced by sensors, the retained configs of the controls are removed.
+func (b *Bridge) entities() []entity {
+	dev := &device{
+		Identifiers:  []string{"dero-stratum-miner_" + b.nodeID},
+		Name:         b.nodeID,
+		Manufacturer: "whalesburg",
+		Model:        "dero-stratum-miner",
+		SWVersion:    version.Version,
+	}
+	sensor := func(object, name, value string, cfg discoveryConfig) entity {
+		cfg.Name = name
+		cfg.UniqueID = b.nodeID + "_" + object
+		cfg.StateTopic = b.topic("stats")
+		cfg.ValueTemplate = value
+		cfg.AvailabilityTopic = b.topic("availability")
+		cfg.Device = dev
+		return entity{component: "sensor", object: object, config: &cfg}
+	}
+	control := func(component, object, name, value string, cfg discoveryConfig) entity {
+		cfg.Name = name
+		cfg.UniqueID = b.nodeID + "_" + object
+		cfg.StateTopic = b.topic("status")
+		cfg.ValueTemplate = value
+		cfg.AvailabilityTopic = b.topic("availability")
+		cfg.Device = dev
+		return entity{component: component, object: object, config: &cfg}
+	}
+	entities := []entity{
+		sensor("hashrate", "Hashrate", "{{ value_json.hashrate }}", discoveryConfig{Icon: "mdi:pickaxe", StateClass: "measurement", Unit: "H/s"}),
+		sensor("accepted_shares", "Accepted shares", "{{ value_json.accepted }}", discoveryConfig{Icon: "mdi:check", StateClass: "total_increasing"}),
+		sensor("rejected_shares", "Rejected shares", "{{ value_json.rejected }}", discoveryConfig{Icon: "mdi:close", StateClass: "total_increasing"}),
+		sensor("share_latency", "Share latency", "{{ value_json.latency_ms }}", discoveryConfig{DeviceClass: "duration", StateClass: "measurement", Unit: "ms"}),
+		sensor("difficulty", "Difficulty", "{{ value_json.difficulty }}", discoveryConfig{Icon: "mdi:chart-line", StateClass: "measurement"}),
+		sensor("height", "Block height", "{{ value_json.height }}", discoveryConfig{Icon: "mdi:cube-outline"}),
+		control("binary_sensor", "connected", "Pool connection", "{{ 'OFF' if value_json.state == 'disconnected' else 'ON' }}", discoveryConfig{DeviceClass: "connectivity"}),
+	}
+	if !b.commands {
+		return append(entities,
+			control("binary_sensor", "mining", "Mining", "{{ 'OFF' if value_json.state == 'paused' else 'ON' }}", discoveryConfig{Icon: "mdi:pickaxe"}),
+			control("sensor", "threads", "Threads", "{{ value_json.threads }}", discoveryConfig{Icon: "mdi:cpu-64-bit"}),
+			entity{component: "switch", object: "mining"},
+			entity{component: "number", object: "threads"},
+		)
+	}
+	return append(entities,
+		control("switch", "mining", "Mining", "{{ 'OFF' if value_json.state == 'paused' else 'ON' }}", discoveryConfig{
+			Icon:         "mdi:pickaxe",
+			CommandTopic: b.topic("command"),
+			PayloadOn:    "resume",
+			PayloadOff:   "pause",
+			StateOn:      "ON",
+			StateOff:     "OFF",
+		}),
+		control("number", "threads", "Threads", "{{ value_json.threads }}", discoveryConfig{
+			Icon:            "mdi:cpu-64-bit",
+			CommandTopic:    b.topic("command"),
+			CommandTemplate: "threads {{ value }}",
+			Min:             1,
+			Max:             runtime.GOMAXPROCS(0),
+			Mode:            "box",
+		}),
+		entity{component: "binary_sensor", object: "mining"},
+		entity{component: "sensor", object: "threads"},
+	)
+}
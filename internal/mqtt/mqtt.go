@@ -0,0 +1,335 @@
+// Package mqtt publishes the status and the stats of the miner to an MQTT broker and optionally receives commands.
+// Discovery payloads let the miner show up as a device in Home Assistant.
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/whalesburg/dero-stratum-miner/internal/config"
+	"github.com/whalesburg/dero-stratum-miner/internal/stratum"
+)
+
+const (
+	keepAlive     = time.Minute
+	dialTimeout   = time.Second * 30
+	retryDelay    = time.Second * 10
+	checkDelay    = time.Second
+	statusOnline  = "online"
+	statusOffline = "offline"
+)
+
+var ErrUnknownCommand = errors.New("unknown command, use pause, resume or threads <n>")
+
+// Miner is the state of the miner which is published and the controls which are used by the commands.
+type Miner interface {
+	GetWorker() string
+	GetPoolURL() string
+	IsConnected() bool
+	IsPaused() bool
+	GetThreads() int
+	GetHashrateAverage(window time.Duration) uint64
+	GetAcceptedShares() uint64
+	GetRejectedShares() uint64
+	GetShareLatency() time.Duration
+	GetJob() *stratum.Job
+	GetUptime() time.Duration
+	Pause()
+	Resume()
+	SetThreads(n int) error
+}
+
+// Status is published as retained message whenever it changes.
+type Status struct {
+	State   string `json:"state"` // mining, paused or disconnected
+	Threads int    `json:"threads"`
+	Pool    string `json:"pool"`
+	Worker  string `json:"worker"`
+}
+
+// Stats are published periodically.
+type Stats struct {
+	Hashrate    uint64  `json:"hashrate"`
+	Hashrate60s uint64  `json:"hashrate_60s"`
+	Hashrate15m uint64  `json:"hashrate_15m"`
+	Accepted    uint64  `json:"accepted"`
+	Rejected    uint64  `json:"rejected"`
+	LatencyMS   int64   `json:"latency_ms"`
+	Difficulty  uint64  `json:"difficulty"`
+	Height      float64 `json:"height"`
+	Uptime      int64   `json:"uptime"`
+}
+
+// Bridge connects the miner to the broker. The topics below the prefix are:
+//
+//	availability  online or offline, retained
+//	status        the Status as JSON, retained
+//	stats         the Stats as JSON
+//	command       pause, resume or threads <n>, only subscribed if commands are enabled
+//
+// Anyone who can publish to the command topic controls the miner, so the commands are opt-in.
+type Bridge struct {
+	m         Miner
+	opts      *Options
+	broker    string // the url without password, for logging
+	prefix    string
+	nodeID    string
+	interval  time.Duration
+	commands  bool
+	discovery string
+	logger    logr.Logger
+	retry     time.Duration
+	changed   chan struct{}
+}
+
+// New creates a bridge for cfg. The prefix defaults to dero-stratum-miner/<hostname>.
+func New(m Miner, cfg *config.MQTT, logger logr.Logger) (*Bridge, error) {
+	if _, _, err := parseBroker(cfg.Broker); err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(cfg.Broker)
+	if err != nil {
+		return nil, err
+	}
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "miner"
+	}
+	nodeID := sanitize(host)
+	b := &Bridge{
+		m:        m,
+		broker:   u.Redacted(),
+		prefix:   strings.TrimSuffix(cfg.Prefix, "/"),
+		nodeID:   nodeID,
+		interval: time.Duration(cfg.Interval),
+		commands: cfg.Commands,
+		logger:   logger.WithName("mqtt"),
+		retry:    retryDelay,
+		changed:  make(chan struct{}, 1),
+	}
+	if b.prefix == "" {
+		b.prefix = "dero-stratum-miner/" + nodeID
+	}
+	if cfg.Discovery {
+		b.discovery = strings.TrimSuffix(cfg.DiscoveryPrefix, "/")
+	}
+	clientID := cfg.ClientID
+	if clientID == "" {
+		clientID = "dero-stratum-miner-" + nodeID
+	}
+	username, password := cfg.Username, cfg.Password
+	if username == "" && u.User != nil {
+		username = u.User.Username()
+		password, _ = u.User.Password()
+	}
+	b.opts = &Options{
+		Broker:    cfg.Broker,
+		ClientID:  clientID,
+		Username:  username,
+		Password:  password,
+		KeepAlive: keepAlive,
+		Will:      &Message{Topic: b.topic("availability"), Payload: []byte(statusOffline), Retain: true},
+	}
+	return b, nil
+}
+
+// sanitize turns s into a valid node id of Home Assistant and topic level.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return '_'
+	}, s)
+}
+
+func (b *Bridge) topic(name string) string {
+	return b.prefix + "/" + name
+}
+
+// Run keeps the bridge connected until the context is cancelled.
+func (b *Bridge) Run(ctx context.Context) {
+	for {
+		err := b.session(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		b.logger.Error(err, "Disconnected from the MQTT broker", "broker", b.broker)
+		select {
+		case <-time.After(b.retry):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// session connects to the broker and publishes until the connection is lost or the context is cancelled.
+func (b *Bridge) session(ctx context.Context) error {
+	var handler func(*Message)
+	if b.commands {
+		handler = b.handle
+	}
+	dialCtx, cancel := context.WithTimeout(ctx, dialTimeout)
+	c, err := Dial(dialCtx, b.opts, handler)
+	cancel()
+	if err != nil {
+		return err
+	}
+	b.logger.Info("Connected to the MQTT broker", "broker", b.broker, "prefix", b.prefix)
+
+	if err := b.announce(c); err != nil {
+		return err
+	}
+	status := b.status()
+	if err := b.publishJSON(c, "status", status, true); err != nil {
+		return err
+	}
+	if err := b.publishJSON(c, "stats", b.stats(), false); err != nil {
+		return err
+	}
+	if b.commands {
+		if err := c.Subscribe(b.topic("command")); err != nil {
+			return err
+		}
+	}
+
+	check := time.NewTicker(checkDelay)
+	defer check.Stop()
+	stats := time.NewTicker(b.interval)
+	defer stats.Stop()
+	for {
+		select {
+		case <-check.C:
+		case <-b.changed:
+		case <-stats.C:
+			if err := b.publishJSON(c, "stats", b.stats(), false); err != nil {
+				return err
+			}
+			continue
+		case <-c.Done():
+			return c.Err()
+		case <-ctx.Done():
+			// a clean disconnect doesn't publish the will
+			c.Publish(b.topic("availability"), []byte(statusOffline), true) // nolint: errcheck
+			return c.Disconnect()
+		}
+		if s := b.status(); s != status {
+			status = s
+			if err := b.publishJSON(c, "status", status, true); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// announce publishes the availability and the discovery payloads.
+func (b *Bridge) announce(c *Client) error {
+	if b.discovery != "" {
+		for _, e := range b.entities() {
+			topic := fmt.Sprintf("%s/%s/%s/%s/config", b.discovery, e.component, b.nodeID, e.object)
+			// an empty retained message removes the entity
+			var data []byte
+			if e.config != nil {
+				var err error
+				if data, err = json.Marshal(e.config); err != nil {
+					return err
+				}
+			}
+			if err := c.Publish(topic, data, true); err != nil {
+				return err
+			}
+		}
+	}
+	return c.Publish(b.topic("availability"), []byte(statusOnline), true)
+}
+
+func (b *Bridge) publishJSON(c *Client, name string, v any, retain bool) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Publish(b.topic(name), data, retain)
+}
+
+func (b *Bridge) status() Status {
+	s := Status{
+		State:   "mining",
+		Threads: b.m.GetThreads(),
+		Pool:    b.m.GetPoolURL(),
+		Worker:  b.m.GetWorker(),
+	}
+	switch {
+	case !b.m.IsConnected():
+		s.State = "disconnected"
+	case b.m.IsPaused():
+		s.State = "paused"
+	}
+	return s
+}
+
+func (b *Bridge) stats() *Stats {
+	s := &Stats{
+		Hashrate:    b.m.GetHashrateAverage(time.Second * 10),
+		Hashrate60s: b.m.GetHashrateAverage(time.Minute),
+		Hashrate15m: b.m.GetHashrateAverage(time.Minute * 15),
+		Accepted:    b.m.GetAcceptedShares(),
+		Rejected:    b.m.GetRejectedShares(),
+		LatencyMS:   b.m.GetShareLatency().Milliseconds(),
+		Uptime:      int64(b.m.GetUptime().Seconds()),
+	}
+	if job := b.m.GetJob(); job != nil {
+		s.Difficulty = job.Difficulty
+		s.Height = job.Height
+	}
+	return s
+}
+
+// handle executes a message on the command topic. Retained commands are ignored, the broker
+// would deliver them again after every reconnect.
+func (b *Bridge) handle(msg *Message) {
+	cmd := strings.TrimSpace(string(msg.Payload))
+	if msg.Retain {
+		b.logger.Error(nil, "Ignoring a retained MQTT command", "command", cmd, "topic", msg.Topic)
+		return
+	}
+	if err := b.execute(cmd); err != nil {
+		b.logger.Error(err, "Invalid MQTT command", "command", cmd)
+		return
+	}
+	b.logger.Info("MQTT command", "command", cmd)
+	select {
+	case b.changed <- struct{}{}:
+	default:
+	}
+}
+
+func (b *Bridge) execute(cmd string) error {
+	fields := strings.Fields(strings.ToLower(cmd))
+	switch {
+	case len(fields) == 1 && fields[0] == "pause":
+		b.m.Pause()
+	case len(fields) == 1 && fields[0] == "resume":
+		b.m.Resume()
+	case len(fields) == 2 && fields[0] == "threads":
+		// Home Assistant sends numbers as floats, e.g. 4.0
+		n, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || n != float64(int(n)) {
+			return fmt.Errorf("invalid thread count %s", fields[1])
+		}
+		return b.m.SetThreads(int(n))
+	default:
+		return ErrUnknownCommand
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/jpillora/backoff"
+	"github.com/whalesburg/dero-stratum-miner/internal/config"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown webhook format, use generic, discord, slack or telegram")
+	ErrUnknownEvent  = errors.New("unknown webhook event")
+	ErrNoChatID      = errors.New("telegram webhooks need a chat_id parameter")
+)
+
+// Payload formats
+const (
+	FormatGeneric  = "generic"
+	FormatDiscord  = "discord"
+	FormatSlack    = "slack"
+	FormatTelegram = "telegram"
+)
+
+// Event is the reason of a notification.
+type Event string
+
+const (
+	EventStarted        Event = "started"
+	EventDisconnected   Event = "disconnected"
+	EventReconnected    Event = "reconnected"
+	EventReconnectStorm Event = "reconnect_storm"
+	EventRejectStreak   Event = "reject_streak"
+	EventHashrateDrop   Event = "hashrate_drop"
+	EventNotMining      Event = "not_mining"
+)
+
+// Events are all the events webhooks can subscribe to.
+var Events = []Event{EventStarted, EventDisconnected, EventReconnected, EventReconnectStorm, EventRejectStreak, EventHashrateDrop, EventNotMining}
+
+// Notification is sent as JSON by generic webhooks.
+type Notification struct {
+	Event    Event     `json:"event"`
+	Message  string    `json:"message"`
+	Host     string    `json:"host"`
+	Pool     string    `json:"pool"`
+	Hashrate uint64    `json:"hashrate"`
+	Time     time.Time `json:"time"`
+}
+
+// Text is the message of the chat formats.
+func (n *Notification) Text() string {
+	return fmt.Sprintf("dero-stratum-miner on %s: %s", n.Host, n.Message)
+}
+
+type webhook struct {
+	url    string
+	format string
+	chatID string
+	events map[Event]bool
+}
+
+func newWebhook(cfg config.Webhook) (*webhook, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	w := &webhook{
+		url:    cfg.URL,
+		format: cfg.Format,
+		events: make(map[Event]bool),
+	}
+	if w.format == "" {
+		w.format = detectFormat(u)
+	}
+	switch w.format {
+	case FormatGeneric, FormatDiscord, FormatSlack:
+	case FormatTelegram:
+		// the chat is passed as parameter of the url, the bot api expects it in the payload
+		q := u.Query()
+		w.chatID = q.Get("chat_id")
+		if w.chatID == "" {
+			return nil, ErrNoChatID
+		}
+		q.Del("chat_id")
+		u.RawQuery = q.Encode()
+		w.url = u.String()
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, w.format)
+	}
+
+	for _, e := range cfg.Events {
+		if !isEvent(Event(e)) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownEvent, e)
+		}
+		w.events[Event(e)] = true
+	}
+	return w, nil
+}
+
+func detectFormat(u *url.URL) string {
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case host == "discord.com" || host == "discordapp.com" || strings.HasSuffix(host, ".discord.com"):
+		return FormatDiscord
+	case host == "hooks.slack.com":
+		return FormatSlack
+	case host == "api.telegram.org":
+		return FormatTelegram
+	}
+	return FormatGeneric
+}
+
+func isEvent(e Event) bool {
+	for _, v := range Events {
+		if v == e {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *webhook) wants(e Event) bool {
+	return len(w.events) == 0 || w.events[e]
+}
+
+func (w *webhook) payload(n *Notification) ([]byte, error) {
+	switch w.format {
+	case FormatDiscord:
+		return json.Marshal(map[string]string{"content": n.Text()})
+	case FormatSlack:
+		return json.Marshal(map[string]string{"text": n.Text()})
+	case FormatTelegram:
+		return json.Marshal(map[string]string{"chat_id": w.chatID, "text": n.Text()})
+	}
+	return json.Marshal(n)
+}
+
+// Notifier sends notifications to webhooks. Failed requests are retried with backoff and every event
+// has a cooldown, so a flapping rig doesn't flood the channel.
+type Notifier struct {
+	ctx      context.Context
+	webhooks []*webhook
+	cooldown time.Duration
+	retries  int
+	client   *http.Client
+	backoff  func() *backoff.Backoff
+	now      func() time.Time
+	logger   logr.Logger
+
+	mu   sync.Mutex
+	sent map[Event]time.Time
+}
+
+// NewNotifier creates a notifier for the configured webhooks.
+func NewNotifier(ctx context.Context, cfg *config.Notify, logger logr.Logger) (*Notifier, error) {
+	n := &Notifier{
+		ctx:      ctx,
+		cooldown: time.Duration(cfg.Cooldown),
+		retries:  cfg.Retries,
+		client:   &http.Client{Timeout: time.Second * 10},
+		backoff: func() *backoff.Backoff {
+			return &backoff.Backoff{Min: time.Second, Max: time.Minute, Factor: 2, Jitter: true}
+		},
+		now:    time.Now,
+		logger: logger.WithName("notify"),
+		sent:   make(map[Event]time.Time),
+	}
+	for i, c := range cfg.Webhooks {
+		w, err := newWebhook(c)
+		if err != nil {
+			// the url isn't part of the error, it usually contains a secret token
+			return nil, fmt.Errorf("webhook %d: %w", i+1, err)
+		}
+		n.webhooks = append(n.webhooks, w)
+	}
+	return n, nil
+}
+
+// Notify sends the notification in the background unless the event is in its cooldown.
+// It reports whether the notification is sent.
+func (n *Notifier) Notify(notification *Notification) bool {
+	now := n.now()
+	n.mu.Lock()
+	if last, ok := n.sent[notification.Event]; ok && now.Sub(last) < n.cooldown {
+		n.mu.Unlock()
+		n.logger.V(1).Info("Notification in cooldown", "event", notification.Event)
+		return false
+	}
+	n.sent[notification.Event] = now
+	n.mu.Unlock()
+	if notification.Time.IsZero() {
+		notification.Time = now
+	}
+
+	for _, w := range n.webhooks {
+		if !w.wants(notification.Event) {
+			continue
+		}
+		go func(w *webhook) {
+			if err := n.send(w, notification); err != nil {
+				n.logger.Error(err, "Failed to send notification", "event", notification.Event, "format", w.format)
+			}
+		}(w)
+	}
+	return true
+}
+
+// send posts the notification and retries on network errors, rate limits and server errors.
+func (n *Notifier) send(w *webhook, notification *Notification) error {
+	body, err := w.payload(notification)
+	if err != nil {
+		return err
+	}
+	b := n.backoff()
+	for attempt := 0; ; attempt++ {
+		wait, err := n.post(w.url, body)
+		if err == nil {
+			return nil
+		}
+		if wait < 0 || attempt >= n.retries {
+			return err
+		}
+		if wait == 0 {
+			wait = b.Duration()
+		}
+		n.logger.V(1).Info("Retrying notification", "event", notification.Event, "error", err.Error(), "wait", wait)
+		select {
+		case <-time.After(wait):
+		case <-n.ctx.Done():
+			return n.ctx.Err()
+		}
+	}
+}
+
+// post sends the request once. The returned duration is how long to wait before a retry,
+// 0 for the backoff and negative if the request must not be retried.
+func (n *Notifier) post(u string, body []byte) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(n.ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return -1, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := n.client.Do(req)
+	if err != nil {
+		// the url would end up in the log otherwise
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 4096)) // nolint: errcheck
+
+	switch {
+	case res.StatusCode < 300:
+		return 0, nil
+	case res.StatusCode == http.StatusTooManyRequests:
+		wait := time.Duration(0)
+		if s, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && s > 0 {
+			wait = time.Duration(s) * time.Second
+		}
+		return wait, fmt.Errorf("rate limited: %s", res.Status)
+	case res.StatusCode >= 500:
+		return 0, fmt.Errorf("server error: %s", res.Status)
+	}
+	return -1, fmt.Errorf("webhook rejected the notification: %s", res.Status)
+}
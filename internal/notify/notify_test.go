@@ -0,0 +1,207 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/jpillora/backoff"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalesburg/dero-stratum-miner/internal/config"
+	"github.com/whalesburg/dero-stratum-miner/internal/testutil"
+)
+
+type request struct {
+	path string
+	body map[string]interface{}
+}
+
+// webhookServer stands in for the webhook services and answers with the given status codes in order.
+type webhookServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	requests chan request
+}
+
+func newWebhookServer(t *testing.T, statuses ...int) *webhookServer {
+	s := &webhookServer{statuses: statuses, requests: make(chan request, 16)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		s.mu.Lock()
+		status := http.StatusNoContent
+		if len(s.statuses) > 0 {
+			status, s.statuses = s.statuses[0], s.statuses[1:]
+		}
+		s.mu.Unlock()
+		w.WriteHeader(status)
+		s.requests <- request{path: r.URL.RequestURI(), body: body}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *webhookServer) next(t *testing.T) request {
+	t.Helper()
+	select {
+	case r := <-s.requests:
+		return r
+	case <-time.After(time.Second * 5):
+		t.Fatal("no request received")
+	}
+	return request{}
+}
+
+func (s *webhookServer) none(t *testing.T) {
+	t.Helper()
+	select {
+	case r := <-s.requests:
+		t.Fatalf("unexpected request: %v", r)
+	case <-time.After(time.Millisecond * 100):
+	}
+}
+
+func newTestNotifier(t *testing.T, cfg *config.Notify) *Notifier {
+	n, err := NewNotifier(context.Background(), cfg, logr.Discard())
+	require.NoError(t, err)
+	n.backoff = func() *backoff.Backoff {
+		return &backoff.Backoff{Min: time.Millisecond, Max: time.Millisecond * 10}
+	}
+	return n
+}
+
+func testNotification(e Event) *Notification {
+	return &Notification{Event: e, Message: "disconnected from the pool for 1m0s", Host: "rig1", Pool: "stratum+tcp://pool:10300"}
+}
+
+func TestFormats(t *testing.T) {
+	s := newWebhookServer(t)
+	for _, tc := range []struct {
+		format string
+		url    string
+		want   map[string]interface{}
+		path   string
+	}{
+		{FormatGeneric, "/hook", map[string]interface{}{
+			"event": "disconnected", "message": "disconnected from the pool for 1m0s", "host": "rig1",
+			"pool": "stratum+tcp://pool:10300", "hashrate": float64(0), "time": "2022-01-01T12:00:00Z",
+		}, "/hook"},
+		{FormatDiscord, "/api/webhooks/1/token", map[string]interface{}{
+			"content": "dero-stratum-miner on rig1: disconnected from the pool for 1m0s",
+		}, "/api/webhooks/1/token"},
+		{FormatSlack, "/services/T/B/X", map[string]interface{}{
+			"text": "dero-stratum-miner on rig1: disconnected from the pool for 1m0s",
+		}, "/services/T/B/X"},
+		{FormatTelegram, "/bot123:abc/sendMessage?chat_id=-42", map[string]interface{}{
+			"chat_id": "-42", "text": "dero-stratum-miner on rig1: disconnected from the pool for 1m0s",
+		}, "/bot123:abc/sendMessage"},
+	} {
+		t.Run(tc.format, func(t *testing.T) {
+			n := newTestNotifier(t, &config.Notify{Webhooks: []config.Webhook{{URL: s.URL + tc.url, Format: tc.format}}})
+			notification := testNotification(EventDisconnected)
+			notification.Time = testutil.Epoch
+			assert.True(t, n.Notify(notification))
+			r := s.next(t)
+			assert.Equal(t, tc.path, r.path)
+			assert.Equal(t, tc.want, r.body)
+		})
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	for url, format := range map[string]string{
+		"https://discord.com/api/webhooks/1/token":                  FormatDiscord,
+		"https://hooks.slack.com/services/T/B/X":                    FormatSlack,
+		"https://api.telegram.org/bot123:abc/sendMessage?chat_id=1": FormatTelegram,
+		"https://example.com/hook":                                  FormatGeneric,
+	} {
+		w, err := newWebhook(config.Webhook{URL: url})
+		require.NoError(t, err)
+		assert.Equal(t, format, w.format, url)
+	}
+}
+
+func TestNewNotifierErrors(t *testing.T) {
+	for _, tc := range []struct {
+		webhook config.Webhook
+		err     error
+	}{
+		{config.Webhook{URL: "https://api.telegram.org/bot123:abc/sendMessage"}, ErrNoChatID},
+		{config.Webhook{URL: "https://example.com/hook", Format: "teams"}, ErrUnknownFormat},
+		{config.Webhook{URL: "https://example.com/hook", Events: []string{"exploded"}}, ErrUnknownEvent},
+	} {
+		_, err := NewNotifier(context.Background(), &config.Notify{Webhooks: []config.Webhook{tc.webhook}}, logr.Discard())
+		assert.ErrorIs(t, err, tc.err)
+		assert.NotContains(t, err.Error(), "example.com")
+	}
+}
+
+func TestRetry(t *testing.T) {
+	s := newWebhookServer(t, http.StatusInternalServerError, http.StatusBadGateway)
+	n := newTestNotifier(t, &config.Notify{Webhooks: []config.Webhook{{URL: s.URL}}, Retries: 3})
+	assert.NoError(t, n.send(n.webhooks[0], testNotification(EventStarted)))
+	for i := 0; i < 3; i++ {
+		s.next(t)
+	}
+	s.none(t)
+}
+
+func TestRetryGiveUp(t *testing.T) {
+	s := newWebhookServer(t, http.StatusInternalServerError, http.StatusInternalServerError)
+	n := newTestNotifier(t, &config.Notify{Webhooks: []config.Webhook{{URL: s.URL}}, Retries: 1})
+	assert.ErrorContains(t, n.send(n.webhooks[0], testNotification(EventStarted)), "500")
+	s.next(t)
+	s.next(t)
+	s.none(t)
+}
+
+func TestNoRetryOnClientError(t *testing.T) {
+	s := newWebhookServer(t, http.StatusBadRequest)
+	n := newTestNotifier(t, &config.Notify{Webhooks: []config.Webhook{{URL: s.URL}}, Retries: 3})
+	assert.ErrorContains(t, n.send(n.webhooks[0], testNotification(EventStarted)), "400")
+	s.next(t)
+	s.none(t)
+}
+
+func TestCooldown(t *testing.T) {
+	s := newWebhookServer(t)
+	n := newTestNotifier(t, &config.Notify{Webhooks: []config.Webhook{{URL: s.URL}}, Cooldown: config.Duration(time.Minute)})
+	clk := testutil.NewClock()
+	n.now = clk.Now
+
+	assert.True(t, n.Notify(testNotification(EventDisconnected)))
+	s.next(t)
+	assert.False(t, n.Notify(testNotification(EventDisconnected)))
+	// other events have their own cooldown
+	assert.True(t, n.Notify(testNotification(EventRejectStreak)))
+	s.next(t)
+
+	clk.Add(time.Minute)
+	assert.True(t, n.Notify(testNotification(EventDisconnected)))
+	s.next(t)
+	s.none(t)
+}
+
+func TestEventFilter(t *testing.T) {
+	all, filtered := newWebhookServer(t), newWebhookServer(t)
+	n := newTestNotifier(t, &config.Notify{Webhooks: []config.Webhook{
+		{URL: all.URL},
+		{URL: filtered.URL, Events: []string{string(EventDisconnected)}},
+	}})
+
+	n.Notify(testNotification(EventStarted))
+	assert.Equal(t, "started", all.next(t).body["event"])
+	filtered.none(t)
+
+	n.Notify(testNotification(EventDisconnected))
+	assert.Equal(t, "disconnected", all.next(t).body["event"])
+	assert.Equal(t, "disconnected", filtered.next(t).body["event"])
+}
@@ -0,0 +1,203 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jon4hz/hashconv"
+	"github.com/whalesburg/dero-stratum-miner/internal/config"
+)
+
+// Miner is the state of the miner the watcher looks at.
+type Miner interface {
+	IsConnected() bool
+	IsPaused() bool
+	GetReconnects() int
+	GetAcceptedShares() uint64
+	GetRejectedShares() uint64
+	GetHashrateAverage(window time.Duration) uint64
+	GetPoolURL() string
+}
+
+type notifier interface {
+	Notify(n *Notification) bool
+}
+
+// Watcher checks the miner every second and sends a notification if something goes wrong.
+// Every check can be switched off by setting its threshold to 0.
+type Watcher struct {
+	m    Miner
+	n    notifier
+	cfg  config.Notify
+	host string
+	now  func() time.Time
+
+	disconnectedSince time.Time
+	disconnectAlerted bool
+
+	lastReconnects int
+	reconnects     []time.Time
+
+	lastAccepted  uint64
+	lastRejected  uint64
+	rejectStreak  uint64
+	streakAlerted bool
+
+	notMiningSince   time.Time
+	notMiningAlerted bool
+
+	dropAlerted bool
+}
+
+// NewWatcher creates a watcher which sends its notifications with n.
+func NewWatcher(m Miner, n *Notifier, cfg *config.Notify) *Watcher {
+	return newWatcher(m, n, cfg)
+}
+
+func newWatcher(m Miner, n notifier, cfg *config.Notify) *Watcher {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown host"
+	}
+	return &Watcher{
+		m:              m,
+		n:              n,
+		cfg:            *cfg,
+		host:           host,
+		now:            time.Now,
+		lastReconnects: m.GetReconnects(),
+		lastAccepted:   m.GetAcceptedShares(),
+		lastRejected:   m.GetRejectedShares(),
+	}
+}
+
+// Run announces the start of the miner and watches it until the context is cancelled.
+func (w *Watcher) Run(ctx context.Context) {
+	w.notify(EventStarted, "miner started")
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.check()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (w *Watcher) check() {
+	now := w.now()
+	w.checkConnection(now)
+	w.checkReconnects(now)
+	w.checkRejects()
+	w.checkMining(now)
+	w.checkHashrate()
+}
+
+func (w *Watcher) notify(e Event, msg string) {
+	w.n.Notify(&Notification{
+		Event:    e,
+		Message:  msg,
+		Host:     w.host,
+		Pool:     w.m.GetPoolURL(),
+		Hashrate: w.m.GetHashrateAverage(time.Minute),
+		Time:     w.now(),
+	})
+}
+
+func (w *Watcher) checkConnection(now time.Time) {
+	if w.m.IsConnected() {
+		if w.disconnectAlerted {
+			w.notify(EventReconnected, fmt.Sprintf("connected again after %s", now.Sub(w.disconnectedSince).Truncate(time.Second)))
+		}
+		w.disconnectedSince = time.Time{}
+		w.disconnectAlerted = false
+		return
+	}
+	if w.disconnectedSince.IsZero() {
+		w.disconnectedSince = now
+	}
+	if w.cfg.DisconnectAfter > 0 && !w.disconnectAlerted && now.Sub(w.disconnectedSince) >= time.Duration(w.cfg.DisconnectAfter) {
+		w.notify(EventDisconnected, fmt.Sprintf("disconnected from the pool for %s", now.Sub(w.disconnectedSince).Truncate(time.Second)))
+		w.disconnectAlerted = true
+	}
+}
+
+func (w *Watcher) checkReconnects(now time.Time) {
+	r := w.m.GetReconnects()
+	for i := w.lastReconnects; i < r; i++ {
+		w.reconnects = append(w.reconnects, now)
+	}
+	w.lastReconnects = r
+	if w.cfg.ReconnectStorm <= 0 {
+		w.reconnects = nil
+		return
+	}
+
+	window := time.Duration(w.cfg.ReconnectWindow)
+	for len(w.reconnects) > 0 && now.Sub(w.reconnects[0]) > window {
+		w.reconnects = w.reconnects[1:]
+	}
+	if len(w.reconnects) >= w.cfg.ReconnectStorm {
+		w.notify(EventReconnectStorm, fmt.Sprintf("%d reconnects within %s", len(w.reconnects), window))
+		w.reconnects = nil
+	}
+}
+
+func (w *Watcher) checkRejects() {
+	accepted, rejected := w.m.GetAcceptedShares(), w.m.GetRejectedShares()
+	if accepted != w.lastAccepted {
+		w.rejectStreak = 0
+		w.streakAlerted = false
+	}
+	if rejected > w.lastRejected {
+		w.rejectStreak += rejected - w.lastRejected
+	}
+	w.lastAccepted, w.lastRejected = accepted, rejected
+
+	if w.cfg.RejectStreak > 0 && !w.streakAlerted && w.rejectStreak >= uint64(w.cfg.RejectStreak) {
+		w.notify(EventRejectStreak, fmt.Sprintf("%d shares rejected in a row", w.rejectStreak))
+		w.streakAlerted = true
+	}
+}
+
+// checkMining detects a miner which is connected but doesn't hash.
+func (w *Watcher) checkMining(now time.Time) {
+	if !w.m.IsConnected() || w.m.IsPaused() || w.m.GetHashrateAverage(time.Second*10) > 0 {
+		w.notMiningSince = time.Time{}
+		w.notMiningAlerted = false
+		return
+	}
+	if w.notMiningSince.IsZero() {
+		w.notMiningSince = now
+	}
+	if w.cfg.NotMiningAfter > 0 && !w.notMiningAlerted && now.Sub(w.notMiningSince) >= time.Duration(w.cfg.NotMiningAfter) {
+		w.notify(EventNotMining, fmt.Sprintf("not mining for %s", now.Sub(w.notMiningSince).Truncate(time.Second)))
+		w.notMiningAlerted = true
+	}
+}
+
+// checkHashrate compares the hashrate of the last minute to the 15 minute baseline.
+func (w *Watcher) checkHashrate() {
+	if w.cfg.HashrateDrop <= 0 || !w.m.IsConnected() || w.m.IsPaused() {
+		return
+	}
+	baseline := w.m.GetHashrateAverage(time.Minute * 15)
+	current := w.m.GetHashrateAverage(time.Minute)
+	if baseline == 0 {
+		return
+	}
+	limit := baseline * uint64(100-w.cfg.HashrateDrop) / 100
+	if current >= limit {
+		w.dropAlerted = false
+		return
+	}
+	if !w.dropAlerted {
+		w.notify(EventHashrateDrop, fmt.Sprintf("hashrate dropped to %s/s, %d%% below the baseline of %s/s",
+			hashconv.Format(int64(current)), 100-current*100/baseline, hashconv.Format(int64(baseline))))
+		w.dropAlerted = true
+	}
+}
@@ -0,0 +1,153 @@
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalesburg/dero-stratum-miner/internal/config"
+	"github.com/whalesburg/dero-stratum-miner/internal/minertest"
+	"github.com/whalesburg/dero-stratum-miner/internal/testutil"
+)
+
+type fakeNotifier struct {
+	notifications []*Notification
+}
+
+func (n *fakeNotifier) Notify(notification *Notification) bool {
+	n.notifications = append(n.notifications, notification)
+	return true
+}
+
+func (n *fakeNotifier) take() []Event {
+	var events []Event
+	for _, notification := range n.notifications {
+		events = append(events, notification.Event)
+	}
+	n.notifications = nil
+	return events
+}
+
+func newTestWatcher(m *minertest.Miner, cfg config.Notify) (*Watcher, *fakeNotifier, *testutil.Clock) {
+	n := &fakeNotifier{}
+	w := newWatcher(m, n, &cfg)
+	clk := testutil.NewClock()
+	w.now = clk.Now
+	return w, n, clk
+}
+
+func TestWatchDisconnect(t *testing.T) {
+	m := minertest.New()
+	m.Hashrate = 1000
+	w, n, clk := newTestWatcher(m, config.Notify{DisconnectAfter: config.Duration(time.Minute)})
+
+	m.Connected = false
+	w.check()
+	clk.Add(time.Second * 59)
+	w.check()
+	assert.Empty(t, n.take())
+
+	clk.Add(time.Second)
+	w.check()
+	clk.Add(time.Second)
+	w.check()
+	assert.Equal(t, []Event{EventDisconnected}, n.take())
+
+	m.Connected = true
+	w.check()
+	require.Len(t, n.notifications, 1)
+	assert.Equal(t, EventReconnected, n.notifications[0].Event)
+	assert.Equal(t, "connected again after 1m1s", n.notifications[0].Message)
+	n.take()
+
+	// short disconnects are ignored
+	m.Connected = false
+	w.check()
+	m.Connected = true
+	w.check()
+	assert.Empty(t, n.take())
+}
+
+func TestWatchReconnectStorm(t *testing.T) {
+	m := minertest.New()
+	m.Hashrate = 1000
+	w, n, clk := newTestWatcher(m, config.Notify{ReconnectStorm: 3, ReconnectWindow: config.Duration(time.Minute)})
+
+	m.Reconnects = 2
+	w.check()
+	// the first reconnects are outside of the window
+	clk.Add(time.Minute * 2)
+	m.Reconnects = 3
+	w.check()
+	assert.Empty(t, n.take())
+
+	m.Reconnects = 5
+	w.check()
+	require.Len(t, n.notifications, 1)
+	assert.Equal(t, EventReconnectStorm, n.notifications[0].Event)
+	assert.Equal(t, "3 reconnects within 1m0s", n.notifications[0].Message)
+}
+
+func TestWatchRejectStreak(t *testing.T) {
+	m := minertest.New()
+	m.Hashrate = 1000
+	m.Accepted = 10
+	w, n, _ := newTestWatcher(m, config.Notify{RejectStreak: 3})
+
+	m.Rejected = 2
+	w.check()
+	// an accepted share breaks the streak
+	m.Accepted++
+	m.Rejected = 3
+	w.check()
+	assert.Empty(t, n.take())
+
+	m.Rejected = 5
+	w.check()
+	m.Rejected = 6
+	w.check()
+	assert.Equal(t, []Event{EventRejectStreak}, n.take())
+}
+
+func TestWatchNotMining(t *testing.T) {
+	m := minertest.New()
+	w, n, clk := newTestWatcher(m, config.Notify{NotMiningAfter: config.Duration(time.Minute)})
+
+	w.check()
+	clk.Add(time.Minute)
+	w.check()
+	w.check()
+	assert.Equal(t, []Event{EventNotMining}, n.take())
+
+	// a paused miner doesn't hash on purpose
+	m.Paused = true
+	w.check()
+	clk.Add(time.Minute * 2)
+	w.check()
+	assert.Empty(t, n.take())
+}
+
+func TestWatchHashrateDrop(t *testing.T) {
+	m := minertest.New()
+	m.Hashrate = 900
+	m.Baseline = 1000
+	w, n, _ := newTestWatcher(m, config.Notify{HashrateDrop: 50})
+
+	w.check()
+	assert.Empty(t, n.take())
+
+	m.Hashrate = 400
+	w.check()
+	w.check()
+	require.Len(t, n.notifications, 1)
+	assert.Equal(t, EventHashrateDrop, n.notifications[0].Event)
+	assert.Equal(t, "hashrate dropped to 400 H/s, 60% below the baseline of 1 kH/s", n.notifications[0].Message)
+	n.take()
+
+	m.Hashrate = 1000
+	w.check()
+	m.Hashrate = 400
+	w.check()
+	assert.Equal(t, []Event{EventHashrateDrop}, n.take())
+}
@@ -0,0 +1,59 @@
+// Package raveos integrates the miner as custom miner into RaveOS.
+package raveos
+
+import (
+	"github.com/whalesburg/dero-stratum-miner/internal/api"
+	"github.com/whalesburg/dero-stratum-miner/internal/sensors"
+)
+
+// Algo is the name of the algorithm in RaveOS.
+const Algo = "astrobwt"
+
+// Stats is the JSON the RaveOS stats script reports. Every mining thread is a device.
+type Stats struct {
+	Name     string   `json:"name"`
+	Version  string   `json:"version"`
+	Algo     string   `json:"algo"`
+	Uptime   int64    `json:"uptime"`
+	Hashrate uint64   `json:"hashrate"` // in H/s
+	Shares   Shares   `json:"shares"`
+	Devices  []Device `json:"devices"`
+	Temps    []int    `json:"temps"` // of the CPU packages
+	Fans     []int    `json:"fans"`  // in percent
+	Pool     string   `json:"pool"`
+}
+
+type Shares struct {
+	Accepted uint64 `json:"accepted"`
+	Rejected uint64 `json:"rejected"`
+	Invalid  uint64 `json:"invalid"`
+}
+
+type Device struct {
+	ID       int    `json:"id"`
+	Hashrate uint64 `json:"hashrate"`
+}
+
+// NewStats converts the stats of the miner.
+func NewStats(s *api.Stats, temps []float64, fans []int) *Stats {
+	stats := &Stats{
+		Name:     "dero-stratum-miner",
+		Version:  s.Version,
+		Algo:     Algo,
+		Uptime:   s.Uptime,
+		Hashrate: s.Hashrate,
+		Shares: Shares{
+			Accepted: s.Accepted,
+			Rejected: s.Late(),
+			Invalid:  s.Invalid,
+		},
+		Devices: make([]Device, len(s.Threads)),
+		Temps:   sensors.Celsius(temps),
+		Fans:    append(make([]int, 0, len(fans)), fans...),
+		Pool:    s.Pool,
+	}
+	for i, h := range s.Threads {
+		stats.Devices[i] = Device{ID: i, Hashrate: h}
+	}
+	return stats
+}
@@ -0,0 +1,316 @@
+// Package remote polls the config of the miner from a central https endpoint.
+package remote
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/whalesburg/dero-stratum-miner/internal/config"
+)
+
+// SignatureHeader is the response header which carries the base64 encoded signature of the body.
+const SignatureHeader = "X-Config-Signature"
+
+// maxSize limits the size of a config.
+const maxSize = 1 << 20
+
+// minerKeys are the keys of the miner section a remote config can set. The other settings,
+// like the API tokens or the log file, stay under the control of the local config.
+var minerKeys = map[string]bool{"wallet": true, "pool": true, "pools": true, "threads": true}
+
+var (
+	ErrInsecureURL       = errors.New("remote config: the url must use https")
+	ErrForbiddenKey      = errors.New("remote config: only the wallet, the pools and the threads of the miner can be set")
+	ErrMissingSignature  = errors.New("remote config: missing signature")
+	ErrInvalidSignature  = errors.New("remote config: invalid signature")
+	ErrInvalidPublicKey  = errors.New("remote config: invalid Ed25519 public key")
+	ErrNoCache           = errors.New("remote config: no cached copy")
+	ErrUnexpectedStatus  = errors.New("remote config: unexpected status")
+	ErrMultipleVerifiers = errors.New("remote config: use either an HMAC key or a public key")
+)
+
+// cached is the last known good copy of the config on disk. The config is stored as it was received,
+// the signature wouldn't match a reformatted copy.
+type cached struct {
+	ETag      string `json:"etag"`
+	Signature string `json:"signature"`
+	Config    []byte `json:"config"`
+}
+
+// Source is a config which is polled from an https endpoint. A new config is only accepted if it only sets
+// allowed keys, its signature is valid and it passes the validate function, the last accepted config is cached on disk.
+type Source struct {
+	url      string
+	interval time.Duration
+	cache    string
+	verify   func(body []byte, signature string) error
+	validate func(data []byte) error
+	client   *http.Client
+
+	mu   sync.RWMutex
+	etag string
+	data []byte
+}
+
+// New creates a source from cfg. validate checks a new config before it's accepted.
+func New(cfg *config.Remote, validate func(data []byte) error) (*Source, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("remote config: %w", err)
+	}
+	s := &Source{
+		url:      cfg.URL,
+		interval: time.Duration(cfg.Interval),
+		cache:    cfg.Cache,
+		validate: validate,
+		client:   &http.Client{Timeout: time.Second * 30},
+	}
+	// even a signed config could be replayed by a man in the middle once it's outdated
+	if u.Scheme != "https" {
+		return nil, ErrInsecureURL
+	}
+	switch {
+	case cfg.HMACKey != "" && cfg.PublicKey != "":
+		return nil, ErrMultipleVerifiers
+	case cfg.HMACKey != "":
+		s.verify = verifyHMAC([]byte(cfg.HMACKey))
+	case cfg.PublicKey != "":
+		key, err := base64.StdEncoding.DecodeString(cfg.PublicKey)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, ErrInvalidPublicKey
+		}
+		s.verify = verifyEd25519(key)
+	}
+	return s, nil
+}
+
+// DefaultCache returns the default path of the cached config. systemd sets $CACHE_DIRECTORY
+// for units with CacheDirectory=.
+func DefaultCache() string {
+	dir := os.Getenv("CACHE_DIRECTORY")
+	if dir == "" {
+		userDir, err := os.UserCacheDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(userDir, "dero-stratum-miner")
+	}
+	return filepath.Join(dir, "remote-config.json")
+}
+
+func verifyHMAC(key []byte) func([]byte, string) error {
+	return func(body []byte, signature string) error {
+		sig, err := decodeSignature(signature)
+		if err != nil {
+			return err
+		}
+		mac := hmac.New(sha256.New, key)
+		mac.Write(body) // nolint: errcheck
+		if !hmac.Equal(mac.Sum(nil), sig) {
+			return ErrInvalidSignature
+		}
+		return nil
+	}
+}
+
+func verifyEd25519(key ed25519.PublicKey) func([]byte, string) error {
+	return func(body []byte, signature string) error {
+		sig, err := decodeSignature(signature)
+		if err != nil {
+			return err
+		}
+		if !ed25519.Verify(key, body, sig) {
+			return ErrInvalidSignature
+		}
+		return nil
+	}
+}
+
+func decodeSignature(signature string) ([]byte, error) {
+	if signature == "" {
+		return nil, ErrMissingSignature
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	return sig, nil
+}
+
+// Config returns the last accepted config or nil if there is none.
+func (s *Source) Config() []byte {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.data
+}
+
+// Fetch polls the config once and reports whether a new config was accepted.
+// The ETag of the last config is sent, so unchanged configs aren't transferred again.
+func (s *Source) Fetch(ctx context.Context) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "application/json")
+	s.mu.RLock()
+	if s.etag != "" {
+		req.Header.Set("If-None-Match", s.etag)
+	}
+	s.mu.RUnlock()
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return false, nil
+	default:
+		return false, fmt.Errorf("%w: %s", ErrUnexpectedStatus, res.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxSize))
+	if err != nil {
+		return false, err
+	}
+	c := &cached{
+		ETag:      res.Header.Get("ETag"),
+		Signature: res.Header.Get(SignatureHeader),
+		Config:    body,
+	}
+	if err := s.accept(c); err != nil {
+		return false, err
+	}
+	changed := !bytes.Equal(s.Config(), body)
+	s.set(c)
+	return changed, s.writeCache(c)
+}
+
+// LoadCache accepts the cached copy of the config. The signature is verified again,
+// since the file could have been changed.
+func (s *Source) LoadCache() error {
+	if s.cache == "" {
+		return ErrNoCache
+	}
+	data, err := os.ReadFile(s.cache)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNoCache
+	}
+	if err != nil {
+		return err
+	}
+	var c cached
+	if err := json.Unmarshal(data, &c); err != nil {
+		return fmt.Errorf("remote config: invalid cache: %w", err)
+	}
+	if err := s.accept(&c); err != nil {
+		return err
+	}
+	s.set(&c)
+	return nil
+}
+
+func (s *Source) accept(c *cached) error {
+	if s.verify != nil {
+		if err := s.verify(c.Config, c.Signature); err != nil {
+			return err
+		}
+	}
+	if err := checkKeys(c.Config); err != nil {
+		return err
+	}
+	if s.validate != nil {
+		return s.validate(c.Config)
+	}
+	return nil
+}
+
+// checkKeys rejects configs with keys other than the allowed keys of the miner section.
+// The keys must match exactly, json.Unmarshal would also accept them in another case.
+func checkKeys(data []byte) error {
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(data, &sections); err != nil {
+		return fmt.Errorf("remote config: %w", err)
+	}
+	for name, section := range sections {
+		if name != "miner" {
+			return fmt.Errorf("%w: %s", ErrForbiddenKey, name)
+		}
+		var keys map[string]json.RawMessage
+		if err := json.Unmarshal(section, &keys); err != nil || keys == nil {
+			return fmt.Errorf("%w: miner must be an object", ErrForbiddenKey)
+		}
+		for key := range keys {
+			if !minerKeys[key] {
+				return fmt.Errorf("%w: miner.%s", ErrForbiddenKey, key)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Source) set(c *cached) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.etag = c.ETag
+	s.data = c.Config
+}
+
+// writeCache replaces the cache atomically, so a crash can't leave a partial copy behind.
+func (s *Source) writeCache(c *cached) error {
+	if s.cache == "" {
+		return nil
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.cache), 0o700); err != nil {
+		return err
+	}
+	tmp := s.cache + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.cache)
+}
+
+// Run polls the config until the context is cancelled and calls onChange after a new config was accepted.
+// Errors are logged, the last accepted config stays in use.
+func (s *Source) Run(ctx context.Context, logger logr.Logger, onChange func()) {
+	logger = logger.WithName("remote")
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			changed, err := s.Fetch(ctx)
+			if err != nil {
+				logger.Error(err, "Failed to update the remote config", "url", s.url)
+			}
+			// a config which couldn't be cached is still applied
+			if changed {
+				logger.Info("Remote config changed", "url", s.url)
+				onChange()
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package remote
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalesburg/dero-stratum-miner/internal/config"
+)
+
+// configServer serves a config with an ETag and counts the requests which were answered with 304.
+type configServer struct {
+	mu          sync.Mutex
+	body        string
+	etag        string
+	signature   string
+	notModified int
+	fail        bool
+}
+
+func (s *configServer) set(body, etag, signature string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.body, s.etag, s.signature = body, etag, signature
+}
+
+func (s *configServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if r.Header.Get("If-None-Match") == s.etag {
+		s.notModified++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", s.etag)
+	if s.signature != "" {
+		w.Header().Set(SignatureHeader, s.signature)
+	}
+	w.Write([]byte(s.body)) // nolint: errcheck
+}
+
+func signHMAC(key, body string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(body)) // nolint: errcheck
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestFetchETag(t *testing.T) {
+	cs := &configServer{}
+	cs.set(`{"miner": {"threads": 2}}`, `"v1"`, "")
+	srv := httptest.NewTLSServer(cs)
+	defer srv.Close()
+
+	s, err := New(&config.Remote{URL: srv.URL, Cache: filepath.Join(t.TempDir(), "cache.json")}, nil)
+	require.NoError(t, err)
+	s.client = srv.Client()
+	ctx := context.Background()
+
+	changed, err := s.Fetch(ctx)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, `{"miner": {"threads": 2}}`, string(s.Config()))
+
+	changed, err = s.Fetch(ctx)
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, 1, cs.notModified)
+
+	// a new ETag with the same content isn't a change
+	cs.set(`{"miner": {"threads": 2}}`, `"v2"`, "")
+	changed, err = s.Fetch(ctx)
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	cs.set(`{"miner": {"threads": 4}}`, `"v3"`, "")
+	changed, err = s.Fetch(ctx)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, `{"miner": {"threads": 4}}`, string(s.Config()))
+}
+
+func TestFetchHMAC(t *testing.T) {
+	const body = `{"miner": {"threads": 2}}`
+	cs := &configServer{}
+	srv := httptest.NewTLSServer(cs)
+	defer srv.Close()
+
+	s, err := New(&config.Remote{URL: srv.URL, HMACKey: "secret"}, nil)
+	require.NoError(t, err)
+	s.client = srv.Client()
+	ctx := context.Background()
+
+	cs.set(body, `"v1"`, "")
+	_, err = s.Fetch(ctx)
+	assert.ErrorIs(t, err, ErrMissingSignature)
+
+	cs.set(body, `"v2"`, signHMAC("wrong", body))
+	_, err = s.Fetch(ctx)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	assert.Nil(t, s.Config())
+
+	cs.set(body, `"v3"`, signHMAC("secret", body))
+	changed, err := s.Fetch(ctx)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, body, string(s.Config()))
+}
+
+func TestFetchEd25519(t *testing.T) {
+	const body = `{"miner": {"threads": 2}}`
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	cs := &configServer{}
+	srv := httptest.NewTLSServer(cs)
+	defer srv.Close()
+
+	s, err := New(&config.Remote{URL: srv.URL, PublicKey: base64.StdEncoding.EncodeToString(pub)}, nil)
+	require.NoError(t, err)
+	s.client = srv.Client()
+	ctx := context.Background()
+
+	cs.set(body, `"v1"`, base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte(`{"miner": {"threads": 64}}`))))
+	_, err = s.Fetch(ctx)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	cs.set(body, `"v2"`, base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte(body))))
+	changed, err := s.Fetch(ctx)
+	require.NoError(t, err)
+	assert.True(t, changed)
+}
+
+func TestValidate(t *testing.T) {
+	errInvalid := errors.New("invalid wallet")
+	cs := &configServer{}
+	srv := httptest.NewTLSServer(cs)
+	defer srv.Close()
+
+	s, err := New(&config.Remote{URL: srv.URL}, func(data []byte) error {
+		if string(data) == `{"miner": {"wallet": "nope"}}` {
+			return errInvalid
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	s.client = srv.Client()
+	ctx := context.Background()
+
+	cs.set(`{"miner": {"threads": 2}}`, `"v1"`, "")
+	_, err = s.Fetch(ctx)
+	require.NoError(t, err)
+
+	// the last good config stays in use
+	cs.set(`{"miner": {"wallet": "nope"}}`, `"v2"`, "")
+	_, err = s.Fetch(ctx)
+	assert.ErrorIs(t, err, errInvalid)
+	assert.Equal(t, `{"miner": {"threads": 2}}`, string(s.Config()))
+}
+
+func TestCheckKeys(t *testing.T) {
+	for _, tt := range []struct {
+		body string
+		err  error
+	}{
+		{body: `{"miner": {"wallet": "dero1abc", "pool": "pool:10300", "pools": ["backup:10300"], "threads": 4}}`},
+		{body: `{}`},
+		{body: `{"api": {"admin_token": "mine"}}`, err: ErrForbiddenKey},
+		{body: `{"miner": {"threads": 4, "dns": "1.1.1.1:53"}}`, err: ErrForbiddenKey},
+		// json.Unmarshal matches the keys case insensitive
+		{body: `{"miner": {"Ignore_TLS_Validation": true}}`, err: ErrForbiddenKey},
+		{body: `{"Logger": {"file": "/etc/passwd"}}`, err: ErrForbiddenKey},
+		{body: `{"miner": null}`, err: ErrForbiddenKey},
+	} {
+		err := checkKeys([]byte(tt.body))
+		if tt.err == nil {
+			assert.NoError(t, err, tt.body)
+		} else {
+			assert.ErrorIs(t, err, tt.err, tt.body)
+		}
+	}
+	assert.Error(t, checkKeys([]byte(`[]`)))
+}
+
+func TestCache(t *testing.T) {
+	const body = "{\n  \"miner\": {\"threads\": 2}\n}\n"
+	cs := &configServer{}
+	cs.set(body, `"v1"`, signHMAC("secret", body))
+	srv := httptest.NewTLSServer(cs)
+	defer srv.Close()
+	cfg := &config.Remote{URL: srv.URL, HMACKey: "secret", Cache: filepath.Join(t.TempDir(), "remote", "cache.json")}
+	ctx := context.Background()
+
+	s, err := New(cfg, nil)
+	require.NoError(t, err)
+	s.client = srv.Client()
+	assert.ErrorIs(t, s.LoadCache(), ErrNoCache)
+	_, err = s.Fetch(ctx)
+	require.NoError(t, err)
+
+	// a restarted miner falls back to the cache if the endpoint fails
+	cs.fail = true
+	s, err = New(cfg, nil)
+	require.NoError(t, err)
+	s.client = srv.Client()
+	_, err = s.Fetch(ctx)
+	assert.ErrorIs(t, err, ErrUnexpectedStatus)
+	require.NoError(t, s.LoadCache())
+	assert.Equal(t, body, string(s.Config()))
+
+	// the ETag of the cache is sent
+	cs.fail = false
+	changed, err := s.Fetch(ctx)
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, 1, cs.notModified)
+
+	// the cache is verified with the current key
+	cfg.HMACKey = "rotated"
+	s, err = New(cfg, nil)
+	require.NoError(t, err)
+	assert.ErrorIs(t, s.LoadCache(), ErrInvalidSignature)
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(&config.Remote{URL: "http://config.example.com/rig1.json"}, nil)
+	assert.ErrorIs(t, err, ErrInsecureURL)
+	_, err = New(&config.Remote{URL: "ftp://config.example.com/rig1.json", HMACKey: "secret"}, nil)
+	assert.ErrorIs(t, err, ErrInsecureURL)
+	_, err = New(&config.Remote{URL: "https://config.example.com/rig1.json", HMACKey: "secret", PublicKey: "key"}, nil)
+	assert.ErrorIs(t, err, ErrMultipleVerifiers)
+	_, err = New(&config.Remote{URL: "https://config.example.com/rig1.json", PublicKey: "c2hvcnQ="}, nil)
+	assert.ErrorIs(t, err, ErrInvalidPublicKey)
+	// signed configs could be replayed over http
+	_, err = New(&config.Remote{URL: "http://config.example.com/rig1.json", HMACKey: "secret"}, nil)
+	assert.ErrorIs(t, err, ErrInsecureURL)
+	_, err = New(&config.Remote{URL: "https://config.example.com/rig1.json", HMACKey: "secret"}, nil)
+	assert.NoError(t, err)
+}
+
+func TestRun(t *testing.T) {
+	cs := &configServer{}
+	cs.set(`{"miner": {"threads": 2}}`, `"v1"`, "")
+	srv := httptest.NewTLSServer(cs)
+	defer srv.Close()
+
+	s, err := New(&config.Remote{URL: srv.URL, Interval: config.Duration(time.Millisecond * 10)}, nil)
+	require.NoError(t, err)
+	s.client = srv.Client()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan struct{}, 10)
+	go s.Run(ctx, logr.Discard(), func() { changes <- struct{}{} })
+	select {
+	case <-changes:
+	case <-time.After(time.Second * 5):
+		t.Fatal("config wasn't fetched")
+	}
+	assert.Equal(t, `{"miner": {"threads": 2}}`, string(s.Config()))
+}
@@ -0,0 +1,154 @@
+// Package sensors reads the CPU temperatures and fan speeds from sysfs.
+package sensors
+
+import (
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// cpuChips are the hwmon drivers of CPU temperature sensors.
+var cpuChips = map[string]bool{
+	"coretemp":    true, // intel
+	"k10temp":     true, // amd
+	"zenpower":    true, // amd
+	"cpu_thermal": true, // arm, e.g. raspberry pi
+	"soc_thermal": true,
+}
+
+// cpuZones are the thermal zones which are used if no hwmon sensor is found.
+var cpuZones = map[string]bool{
+	"x86_pkg_temp": true,
+	"cpu-thermal":  true,
+	"cpu_thermal":  true,
+	"soc_thermal":  true,
+}
+
+// Sensors reads the sensors of a sysfs tree.
+type Sensors struct {
+	fsys fs.FS
+}
+
+// New reads the sensors of the system.
+func New() *Sensors {
+	return &Sensors{fsys: os.DirFS("/sys")}
+}
+
+// NewFS reads the sensors of a sysfs tree, it's used in tests.
+func NewFS(fsys fs.FS) *Sensors {
+	return &Sensors{fsys: fsys}
+}
+
+// Temperatures returns the temperature of every CPU package in °C.
+// Each package reports its package sensor, or the hottest core if there is none.
+func (s *Sensors) Temperatures() []float64 {
+	var temps []float64
+	for _, dir := range s.hwmons() {
+		if !cpuChips[s.read(dir, "name")] {
+			continue
+		}
+		if t, ok := s.packageTemperature(dir); ok {
+			temps = append(temps, t)
+		}
+	}
+	if len(temps) > 0 {
+		return temps
+	}
+
+	zones, _ := fs.Glob(s.fsys, "class/thermal/thermal_zone*")
+	sort.Strings(zones)
+	for _, zone := range zones {
+		if !cpuZones[s.read(zone, "type")] {
+			continue
+		}
+		if milli, err := strconv.ParseInt(s.read(zone, "temp"), 10, 64); err == nil {
+			temps = append(temps, float64(milli)/1000)
+		}
+	}
+	return temps
+}
+
+func (s *Sensors) packageTemperature(dir string) (float64, bool) {
+	inputs, _ := fs.Glob(s.fsys, path.Join(dir, "temp*_input"))
+	sort.Strings(inputs)
+	// "Package id N" is the package sensor of intel cpus, amd cpus report the die temperature
+	// as Tdie and the control value as Tctl, which can have an offset
+	byLabel := make(map[string]float64)
+	var (
+		hottest float64
+		found   bool
+	)
+	for _, input := range inputs {
+		milli, err := strconv.ParseInt(s.read(input), 10, 64)
+		if err != nil {
+			continue
+		}
+		t := float64(milli) / 1000
+		label := s.read(strings.TrimSuffix(input, "_input") + "_label")
+		if strings.HasPrefix(label, "Package id") {
+			label = "Package"
+		}
+		byLabel[label] = t
+		if !found || t > hottest {
+			hottest, found = t, true
+		}
+	}
+	for _, label := range []string{"Package", "Tdie", "Tctl"} {
+		if t, ok := byLabel[label]; ok {
+			return t, true
+		}
+	}
+	return hottest, found
+}
+
+// Fans returns the speed of every PWM controlled fan in percent.
+func (s *Sensors) Fans() []int {
+	var fans []int
+	for _, dir := range s.hwmons() {
+		pwms, _ := fs.Glob(s.fsys, path.Join(dir, "pwm[0-9]"))
+		sort.Strings(pwms)
+		for _, pwm := range pwms {
+			v, err := strconv.Atoi(s.read(pwm))
+			if err != nil {
+				continue
+			}
+			fans = append(fans, v*100/255)
+		}
+	}
+	return fans
+}
+
+func (s *Sensors) hwmons() []string {
+	dirs, _ := fs.Glob(s.fsys, "class/hwmon/hwmon*")
+	sort.Slice(dirs, func(i, j int) bool {
+		return hwmonIndex(dirs[i]) < hwmonIndex(dirs[j])
+	})
+	return dirs
+}
+
+// hwmonIndex sorts hwmon10 after hwmon9.
+func hwmonIndex(dir string) int {
+	n, _ := strconv.Atoi(strings.TrimPrefix(path.Base(dir), "hwmon"))
+	return n
+}
+
+func (s *Sensors) read(elem ...string) string {
+	b, err := fs.ReadFile(s.fsys, path.Join(elem...))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// Celsius rounds the temperatures to whole degrees. The result is never nil, so it's encoded as JSON array.
+func Celsius(temps []float64) []int {
+	c := make([]int, len(temps))
+	for i, t := range temps {
+		c[i] = int(math.Round(t))
+	}
+	return c
+}
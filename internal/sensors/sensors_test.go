@@ -0,0 +1,24 @@
+package sensors
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemperatures(t *testing.T) {
+	for name, want := range map[string][]float64{
+		"intel": {61, 58.5},
+		"amd":   {65.25},
+		"arm":   {48.312},
+		"none":  nil,
+	} {
+		assert.Equal(t, want, NewFS(os.DirFS("testdata/"+name)).Temperatures(), name)
+	}
+}
+
+func TestFans(t *testing.T) {
+	assert.Equal(t, []int{100, 50}, NewFS(os.DirFS("testdata/intel")).Fans())
+	assert.Empty(t, NewFS(os.DirFS("testdata/amd")).Fans())
+}
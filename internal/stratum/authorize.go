@@ -1,8 +1,12 @@
 package stratum
 
 func (c *Client) authorize() error {
+	c.mu.RLock()
+	username := c.username
+	c.mu.RUnlock()
+
 	args := map[string]any{
-		"login": c.username,
+		"login": username,
 		"pass":  c.password,
 		"agent": c.agentName,
 	}
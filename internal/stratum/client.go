@@ -50,12 +50,15 @@ type Client struct {
 	sessionID               string
 	jobBroadcaster          *broadcast.Relay[*Job]
 	respBroadcaster         *broadcast.Relay[*Response]
+	shareBroadcaster        *broadcast.Relay[*ShareResult]
+	connBroadcaster         *broadcast.Relay[bool]
 
 	submittedShares int
 	acceptedShares  int
 	rejectedInARow  int
+	reconnects      int
 
-	submittedJobIds    map[int]struct{}
+	submittedJobIds    map[int]*pendingShare
 	submittedJobsIdsMu sync.Mutex
 	lastSubmittedShare *Share
 
@@ -67,6 +70,7 @@ type Client struct {
 
 	lastMsg      time.Time
 	reconnCancel context.CancelFunc
+	connectedAt  time.Time
 }
 
 type logFnOptions struct {
@@ -85,8 +89,10 @@ func New(url string, opts ...Opts) *Client {
 		reconnectIntervalFactor: 1.5,
 		jobBroadcaster:          broadcast.NewRelay[*Job](),
 		respBroadcaster:         broadcast.NewRelay[*Response](),
+		shareBroadcaster:        broadcast.NewRelay[*ShareResult](),
+		connBroadcaster:         broadcast.NewRelay[bool](),
 		lastSubmittedShare:      &Share{},
-		submittedJobIds:         make(map[int]struct{}),
+		submittedJobIds:         make(map[int]*pendingShare),
 		LogFn: logFnOptions{
 			Debug: func(string) {},
 			Info:  func(string) {},
@@ -116,6 +122,9 @@ func (c *Client) Close(forever bool) {
 	if c.state&(closedState|closedForeverState) > 0 {
 		return
 	}
+	if c.state == connectedState {
+		c.connBroadcaster.Broadcast(false)
+	}
 	if c.conn != nil {
 		if err := c.conn.Close(); err != nil {
 			c.LogFn.Error(err, "connection closing error")
@@ -146,6 +155,18 @@ func (c *Client) NewResponseListener(buff int) *broadcast.Listener[*Response] {
 	return c.respBroadcaster.Listener(buff)
 }
 
+func (c *Client) NewShareListener(buff int) *broadcast.Listener[*ShareResult] {
+	c.LogFn.Debug(fmt.Sprintf("registered share listener, buff: %d", buff))
+	return c.shareBroadcaster.Listener(buff)
+}
+
+// NewConnectionListener returns a listener which receives true once the client is connected and authorized
+// and false if the connection was closed. Notifications are dropped if the listener isn't ready to receive them.
+func (c *Client) NewConnectionListener(buff int) *broadcast.Listener[bool] {
+	c.LogFn.Debug(fmt.Sprintf("registered connection listener, buff: %d", buff))
+	return c.connBroadcaster.Listener(buff)
+}
+
 func (c *Client) Dial() error {
 	if !c.setStateIfNot(connectingState, connectingState|connectedState) {
 		return nil
@@ -177,6 +198,7 @@ func (c *Client) dial(ctx context.Context) error {
 	}
 	if err == nil {
 		c.state = connectedState
+		c.connectedAt = time.Now()
 	} else {
 		c.state = closedState
 	}
@@ -184,12 +206,13 @@ func (c *Client) dial(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	c.LogFn.Info("successfully connected to pool: " + c.url)
+	c.LogFn.Info("successfully connected to pool: " + c.GetURL())
 	c.reader = bufio.NewReader(c.conn)
 
 	if er := c.authorize(); er != nil {
 		return errors.New("authorization error: " + er.Error())
 	}
+	c.connBroadcaster.Broadcast(true)
 	c.handleMessages()
 	return nil
 }
@@ -199,6 +222,10 @@ func (c *Client) reconnect() {
 		return
 	}
 
+	c.mu.Lock()
+	c.reconnects++
+	c.mu.Unlock()
+
 	b := c.makeBackoff()
 	rand.Seed(time.Now().UTC().UnixNano())
 
@@ -330,19 +357,24 @@ func (c *Client) handleMessages() {
 				}
 				id := int(response.ID.(float64))
 
+				var result *ShareResult
 				c.submittedJobsIdsMu.Lock()
-				if _, ok := c.submittedJobIds[id]; ok {
+				if pending, ok := c.submittedJobIds[id]; ok {
+					delete(c.submittedJobIds, id)
+					c.submittedShares++
+					result = &ShareResult{
+						Share:    pending.share,
+						Accepted: !isError,
+						Latency:  time.Since(pending.sent),
+					}
 					if !isError {
 						// This is a response from the server signalling that our work has been accepted
-						delete(c.submittedJobIds, id)
 						c.acceptedShares++
-						c.submittedShares++
 						c.rejectedInARow = 0
-						c.LogFn.Info("accepted share")
+						c.LogFn.Debug("accepted share")
 					} else {
-						delete(c.submittedJobIds, id)
-						c.submittedShares++
-						c.LogFn.Info("rejected share")
+						result.Reason = rejectReason(response)
+						c.LogFn.Debug("rejected share: " + result.Reason)
 						c.checkRejected()
 					}
 				} else {
@@ -350,6 +382,7 @@ func (c *Client) handleMessages() {
 					if ok {
 						s, ok := statusIntf["status"]
 						if !ok {
+							c.submittedJobsIdsMu.Unlock()
 							c.LogFn.Error(errors.New("invalid response"), fmt.Sprintf("failed to parse result: %v", response.Result))
 							continue
 						}
@@ -362,6 +395,9 @@ func (c *Client) handleMessages() {
 				}
 				c.submittedJobsIdsMu.Unlock()
 				c.respBroadcaster.Notify(response)
+				if result != nil {
+					c.shareBroadcaster.Notify(result)
+				}
 
 			default:
 				// this is a notification
@@ -391,6 +427,31 @@ func (c *Client) GetAcceptedShares() int {
 	return c.acceptedShares
 }
 
+// GetReconnects returns how often the client had to reconnect to the pool.
+func (c *Client) GetReconnects() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.reconnects
+}
+
+// GetConnectedSince returns when the current connection to the pool was established.
+// The returned time is zero if the client isn't connected.
+func (c *Client) GetConnectedSince() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.state != connectedState {
+		return time.Time{}
+	}
+	return c.connectedAt
+}
+
+// GetURL returns the address of the pool.
+func (c *Client) GetURL() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.url
+}
+
 func (c *Client) readLine() ([]byte, error) {
 	if !c.IsConnected() {
 		return nil, ErrNotConnected
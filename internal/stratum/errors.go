@@ -31,4 +31,6 @@ func (se *Error) Error() string {
 var (
 	ErrNoSessionID = errors.New("response has no session id")
 	ErrNoJob       = errors.New("reponse has no job")
+	// ErrDuplicateShare is returned if a share for the last submitted job is submitted again, it isn't sent to the pool.
+	ErrDuplicateShare = errors.New("duplicate share")
 )
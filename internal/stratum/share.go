@@ -2,6 +2,7 @@ package stratum
 
 import (
 	"fmt"
+	"time"
 )
 
 type Share struct {
@@ -9,6 +10,23 @@ type Share struct {
 	JobID  string `json:"job_id"`
 	Nonce  string `json:"nonce"`
 	Result string `json:"result"`
+
+	// informational fields which are not sent to the pool
+	Height     float64 `json:"-"`
+	Difficulty uint64  `json:"-"`
+}
+
+// ShareResult is the answer of the pool to a submitted share.
+type ShareResult struct {
+	Share    *Share
+	Accepted bool
+	Reason   string // reject reason, empty if the share was accepted
+	Latency  time.Duration
+}
+
+type pendingShare struct {
+	share *Share
+	sent  time.Time
 }
 
 func NewShare(jobID string, nonce string, result string) *Share {
@@ -23,7 +41,7 @@ func NewShare(jobID string, nonce string, result string) *Share {
 func (c *Client) SubmitShare(s *Share) error {
 	if s.JobID == c.lastSubmittedShare.JobID {
 		c.LogFn.Debug(fmt.Sprintf("duplicate share %s", s.JobID))
-		return nil
+		return ErrDuplicateShare
 	}
 
 	args := make(map[string]interface{})
@@ -41,9 +59,16 @@ func (c *Client) SubmitShare(s *Share) error {
 	}
 	c.submittedJobsIdsMu.Lock()
 	defer c.submittedJobsIdsMu.Unlock()
-	c.submittedJobIds[id] = struct{}{}
+	c.submittedJobIds[id] = &pendingShare{share: s, sent: time.Now()}
 	// Successfully submitted result
 	// TODO: debug logger
 	c.lastSubmittedShare = s
 	return nil
 }
+
+func rejectReason(r *Response) string {
+	if r.Error != nil && r.Error.Message != "" {
+		return r.Error.Message
+	}
+	return "unknown"
+}
@@ -0,0 +1,31 @@
+package stratum
+
+import "strings"
+
+// ParseURL strips the scheme from a stratum url and reports whether TLS should be used.
+func ParseURL(url string) (string, bool) {
+	if strings.HasPrefix(url, "stratum+tls://") || strings.HasPrefix(url, "stratum+ssl://") {
+		url = strings.TrimPrefix(url, "stratum+tls://")
+		url = strings.TrimPrefix(url, "stratum+ssl://")
+		return url, true
+	}
+	url = strings.TrimPrefix(url, "stratum://")
+	url = strings.TrimPrefix(url, "tcp://")
+	url = strings.TrimPrefix(url, "stratum+tcp://")
+	return url, false
+}
+
+// SetURL changes the pool the client connects to. The scheme of the url decides whether TLS is used.
+// The new pool is used on the next (re)connect.
+func (c *Client) SetURL(url string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.url, c.useTLS = ParseURL(url)
+}
+
+// SetUsername changes the username which is used to authorize on the next (re)connect.
+func (c *Client) SetUsername(username string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.username = username
+}
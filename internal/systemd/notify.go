@@ -0,0 +1,55 @@
+package systemd
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoSocket is returned if the miner isn't started by systemd with Type=notify.
+var ErrNoSocket = errors.New("NOTIFY_SOCKET is not set")
+
+// Notifier sends state changes to the service manager with the sd_notify protocol.
+type Notifier struct {
+	addr *net.UnixAddr
+}
+
+// NewNotifier returns a notifier for the socket in $NOTIFY_SOCKET.
+func NewNotifier() (*Notifier, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil, ErrNoSocket
+	}
+	// sockets starting with @ are in the abstract namespace
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+	return &Notifier{addr: &net.UnixAddr{Name: socket, Net: "unixgram"}}, nil
+}
+
+// Notify sends the variable assignments like READY=1 in a single datagram.
+func (n *Notifier) Notify(state ...string) error {
+	conn, err := net.DialUnix(n.addr.Net, nil, n.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(strings.Join(state, "\n") + "\n"))
+	return err
+}
+
+// WatchdogInterval returns the WatchdogSec= of the service or 0 if the watchdog is disabled
+// or meant for another process.
+func WatchdogInterval() time.Duration {
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
@@ -0,0 +1,124 @@
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/jon4hz/hashconv"
+)
+
+const statusInterval = time.Second * 10
+
+// Miner is the state of the miner which is reported to systemd.
+type Miner interface {
+	IsConnected() bool
+	IsPaused() bool
+	GetJobsReceived() int64
+	GetHashes() uint64
+	GetHashrateAverage(window time.Duration) uint64
+	GetAcceptedShares() uint64
+	GetRejectedShares() uint64
+}
+
+type notifier interface {
+	Notify(state ...string) error
+}
+
+// Service reports the state of the miner to systemd. The miner is ready once the first job is received,
+// the status shows the hashrate and the shares. Watchdog pings are only sent while the miner computes
+// hashes, so systemd restarts a stalled miner. While it's paused or can't reach the pool there's nothing
+// to hash and a restart wouldn't help, so the pings continue.
+type Service struct {
+	m        Miner
+	n        notifier
+	watchdog time.Duration
+	logger   logr.Logger
+
+	ready      bool
+	lastStatus time.Time
+	lastPing   time.Time
+	lastHashes uint64
+}
+
+// NewService creates a service which notifies systemd with n. The watchdog is pinged at half of its interval.
+func NewService(m Miner, n *Notifier, watchdog time.Duration, logger logr.Logger) *Service {
+	return newService(m, n, watchdog, logger)
+}
+
+func newService(m Miner, n notifier, watchdog time.Duration, logger logr.Logger) *Service {
+	return &Service{
+		m:        m,
+		n:        n,
+		watchdog: watchdog,
+		logger:   logger.WithName("systemd"),
+	}
+}
+
+// Run notifies systemd until the context is cancelled.
+func (s *Service) Run(ctx context.Context) {
+	interval := time.Second
+	if s.watchdog > 0 && s.watchdog/2 < interval {
+		interval = s.watchdog / 2
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			s.tick(now)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Stopping reports that the miner is shutting down.
+func (s *Service) Stopping() {
+	s.notify("STOPPING=1", "STATUS=Shutting down")
+}
+
+func (s *Service) tick(now time.Time) {
+	var state []string
+	if !s.ready && s.m.GetJobsReceived() > 0 {
+		s.ready = true
+		state = append(state, "READY=1")
+	}
+	if len(state) > 0 || now.Sub(s.lastStatus) >= statusInterval {
+		s.lastStatus = now
+		state = append(state, "STATUS="+s.status())
+	}
+
+	hashes := s.m.GetHashes()
+	progress := hashes != s.lastHashes
+	s.lastHashes = hashes
+	idle := !s.ready || !s.m.IsConnected() || s.m.IsPaused()
+	if s.watchdog > 0 && now.Sub(s.lastPing) >= s.watchdog/2 && (progress || idle) {
+		s.lastPing = now
+		state = append(state, "WATCHDOG=1")
+	}
+
+	if len(state) > 0 {
+		s.notify(state...)
+	}
+}
+
+func (s *Service) status() string {
+	switch {
+	case !s.m.IsConnected():
+		return "Connecting to the pool"
+	case s.m.IsPaused():
+		return "Paused"
+	case !s.ready:
+		return "Waiting for a job"
+	}
+	return fmt.Sprintf("Mining @ %s/s, %d accepted, %d rejected",
+		hashconv.Format(int64(s.m.GetHashrateAverage(time.Minute))), s.m.GetAcceptedShares(), s.m.GetRejectedShares())
+}
+
+func (s *Service) notify(state ...string) {
+	if err := s.n.Notify(state...); err != nil {
+		s.logger.Error(err, "Failed to notify systemd")
+	}
+}
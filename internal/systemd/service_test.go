@@ -0,0 +1,106 @@
+package systemd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whalesburg/dero-stratum-miner/internal/minertest"
+	"github.com/whalesburg/dero-stratum-miner/internal/testutil"
+)
+
+type fakeNotifier struct {
+	sent [][]string
+}
+
+func (n *fakeNotifier) Notify(state ...string) error {
+	n.sent = append(n.sent, state)
+	return nil
+}
+
+func (n *fakeNotifier) take() [][]string {
+	sent := n.sent
+	n.sent = nil
+	return sent
+}
+
+func TestServiceReady(t *testing.T) {
+	m := minertest.NewMining()
+	m.Connected = false
+	n := &fakeNotifier{}
+	s := newService(m, n, 0, logr.Discard())
+	clk := testutil.NewClock()
+
+	s.tick(clk.Now())
+	assert.Equal(t, [][]string{{"STATUS=Connecting to the pool"}}, n.take())
+
+	m.Connected = true
+	clk.Add(time.Second)
+	s.tick(clk.Now())
+	assert.Empty(t, n.take())
+
+	m.Jobs = 1
+	clk.Add(time.Second)
+	s.tick(clk.Now())
+	assert.Equal(t, [][]string{{"READY=1", "STATUS=Mining @ 1 kH/s, 412 accepted, 7 rejected"}}, n.take())
+
+	clk.Add(statusInterval)
+	s.tick(clk.Now())
+	assert.Equal(t, [][]string{{"STATUS=Mining @ 1 kH/s, 412 accepted, 7 rejected"}}, n.take())
+}
+
+func TestServiceWatchdog(t *testing.T) {
+	m := minertest.NewMining()
+	m.Jobs = 1
+	n := &fakeNotifier{}
+	s := newService(m, n, time.Second*20, logr.Discard())
+	clk := testutil.NewClock()
+	s.tick(clk.Now())
+	n.take()
+
+	// no pings without progress
+	clk.Add(time.Second * 10)
+	s.tick(clk.Now())
+	assert.Equal(t, [][]string{{"STATUS=Mining @ 1 kH/s, 412 accepted, 7 rejected"}}, n.take())
+
+	m.Hashes = 100
+	clk.Add(time.Second)
+	s.tick(clk.Now())
+	assert.Equal(t, [][]string{{"WATCHDOG=1"}}, n.take())
+
+	// at most one ping per half interval
+	m.Hashes = 200
+	clk.Add(time.Second)
+	s.tick(clk.Now())
+	assert.Empty(t, n.take())
+
+	// a paused miner doesn't hash on purpose
+	m.Paused = true
+	clk.Add(time.Second * 10)
+	s.tick(clk.Now())
+	assert.Equal(t, [][]string{{"STATUS=Paused", "WATCHDOG=1"}}, n.take())
+}
+
+func TestServiceRun(t *testing.T) {
+	conn := listen(t)
+	n, err := NewNotifier()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m := minertest.NewMining()
+	m.Jobs = 1
+	s := NewService(m, n, 0, logr.Discard())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	assert.Equal(t, "READY=1\nSTATUS=Mining @ 1 kH/s, 412 accepted, 7 rejected\n", read(t, conn))
+	cancel()
+	<-done
+	s.Stopping()
+	assert.Equal(t, "STOPPING=1\nSTATUS=Shutting down\n", read(t, conn))
+}
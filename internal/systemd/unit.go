@@ -0,0 +1,107 @@
+package systemd
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Unit describes the service unit of the miner.
+type Unit struct {
+	Description string
+	ExecStart   []string // the binary followed by its arguments
+	User        string   // a dynamic user is used if empty
+	Watchdog    time.Duration
+	ExitCodes   []int // exit codes which cause a restart, even if they are expected
+}
+
+var unitTemplate = template.Must(template.New("unit").Funcs(template.FuncMap{
+	"exec":    execLine,
+	"seconds": func(d time.Duration) int64 { return int64(d / time.Second) },
+	"join": func(codes []int) string {
+		s := make([]string, len(codes))
+		for i, c := range codes {
+			s[i] = strconv.Itoa(c)
+		}
+		return strings.Join(s, " ")
+	},
+}).Parse(`[Unit]
+Description={{ .Description }}
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+Type=notify
+NotifyAccess=main
+ExecStart={{ exec .ExecStart }}
+Restart=on-failure
+RestartSec=10
+{{- if .ExitCodes }}
+RestartForceExitStatus={{ join .ExitCodes }}
+{{- end }}
+{{- if .Watchdog }}
+WatchdogSec={{ seconds .Watchdog }}
+{{- end }}
+{{- if .User }}
+User={{ .User }}
+{{- else }}
+DynamicUser=yes
+{{- end }}
+CacheDirectory=dero-stratum-miner
+StateDirectory=dero-stratum-miner
+
+# hardening
+NoNewPrivileges=yes
+CapabilityBoundingSet=
+ProtectSystem=strict
+ProtectHome=read-only
+PrivateTmp=yes
+PrivateDevices=yes
+ProtectKernelTunables=yes
+ProtectKernelModules=yes
+ProtectKernelLogs=yes
+ProtectControlGroups=yes
+ProtectClock=yes
+ProtectHostname=yes
+RestrictAddressFamilies=AF_UNIX AF_INET AF_INET6
+RestrictNamespaces=yes
+RestrictRealtime=yes
+RestrictSUIDSGID=yes
+LockPersonality=yes
+MemoryDenyWriteExecute=yes
+SystemCallArchitectures=native
+SystemCallFilter=@system-service
+
+[Install]
+WantedBy=multi-user.target
+`))
+
+// Render returns the content of the unit file.
+func (u *Unit) Render() ([]byte, error) {
+	var b bytes.Buffer
+	if err := unitTemplate.Execute(&b, u); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// execLine quotes the arguments for ExecStart=.
+func execLine(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = quote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// quote escapes an argument for systemd. Specifiers (%) and variables ($) are escaped by doubling them,
+// arguments with spaces, quotes or backslashes are double quoted with C-style escapes.
+func quote(arg string) string {
+	arg = strings.NewReplacer("%", "%%", "$", "$$").Replace(arg)
+	if arg != "" && !strings.ContainsAny(arg, " \t\n\"'\\;") {
+		return arg
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`).Replace(arg) + `"`
+}
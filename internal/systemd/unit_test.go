@@ -0,0 +1,45 @@
+package systemd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	u := &Unit{
+		Description: "Dero Stratum Miner",
+		ExecStart:   []string{"/usr/bin/dero-stratum-miner", "-w", "dero1abc.rig 1", "--log-file", "/var/log/miner-%i.log", "--non-interactive"},
+		Watchdog:    time.Minute,
+		ExitCodes:   []int{75},
+	}
+	data, err := u.Render()
+	require.NoError(t, err)
+	unit := string(data)
+	assert.Contains(t, unit, "\nType=notify\n")
+	assert.Contains(t, unit, `ExecStart=/usr/bin/dero-stratum-miner -w "dero1abc.rig 1" --log-file /var/log/miner-%%i.log --non-interactive`+"\n")
+	assert.Contains(t, unit, "\nRestartForceExitStatus=75\nWatchdogSec=60\nDynamicUser=yes\n")
+	assert.Contains(t, unit, "\nNoNewPrivileges=yes\n")
+
+	u.User, u.Watchdog, u.ExitCodes = "miner", 0, nil
+	data, err = u.Render()
+	require.NoError(t, err)
+	unit = string(data)
+	assert.Contains(t, unit, "\nRestartSec=10\nUser=miner\n")
+	assert.NotContains(t, unit, "WatchdogSec")
+}
+
+func TestQuote(t *testing.T) {
+	for arg, want := range map[string]string{
+		"plain":      "plain",
+		"":           `""`,
+		"with space": `"with space"`,
+		`a"b\c`:      `"a\"b\\c"`,
+		"$HOME":      "$$HOME",
+		"100%":       "100%%",
+	} {
+		assert.Equal(t, want, quote(arg), arg)
+	}
+}
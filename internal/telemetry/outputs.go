@@ -0,0 +1,201 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxDatagram keeps UDP packets below the usual MTU.
+const maxDatagram = 1432
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// influx line protocol, see https://docs.influxdata.com/influxdb/v2/reference/syntax/line-protocol/
+
+var (
+	influxMeasurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	influxTagEscaper         = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+)
+
+func influxLines(samples []Sample, prefix string, t time.Time) []string {
+	lines := make([]string, len(samples))
+	for i, s := range samples {
+		var b strings.Builder
+		b.WriteString(influxMeasurementEscaper.Replace(prefix + "_" + s.Name))
+		for _, tag := range s.Tags {
+			// empty tag values are invalid
+			if tag.Value == "" {
+				continue
+			}
+			b.WriteByte(',')
+			b.WriteString(influxTagEscaper.Replace(tag.Key))
+			b.WriteByte('=')
+			b.WriteString(influxTagEscaper.Replace(tag.Value))
+		}
+		b.WriteString(" value=")
+		b.WriteString(formatValue(s.Value))
+		b.WriteByte(' ')
+		b.WriteString(strconv.FormatInt(t.UnixNano(), 10))
+		lines[i] = b.String()
+	}
+	return lines
+}
+
+// influxHTTP writes to the http API of InfluxDB, e.g. /api/v2/write?org=...&bucket=... of InfluxDB 2
+// or /write?db=... of InfluxDB 1.
+type influxHTTP struct {
+	url    string
+	token  string
+	prefix string
+	client *http.Client
+}
+
+func newInfluxHTTP(u *url.URL, prefix, token string) *influxHTTP {
+	target := *u
+	target.Scheme = strings.TrimPrefix(u.Scheme, "influx+")
+	if target.Path == "" || target.Path == "/" {
+		target.Path = "/api/v2/write"
+	}
+	return &influxHTTP{
+		url:    target.String(),
+		token:  token,
+		prefix: prefix,
+		client: &http.Client{},
+	}
+}
+
+func (o *influxHTTP) Write(ctx context.Context, samples []Sample, t time.Time) error {
+	body := strings.Join(influxLines(samples, o.prefix, t), "\n") + "\n"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.url, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if o.token != "" {
+		req.Header.Set("Authorization", "Token "+o.token)
+	}
+	res, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("influx: %s: %s", res.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// influxUDP writes to the UDP listener of InfluxDB or telegraf.
+type influxUDP struct {
+	addr   string
+	prefix string
+}
+
+func (o *influxUDP) Write(ctx context.Context, samples []Sample, t time.Time) error {
+	return writeDatagrams(ctx, o.addr, influxLines(samples, o.prefix, t))
+}
+
+// statsd sends all samples as gauges with DogStatsD tags, which are also understood by telegraf
+// and the prometheus statsd exporter.
+type statsd struct {
+	addr   string
+	prefix string
+}
+
+var statsdTagEscaper = strings.NewReplacer(",", "_", "|", "_", "#", "_", " ", "_", "\n", "_")
+
+func (o *statsd) Write(ctx context.Context, samples []Sample, _ time.Time) error {
+	lines := make([]string, len(samples))
+	for i, s := range samples {
+		var b strings.Builder
+		b.WriteString(o.prefix + "." + s.Name)
+		b.WriteByte(':')
+		b.WriteString(formatValue(s.Value))
+		b.WriteString("|g")
+		sep := "|#"
+		for _, tag := range s.Tags {
+			if tag.Value == "" {
+				continue
+			}
+			b.WriteString(sep)
+			b.WriteString(statsdTagEscaper.Replace(tag.Key) + ":" + statsdTagEscaper.Replace(tag.Value))
+			sep = ","
+		}
+		lines[i] = b.String()
+	}
+	return writeDatagrams(ctx, o.addr, lines)
+}
+
+// writeDatagrams sends the lines in as few UDP packets as possible.
+func writeDatagrams(ctx context.Context, addr string, lines []string) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	var packet []byte
+	for _, line := range lines {
+		if len(packet) > 0 && len(packet)+len(line)+1 > maxDatagram {
+			if _, err := conn.Write(packet); err != nil {
+				return err
+			}
+			packet = packet[:0]
+		}
+		packet = append(packet, line...)
+		packet = append(packet, '\n')
+	}
+	if len(packet) > 0 {
+		_, err = conn.Write(packet)
+	}
+	return err
+}
+
+// graphite writes the plaintext protocol with tags over TCP, see
+// https://graphite.readthedocs.io/en/latest/tags.html
+type graphite struct {
+	addr   string
+	prefix string
+}
+
+var (
+	graphiteNameEscaper = strings.NewReplacer(" ", "_", ";", "_", "\n", "_")
+	graphiteTagEscaper  = strings.NewReplacer(" ", "_", ";", "_", "~", "_", "\n", "_")
+)
+
+func (o *graphite) Write(ctx context.Context, samples []Sample, t time.Time) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", o.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline) // nolint: errcheck
+	}
+
+	var b bytes.Buffer
+	for _, s := range samples {
+		b.WriteString(graphiteNameEscaper.Replace(o.prefix + "." + s.Name))
+		for _, tag := range s.Tags {
+			if tag.Value == "" {
+				continue
+			}
+			b.WriteString(";" + graphiteTagEscaper.Replace(tag.Key) + "=" + graphiteTagEscaper.Replace(tag.Value))
+		}
+		fmt.Fprintf(&b, " %s %d\n", formatValue(s.Value), t.Unix())
+	}
+	_, err = conn.Write(b.Bytes())
+	return err
+}
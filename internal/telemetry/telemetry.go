@@ -0,0 +1,177 @@
+// Package telemetry pushes the metrics of the miner to InfluxDB, StatsD or Graphite.
+// Unlike the prometheus endpoint it works for rigs which can't be scraped, e.g. behind NAT.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/whalesburg/dero-stratum-miner/internal/config"
+	"github.com/whalesburg/dero-stratum-miner/internal/stratum"
+)
+
+var ErrUnknownOutput = errors.New("unknown telemetry output, use influx+http, influx+https, influx+udp, statsd or graphite")
+
+var hashrateWindows = []struct {
+	name   string
+	window time.Duration
+}{
+	{"10s", time.Second * 10},
+	{"60s", time.Minute},
+	{"15m", time.Minute * 15},
+}
+
+// Miner is the state of the miner which is pushed.
+type Miner interface {
+	GetWorker() string
+	GetPoolURL() string
+	GetHashrateAverage(window time.Duration) uint64
+	GetThreadHashrates(window time.Duration) []uint64
+	GetAcceptedShares() uint64
+	GetRejectedShares() uint64
+	GetShareLatency() time.Duration
+	GetJob() *stratum.Job
+}
+
+// Tag is a key value pair which qualifies a sample.
+type Tag struct {
+	Key   string
+	Value string
+}
+
+// Sample is a single value of a metric. The tags of the worker and the pool are added to every sample.
+type Sample struct {
+	Name  string
+	Tags  []Tag
+	Value float64
+}
+
+// Output writes the samples to a metrics backend.
+type Output interface {
+	Write(ctx context.Context, samples []Sample, t time.Time) error
+}
+
+// NewOutput creates the output for an url like influx+udp://127.0.0.1:8089. The prefix is prepended to all metric names.
+func NewOutput(rawURL, prefix string, cfg *config.Telemetry) (Output, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "influx+http", "influx+https":
+		return newInfluxHTTP(u, prefix, cfg.InfluxToken), nil
+	case "influx+udp":
+		return &influxUDP{addr: u.Host, prefix: prefix}, nil
+	case "statsd":
+		return &statsd{addr: u.Host, prefix: prefix}, nil
+	case "graphite":
+		return &graphite{addr: u.Host, prefix: prefix}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownOutput, u.Scheme)
+}
+
+// Collect returns the current samples of the miner. temps are the CPU temperatures in °C.
+func Collect(m Miner, temps []float64) []Sample {
+	var samples []Sample
+	for _, hw := range hashrateWindows {
+		samples = append(samples, Sample{Name: "hashrate", Tags: []Tag{{"window", hw.name}}, Value: float64(m.GetHashrateAverage(hw.window))})
+	}
+	for i, h := range m.GetThreadHashrates(time.Second * 10) {
+		samples = append(samples, Sample{Name: "thread_hashrate", Tags: []Tag{{"thread", strconv.Itoa(i)}}, Value: float64(h)})
+	}
+	samples = append(samples,
+		Sample{Name: "shares", Tags: []Tag{{"outcome", "accepted"}}, Value: float64(m.GetAcceptedShares())},
+		Sample{Name: "shares", Tags: []Tag{{"outcome", "rejected"}}, Value: float64(m.GetRejectedShares())},
+		Sample{Name: "share_latency_ms", Value: float64(m.GetShareLatency().Milliseconds())},
+	)
+	if job := m.GetJob(); job != nil {
+		samples = append(samples,
+			Sample{Name: "difficulty", Value: float64(job.Difficulty)},
+			Sample{Name: "height", Value: job.Height},
+		)
+	}
+	for i, t := range temps {
+		samples = append(samples, Sample{Name: "temperature", Tags: []Tag{{"cpu", strconv.Itoa(i)}}, Value: t})
+	}
+	return samples
+}
+
+// Pusher periodically pushes the samples to all outputs.
+type Pusher struct {
+	m        Miner
+	temps    func() []float64
+	outputs  map[string]Output
+	interval time.Duration
+	logger   logr.Logger
+}
+
+// New creates a pusher for the outputs of the config. temps reads the CPU temperatures.
+func New(m Miner, temps func() []float64, cfg *config.Telemetry, logger logr.Logger) (*Pusher, error) {
+	p := &Pusher{
+		m:        m,
+		temps:    temps,
+		outputs:  make(map[string]Output, len(cfg.Outputs)),
+		interval: time.Duration(cfg.Interval),
+		logger:   logger.WithName("telemetry"),
+	}
+	for _, rawURL := range cfg.Outputs {
+		o, err := NewOutput(rawURL, cfg.Prefix, cfg)
+		if err != nil {
+			return nil, err
+		}
+		p.outputs[redact(rawURL)] = o
+	}
+	return p, nil
+}
+
+// Run pushes the samples until the context is cancelled.
+func (p *Pusher) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			p.push(ctx, now)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (p *Pusher) push(ctx context.Context, now time.Time) {
+	samples := Collect(p.m, p.temps())
+	tags := []Tag{{"worker", p.m.GetWorker()}, {"pool", p.m.GetPoolURL()}}
+	for i := range samples {
+		samples[i].Tags = append(samples[i].Tags, tags...)
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.interval)
+	defer cancel()
+	for name, o := range p.outputs {
+		if err := o.Write(ctx, samples, now); err != nil {
+			p.logger.Error(err, "Failed to push metrics", "output", name)
+		}
+	}
+}
+
+// redact removes the credentials from an url, so it can be logged.
+func redact(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	u.User = nil
+	q := u.Query()
+	for k := range q {
+		if strings.EqualFold(k, "p") || strings.EqualFold(k, "token") {
+			q.Set(k, "redacted")
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
@@ -0,0 +1,35 @@
+// Package testutil has helpers which are shared by the tests of several packages.
+package testutil
+
+import (
+	"sync"
+	"time"
+)
+
+// Epoch is the time a Clock starts at.
+var Epoch = time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// Clock is a fake clock for code which takes a now function.
+type Clock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+// NewClock returns a clock which stands still at Epoch until it's advanced.
+func NewClock() *Clock {
+	return &Clock{t: Epoch}
+}
+
+// Now returns the current time of the clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+// Add advances the clock.
+func (c *Clock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
@@ -0,0 +1,55 @@
+package tui
+
+import (
+	"bytes"
+	"sync"
+)
+
+// logBuffer keeps the last lines written by the logger.
+type logBuffer struct {
+	mu      sync.Mutex
+	lines   []string
+	max     int
+	partial []byte
+	written func()
+}
+
+func newLogBuffer(max int) *logBuffer {
+	return &logBuffer{max: max}
+}
+
+func (l *logBuffer) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	l.partial = append(l.partial, p...)
+	for {
+		i := bytes.IndexByte(l.partial, '\n')
+		if i < 0 {
+			break
+		}
+		l.lines = append(l.lines, stripEscapes(string(bytes.TrimRight(l.partial[:i], "\r"))))
+		l.partial = l.partial[i+1:]
+	}
+	if len(l.lines) > l.max {
+		l.lines = append(l.lines[:0], l.lines[len(l.lines)-l.max:]...)
+	}
+	written := l.written
+	l.mu.Unlock()
+
+	if written != nil {
+		written()
+	}
+	return len(p), nil
+}
+
+// last returns up to n of the newest lines.
+func (l *logBuffer) last(n int) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if n <= 0 {
+		return nil
+	}
+	if n > len(l.lines) {
+		n = len(l.lines)
+	}
+	return append([]string(nil), l.lines[len(l.lines)-n:]...)
+}
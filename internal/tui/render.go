@@ -0,0 +1,198 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jon4hz/hashconv"
+)
+
+const (
+	reset   = "\033[0m"
+	bold    = "\033[1m"
+	reverse = "\033[7m"
+	red     = "\033[31m"
+	green   = "\033[32m"
+	yellow  = "\033[33m"
+	cyan    = "\033[36m"
+	gray    = "\033[90m"
+)
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline draws the values as bar chart with one character per value.
+// If there are more values than fit into the width, the newest ones are shown.
+func sparkline(values []uint64, width int) string {
+	if width <= 0 || len(values) == 0 {
+		return ""
+	}
+	if len(values) > width {
+		values = values[len(values)-width:]
+	}
+	var top uint64
+	for _, v := range values {
+		if v > top {
+			top = v
+		}
+	}
+
+	var b strings.Builder
+	for _, v := range values {
+		i := 0
+		if top > 0 {
+			i = int(v * uint64(len(sparks)-1) / top)
+		}
+		b.WriteRune(sparks[i])
+	}
+	return b.String()
+}
+
+// visibleLen returns the number of characters of s without escape sequences.
+func visibleLen(s string) int {
+	n := 0
+	for i := 0; i < len(s); {
+		if s[i] == '\033' {
+			i = skipEscape(s, i)
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		n++
+	}
+	return n
+}
+
+// skipEscape returns the index after the escape sequence starting at i.
+func skipEscape(s string, i int) int {
+	i++
+	if i < len(s) && s[i] == '[' {
+		i++
+		for i < len(s) && (s[i] < 0x40 || s[i] > 0x7e) {
+			i++
+		}
+	}
+	return i + 1
+}
+
+// fit truncates s to width visible characters, escape sequences are kept.
+func fit(s string, width int) string {
+	if visibleLen(s) <= width {
+		return s
+	}
+	var b strings.Builder
+	n := 0
+	for i := 0; i < len(s); {
+		if s[i] == '\033' {
+			j := skipEscape(s, i)
+			if j > len(s) {
+				j = len(s)
+			}
+			b.WriteString(s[i:j])
+			i = j
+			continue
+		}
+		if n == width {
+			break
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		b.WriteString(s[i : i+size])
+		i += size
+		n++
+	}
+	b.WriteString(reset)
+	return b.String()
+}
+
+// pad fits s into exactly width characters.
+func pad(s string, width int) string {
+	s = fit(s, width)
+	if n := visibleLen(s); n < width {
+		s += strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+// stripEscapes removes escape sequences and replaces tabs.
+func stripEscapes(s string) string {
+	if !strings.ContainsAny(s, "\033\t") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case '\033':
+			i = skipEscape(s, i)
+			continue
+		case '\t':
+			b.WriteString("  ")
+		default:
+			b.WriteByte(s[i])
+		}
+		i++
+	}
+	return b.String()
+}
+
+// stripColors removes all color codes. Bold and reverse video are kept, the layout depends on them.
+func stripColors(s string) string {
+	if !strings.Contains(s, "\033") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if s[i] != '\033' {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+		j := skipEscape(s, i)
+		if j > len(s) {
+			j = len(s)
+		}
+		switch seq := s[i:j]; seq {
+		case reset, bold, reverse:
+			b.WriteString(seq)
+		}
+		i = j
+	}
+	return b.String()
+}
+
+// rule draws a horizontal line with a title.
+func rule(title string, width int) string {
+	s := "── " + title + " "
+	return gray + s + strings.Repeat("─", maxInt(width-visibleLen(s), 0)) + reset
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func formatHashrate(h uint64) string {
+	return hashconv.Format(int64(h)) + "/s"
+}
+
+func formatDifficulty(d uint64) string {
+	switch {
+	case d > 1_000_000_000:
+		return fmt.Sprintf("%.1fG", float64(d)/1_000_000_000.0)
+	case d > 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(d)/1_000_000.0)
+	case d > 1000:
+		return fmt.Sprintf("%.1fK", float64(d)/1000.0)
+	}
+	return fmt.Sprintf("%d", d)
+}
+
+func formatDuration(d time.Duration) string {
+	d = d.Truncate(time.Second)
+	if d >= time.Hour*24 {
+		return fmt.Sprintf("%dd%s", d/(time.Hour*24), d%(time.Hour*24))
+	}
+	return d.String()
+}
@@ -0,0 +1,47 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSparkline(t *testing.T) {
+	assert.Equal(t, "▁▄█", sparkline([]uint64{0, 50, 100}, 10))
+	assert.Equal(t, "▁▁", sparkline([]uint64{0, 0}, 10))
+	// only the newest values are shown if they don't fit
+	assert.Equal(t, "▁█", sparkline([]uint64{100, 0, 100}, 2))
+	assert.Equal(t, "", sparkline(nil, 10))
+}
+
+func TestFit(t *testing.T) {
+	assert.Equal(t, "hello", fit("hello", 5))
+	assert.Equal(t, "hel"+reset, fit("hello", 3))
+	assert.Equal(t, green+"he"+reset, fit(green+"hello"+reset, 2))
+	assert.Equal(t, "▁▂"+reset, fit("▁▂▃", 2))
+	assert.Equal(t, 5, visibleLen(red+"he"+reset+"llo"))
+}
+
+func TestPad(t *testing.T) {
+	assert.Equal(t, "ab  ", pad("ab", 4))
+	assert.Equal(t, green+"ab"+reset+"  ", pad(green+"ab"+reset, 4))
+}
+
+func TestStripEscapes(t *testing.T) {
+	assert.Equal(t, "19/10  INFO  miner  started", stripEscapes("19/10\t\033[34mINFO\033[0m\tminer\tstarted"))
+}
+
+func TestLogBuffer(t *testing.T) {
+	l := newLogBuffer(2)
+	l.Write([]byte("one\ntw"))    // nolint: errcheck
+	l.Write([]byte("o\nthree\n")) // nolint: errcheck
+	assert.Equal(t, []string{"two", "three"}, l.last(5))
+	assert.Equal(t, []string{"three"}, l.last(1))
+	assert.Empty(t, l.last(0))
+	assert.Empty(t, l.last(-1))
+}
+
+func TestStripColors(t *testing.T) {
+	assert.Equal(t, reverse+bold+"PAUSED"+reset, stripColors(reverse+bold+yellow+"PAUSED"+reset))
+	assert.Equal(t, "plain", stripColors("plain"))
+}
@@ -0,0 +1,400 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chzyer/readline"
+	"github.com/teivah/broadcast"
+	"github.com/whalesburg/dero-stratum-miner/internal/color"
+	miner "github.com/whalesburg/dero-stratum-miner/internal/dero-stratum-miner"
+	"github.com/whalesburg/dero-stratum-miner/internal/stratum"
+	"github.com/whalesburg/dero-stratum-miner/internal/version"
+)
+
+var ErrNoTerminal = errors.New("the dashboard requires a terminal")
+
+const (
+	maxLogLines   = 500
+	maxShareMarks = 512
+	threadCellLen = 18
+	minWidth      = 40
+	minHeight     = 13 // the status, a row of threads, the rules and the footer
+)
+
+// Miner is the state of the miner which is shown and the controls which are used by the keys.
+type Miner interface {
+	GetPoolURL() string
+	IsConnected() bool
+	IsPaused() bool
+	IsTestnet() bool
+	GetConnectedSince() time.Time
+	GetReconnects() int
+	GetJob() *stratum.Job
+	GetJobsReceived() int64
+	GetThreads() int
+	GetHashrateAverage(window time.Duration) uint64
+	GetHighestHashrate() uint64
+	GetHashrateHistory() []uint64
+	GetThreadHashrates(window time.Duration) []uint64
+	GetAcceptedShares() uint64
+	GetRejectedShares() uint64
+	GetShareLatency() time.Duration
+	GetUptime() time.Duration
+	NewEventListener(buff int) *broadcast.Listener[*miner.Event]
+	Pause()
+	Resume()
+	SetThreads(n int) error
+	Reconnect()
+	Shutdown()
+}
+
+// Dashboard is a full-screen view of the miner which replaces the interactive console.
+type Dashboard struct {
+	ctx    context.Context
+	in     *os.File
+	out    *os.File
+	m      Miner
+	logs   *logBuffer
+	redraw chan struct{}
+	state  *readline.State
+	stop   chan struct{} // stops drawing
+	drawn  chan struct{} // closed when drawing stopped
+
+	mu     sync.Mutex
+	shares []bool // accepted or rejected, oldest first
+}
+
+// New creates a dashboard on stdin and stdout. The logger should write to Writer() so the
+// log lines end up in the log pane instead of corrupting the screen.
+func New(ctx context.Context) *Dashboard {
+	d := &Dashboard{
+		ctx:    ctx,
+		in:     os.Stdin,
+		out:    os.Stdout,
+		logs:   newLogBuffer(maxLogLines),
+		redraw: make(chan struct{}, 1),
+	}
+	d.logs.written = d.requestRedraw
+	return d
+}
+
+// Writer returns the writer of the log pane.
+func (d *Dashboard) Writer() io.Writer {
+	return d.logs
+}
+
+// Start takes over the terminal and draws the dashboard until the context is cancelled.
+func (d *Dashboard) Start(m Miner) error {
+	if !readline.IsTerminal(int(d.in.Fd())) || !readline.IsTerminal(int(d.out.Fd())) {
+		return ErrNoTerminal
+	}
+	state, err := readline.MakeRaw(int(d.in.Fd()))
+	if err != nil {
+		return err
+	}
+	d.state = state
+	d.m = m
+	d.stop = make(chan struct{})
+	d.drawn = make(chan struct{})
+
+	// alternate screen, hide cursor
+	io.WriteString(d.out, "\033[?1049h\033[?25l\033[2J") // nolint: errcheck
+
+	readline.DefaultOnWidthChanged(d.requestRedraw)
+	go d.listenShares()
+	go d.readKeys()
+	go d.draw()
+	return nil
+}
+
+// Close stops drawing and restores the terminal.
+func (d *Dashboard) Close() error {
+	if d.state == nil {
+		return nil
+	}
+	close(d.stop)
+	<-d.drawn
+	io.WriteString(d.out, "\033[?25h\033[?1049l") // nolint: errcheck
+	err := readline.Restore(int(d.in.Fd()), d.state)
+	d.state = nil
+	return err
+}
+
+func (d *Dashboard) requestRedraw() {
+	select {
+	case d.redraw <- struct{}{}:
+	default:
+	}
+}
+
+func (d *Dashboard) listenShares() {
+	l := d.m.NewEventListener(64)
+	defer l.Close()
+	for {
+		select {
+		case ev, ok := <-l.Ch():
+			if !ok {
+				return
+			}
+			if ev.Type != miner.EventShareAccepted && ev.Type != miner.EventShareRejected {
+				continue
+			}
+			d.mu.Lock()
+			d.shares = append(d.shares, ev.Type == miner.EventShareAccepted)
+			if len(d.shares) > maxShareMarks {
+				d.shares = append(d.shares[:0], d.shares[len(d.shares)-maxShareMarks:]...)
+			}
+			d.mu.Unlock()
+			d.requestRedraw()
+		case <-d.ctx.Done():
+			return
+		}
+	}
+}
+
+func (d *Dashboard) readKeys() {
+	buf := make([]byte, 16)
+	for {
+		n, err := d.in.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, b := range buf[:n] {
+			d.handleKey(b)
+		}
+		d.requestRedraw()
+	}
+}
+
+func (d *Dashboard) handleKey(b byte) {
+	switch b {
+	case 'q', readline.CharInterrupt:
+		d.m.Shutdown()
+	case 'p':
+		d.m.Pause()
+	case 'r':
+		d.m.Resume()
+	case '+':
+		d.m.SetThreads(d.m.GetThreads() + 1) // nolint: errcheck
+	case '-':
+		d.m.SetThreads(d.m.GetThreads() - 1) // nolint: errcheck
+	case 'c':
+		d.m.Reconnect()
+	}
+}
+
+func (d *Dashboard) draw() {
+	defer close(d.drawn)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.stop:
+			return
+		default:
+		}
+		d.render()
+		select {
+		case <-ticker.C:
+		case <-d.redraw:
+		case <-d.stop:
+			return
+		case <-d.ctx.Done():
+			return
+		}
+	}
+}
+
+func (d *Dashboard) render() {
+	width, height, err := readline.GetSize(int(d.out.Fd()))
+	if err != nil {
+		return
+	}
+	var frame bytes.Buffer
+	frame.WriteString("\033[H")
+	for i, line := range d.lines(width, height) {
+		if i > 0 {
+			frame.WriteString("\r\n")
+		}
+		if !color.Enabled() {
+			line = stripColors(line)
+		}
+		frame.WriteString(fit(line, width))
+		frame.WriteString("\033[K")
+	}
+	frame.WriteString("\033[J")
+	d.out.Write(frame.Bytes()) // nolint: errcheck
+}
+
+// lines returns the content of the screen.
+func (d *Dashboard) lines(width, height int) []string {
+	if width < minWidth || height < minHeight {
+		return []string{fmt.Sprintf("terminal too small (%dx%d)", width, height)}
+	}
+
+	lines := []string{
+		d.header(width),
+		d.pool(),
+		d.job(),
+		"",
+		d.hashrate(),
+		d.sparkline(width),
+		"",
+		d.shareStats(),
+		d.shareTimeline(width),
+	}
+
+	threads := d.threads(width, (height-len(lines))/3)
+	lines = append(lines, rule("Threads", width))
+	lines = append(lines, threads...)
+
+	lines = append(lines, rule("Log", width))
+	logHeight := height - len(lines) - 1
+	if logHeight < 0 {
+		// the threads use up the rows of the log on the smallest terminals
+		logHeight = 0
+	}
+	logs := d.logs.last(logHeight)
+	for len(logs) < logHeight {
+		logs = append(logs, "")
+	}
+	lines = append(lines, logs...)
+
+	return append(lines, d.footer(width))
+}
+
+func (d *Dashboard) header(width int) string {
+	network := "mainnet"
+	if d.m.IsTestnet() {
+		network = red + "testnet" + reset + reverse
+	}
+	left := fmt.Sprintf(" Dero-Stratum-Miner %s │ %s │ up %s", version.Version, network, formatDuration(d.m.GetUptime()))
+
+	state := green + "MINING"
+	switch {
+	case d.m.IsPaused():
+		state = yellow + "PAUSED"
+	case !d.m.IsConnected():
+		state = red + "DISCONNECTED"
+	}
+	right := bold + state + reset + reverse + " "
+
+	space := width - visibleLen(left) - visibleLen(right)
+	if space < 1 {
+		space = 1
+	}
+	return reverse + left + strings.Repeat(" ", space) + right + reset
+}
+
+func (d *Dashboard) pool() string {
+	status := red + "● disconnected" + reset
+	if d.m.IsConnected() {
+		status = fmt.Sprintf("%s● connected%s for %s", green, reset, formatDuration(time.Since(d.m.GetConnectedSince())))
+	}
+	return fmt.Sprintf("%sPool%s      %s  %s  reconnects %d", bold, reset, d.m.GetPoolURL(), status, d.m.GetReconnects())
+}
+
+func (d *Dashboard) job() string {
+	job := d.m.GetJob()
+	if job == nil {
+		return bold + "Job" + reset + "       waiting for a job"
+	}
+	return fmt.Sprintf("%sJob%s       %s  height %.0f  difficulty %s  jobs %d", bold, reset, job.ID, job.Height, formatDifficulty(job.Difficulty), d.m.GetJobsReceived())
+}
+
+func (d *Dashboard) hashrate() string {
+	return fmt.Sprintf("%sHashrate%s  %s%s%s (10s)  %s (60s)  %s (15m)  highest %s  threads %d",
+		bold, reset,
+		green, formatHashrate(d.m.GetHashrateAverage(time.Second*10)), reset,
+		formatHashrate(d.m.GetHashrateAverage(time.Minute)),
+		formatHashrate(d.m.GetHashrateAverage(time.Minute*15)),
+		formatHashrate(d.m.GetHighestHashrate()),
+		d.m.GetThreads(),
+	)
+}
+
+func (d *Dashboard) sparkline(width int) string {
+	history := d.m.GetHashrateHistory()
+	if len(history) == 0 {
+		return gray + "collecting hashrate history..." + reset
+	}
+	return cyan + sparkline(history, width) + reset
+}
+
+func (d *Dashboard) shareStats() string {
+	accepted, rejected := d.m.GetAcceptedShares(), d.m.GetRejectedShares()
+	s := fmt.Sprintf("%sShares%s    %s%d accepted%s  %s%d rejected%s  latency %s",
+		bold, reset,
+		green, accepted, reset,
+		red, rejected, reset,
+		d.m.GetShareLatency().Truncate(time.Millisecond),
+	)
+	if total := accepted + rejected; total > 0 {
+		s += fmt.Sprintf("  %.1f%%", float64(accepted)/float64(total)*100)
+	}
+	return s
+}
+
+// shareTimeline shows the results of the latest shares, newest on the right.
+func (d *Dashboard) shareTimeline(width int) string {
+	d.mu.Lock()
+	shares := d.shares
+	if len(shares) > width {
+		shares = shares[len(shares)-width:]
+	}
+	var b strings.Builder
+	for _, accepted := range shares {
+		if accepted {
+			b.WriteString(green + "+")
+		} else {
+			b.WriteString(red + "x")
+		}
+	}
+	d.mu.Unlock()
+	if b.Len() == 0 {
+		return gray + "no shares yet" + reset
+	}
+	return b.String() + reset
+}
+
+// threads returns the hashrate of every thread in as many columns as fit into the width.
+func (d *Dashboard) threads(width, maxRows int) []string {
+	rates := d.m.GetThreadHashrates(time.Second * 10)
+	n := d.m.GetThreads()
+	if n > len(rates) {
+		n = len(rates)
+	}
+	cols := width / threadCellLen
+	if cols < 1 {
+		cols = 1
+	}
+	rows := (n + cols - 1) / cols
+	if maxRows < 1 {
+		maxRows = 1
+	}
+	if rows > maxRows {
+		rows = maxRows
+	}
+
+	lines := make([]string, rows)
+	for i := 0; i < n; i++ {
+		row := i % rows
+		if i/rows >= cols {
+			break
+		}
+		lines[row] += pad(fmt.Sprintf("%s#%-3d%s %s", gray, i, reset, formatHashrate(rates[i])), threadCellLen)
+	}
+	return lines
+}
+
+func (d *Dashboard) footer(width int) string {
+	return reverse + pad(" q quit  p pause  r resume  + more threads  - fewer threads  c reconnect", width) + reset
+}
@@ -0,0 +1,30 @@
+package tui
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/whalesburg/dero-stratum-miner/internal/minertest"
+)
+
+func TestLines(t *testing.T) {
+	m := minertest.New()
+	m.ThreadHashrates = []uint64{1000}
+	d := New(context.Background())
+	d.m = m
+	d.logs.Write([]byte("one\ntwo\n")) // nolint: errcheck
+
+	// the smallest terminal has no room for the log
+	lines := d.lines(minWidth, minHeight)
+	assert.Len(t, lines, minHeight)
+	assert.Contains(t, lines[len(lines)-2], "Log")
+
+	lines = d.lines(minWidth, minHeight+1)
+	assert.Len(t, lines, minHeight+1)
+	assert.Equal(t, "two", lines[len(lines)-2])
+
+	lines = d.lines(minWidth, minHeight-1)
+	assert.True(t, strings.HasPrefix(lines[0], "terminal too small"))
+}
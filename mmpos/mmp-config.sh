@@ -0,0 +1,14 @@
+#!/usr/bin/env bash
+
+# translates the mmpOS miner profile into the arguments of the miner.
+# mmpOS passes the pool, the wallet and worker template and the extra arguments of the profile.
+
+. ./mmp-external.conf
+
+conf="-r $POOL_URL "
+conf+="-w $WALLET "
+
+# extra arguments and the API for mmp-stats.sh
+conf+="$EXTRA_ARGS --non-interactive --api-enabled --api-transport http --api-listen 127.0.0.1:$EXTERNAL_API_PORT"
+
+echo -e "$conf" > ./dero-stratum-miner.conf
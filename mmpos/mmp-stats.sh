@@ -0,0 +1,12 @@
+#!/usr/bin/env bash
+
+. ./mmp-external.conf
+
+stats=`./dero-stratum-miner mmpos stats --api 127.0.0.1:$EXTERNAL_API_PORT`
+
+if [[ $? -ne 0 || -z $stats ]]; then
+    echo "Failed to read $EXTERNAL_NAME stats from localhost:$EXTERNAL_API_PORT" >&2
+    exit 1
+fi
+
+echo "$stats"
@@ -0,0 +1,14 @@
+#!/usr/bin/env bash
+
+# translates the RaveOS wallet into the arguments of the miner.
+# RaveOS passes the pool url, the wallet, the worker name and the additional arguments of the wallet.
+
+API_PORT=`jq -r '.api_port' manifest.json`
+
+conf="-r $POOL_URL "
+conf+="-w $WALLET.$WORKER_NAME "
+
+# additional arguments and the API for stats.sh
+conf+="$EXTRA_ARGS --non-interactive --api-enabled --api-transport http --api-listen 127.0.0.1:$API_PORT"
+
+echo -e "$conf" > ./dero-stratum-miner.conf
@@ -0,0 +1,7 @@
+#!/usr/bin/env bash
+
+./config.sh
+
+[[ ! -e ./dero-stratum-miner.conf ]] && echo "No config file found, exiting" && pwd && exit 1
+
+./dero-stratum-miner $(< dero-stratum-miner.conf)
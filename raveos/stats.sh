@@ -0,0 +1,12 @@
+#!/usr/bin/env bash
+
+API_PORT=`jq -r '.api_port' manifest.json`
+
+stats=`./dero-stratum-miner raveos stats --api 127.0.0.1:$API_PORT`
+
+if [[ $? -ne 0 || -z $stats ]]; then
+    echo "Failed to read dero-stratum-miner stats from localhost:$API_PORT" >&2
+    exit 1
+fi
+
+echo "$stats"
@@ -0,0 +1,11 @@
+#!/usr/bin/env bash
+
+# automatically set the version in mmpOSes mmp-external.conf file.
+# The version is read from the first argument passed to the script.
+
+if [ -z "$1" ]; then
+    echo 'Missing version' >&2
+    exit 1
+fi
+
+sed -i "/EXTERNAL_VERSION/c EXTERNAL_VERSION=$1" mmpos/mmp-external.conf
@@ -0,0 +1,11 @@
+#!/usr/bin/env bash
+
+# automatically set the version in RaveOSes manifest.json file.
+# The version is read from the first argument passed to the script.
+
+if [ -z "$1" ]; then
+    echo 'Missing version' >&2
+    exit 1
+fi
+
+sed -i "s/\"version\": \".*\"/\"version\": \"$1\"/" raveos/manifest.json